@@ -4,9 +4,11 @@ import (
 	"FurniSwap/pkg/config"
 	"FurniSwap/pkg/database"
 	"FurniSwap/pkg/middleware"
+	"FurniSwap/pkg/utils"
 
 	// Auth module
 	authHandler "FurniSwap/internal/modules/auth/handler"
+	authOAuth "FurniSwap/internal/modules/auth/oauth"
 	authRepo "FurniSwap/internal/modules/auth/repository"
 	authService "FurniSwap/internal/modules/auth/service"
 
@@ -32,9 +34,31 @@ import (
 
 	// Chat module
 	chatHandler "FurniSwap/internal/modules/chat/handler"
+	chatHub "FurniSwap/internal/modules/chat/hub"
 	chatRepo "FurniSwap/internal/modules/chat/repository"
+	chatScreening "FurniSwap/internal/modules/chat/screening"
 	chatService "FurniSwap/internal/modules/chat/service"
 
+	// Block module
+	blockHandler "FurniSwap/internal/modules/block/handler"
+	blockRepo "FurniSwap/internal/modules/block/repository"
+	blockService "FurniSwap/internal/modules/block/service"
+
+	// Review module
+	reviewHandler "FurniSwap/internal/modules/review/handler"
+	reviewRepo "FurniSwap/internal/modules/review/repository"
+	reviewService "FurniSwap/internal/modules/review/service"
+
+	// Dispute module
+	disputeHandler "FurniSwap/internal/modules/dispute/handler"
+	disputeRepo "FurniSwap/internal/modules/dispute/repository"
+	disputeService "FurniSwap/internal/modules/dispute/service"
+
+	// Admin module
+	adminHandler "FurniSwap/internal/modules/admin/handler"
+	adminRepo "FurniSwap/internal/modules/admin/repository"
+	adminService "FurniSwap/internal/modules/admin/service"
+
 	"context"
 	"database/sql"
 	"log"
@@ -72,6 +96,12 @@ func main() {
 	// Initialize router with default middleware
 	r := gin.Default()
 
+	// Gin trusts X-Forwarded-For from anyone by default, which would let clients pick the IP
+	// the login limits, sessions and lockout alerts see
+	if err := r.SetTrustedProxies(config.Config.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Configure CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     config.Config.AllowedOrigins,
@@ -80,8 +110,11 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	// Serve static files
-	r.Static("/uploads", "./uploads")
+	// Serve static files; chat attachments and dispute evidence are served only to the
+	// participants (and, for evidence, moderators) via the API
+	uploads := r.Group("/uploads")
+	uploads.Use(middleware.PrivateUploads("chats", "disputes"))
+	uploads.Static("/", "./uploads")
 
 	// Initialize module repositories
 	authRepository := authRepo.NewRepository(db)
@@ -90,14 +123,34 @@ func main() {
 	favoriteRepository := favoriteRepo.NewRepository(db)
 	purchaseRepository := purchaseRepo.NewRepository(db)
 	chatRepository := chatRepo.NewRepository(db)
+	reviewRepository := reviewRepo.NewRepository(db)
+	disputeRepository := disputeRepo.NewRepository(db)
+	blockRepository := blockRepo.NewRepository(db)
+	adminRepository := adminRepo.NewRepository(db)
+
+	// Initialize real-time chat hub, shared with other replicas via LISTEN/NOTIFY
+	chatEvents := chatHub.NewHub()
+	if err := chatEvents.StartBridge(db, database.ConnectionString(), chatRepository); err != nil {
+		log.Printf("Warning: chat events bridge not started, real-time chat works within this instance only: %v", err)
+	}
+	defer chatEvents.Close()
 
 	// Initialize module services
-	authSvc := authService.NewService(authRepository)
-	profileSvc := profileService.NewService(profileRepository)
-	listingSvc := listingService.NewService(listingRepository)
+	authSvc := authService.NewService(authRepository, authOAuth.NewProviders(config.Config.OIDCProviders))
+	profileSvc := profileService.NewService(profileRepository, listingRepository, authSvc)
+	chatScreener := chatScreening.NewPipeline(chatScreening.DefaultRules(chatRepository)...)
+	chatSvc := chatService.NewService(chatRepository, chatEvents, blockRepository, chatScreener)
+	listingSvc := listingService.NewService(listingRepository, chatSvc)
 	favoriteSvc := favoriteService.NewService(favoriteRepository)
-	purchaseSvc := purchaseService.NewService(purchaseRepository, listingRepository)
-	chatSvc := chatService.NewService(chatRepository)
+	purchaseSvc := purchaseService.NewService(purchaseRepository, listingRepository, chatSvc)
+	reviewSvc := reviewService.NewService(reviewRepository, purchaseRepository)
+	disputeSvc := disputeService.NewService(disputeRepository, purchaseRepository)
+	blockSvc := blockService.NewService(blockRepository)
+	adminSvc := adminService.NewService(adminRepository)
+
+	// Purge deleted accounts once their grace period is over
+	stopAccountPurge := profileSvc.StartAccountPurge(time.Hour)
+	defer stopAccountPurge()
 
 	// Initialize module handlers
 	authHandler := authHandler.NewHandler(authSvc)
@@ -106,10 +159,38 @@ func main() {
 	favoriteHandler := favoriteHandler.NewHandler(favoriteSvc)
 	purchaseHandler := purchaseHandler.NewHandler(purchaseSvc)
 	chatHandler := chatHandler.NewHandler(chatSvc)
+	reviewHandler := reviewHandler.NewHandler(reviewSvc)
+	disputeHandler := disputeHandler.NewHandler(disputeSvc)
+	blockHandler := blockHandler.NewHandler(blockSvc)
+	adminHandler := adminHandler.NewHandler(adminSvc)
 
 	// Register public routes (no auth required)
 	authHandler.RegisterRoutes(r.Group(""))
 
+	// Development mail catcher inbox (MAIL_CATCHER_DIR); never registered in release mode
+	if mailDir := utils.MailCatcherDir(); mailDir != "" {
+		log.Printf("WARNING: mail catcher enabled, emails are written to %s instead of being sent", mailDir)
+
+		r.GET("/dev/mail", func(c *gin.Context) {
+			emails, err := utils.CaughtEmails(mailDir, c.Query("to"))
+			if err != nil {
+				log.Printf("Error reading caught emails: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error reading caught emails"})
+				return
+			}
+			c.JSON(http.StatusOK, emails)
+		})
+
+		r.DELETE("/dev/mail", func(c *gin.Context) {
+			if err := utils.ClearCaughtEmails(mailDir); err != nil {
+				log.Printf("Error clearing caught emails: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error clearing caught emails"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Inbox cleared"})
+		})
+	}
+
 	// User public endpoints
 	r.GET("/users/:id", func(c *gin.Context) {
 		// Получаем ID пользователя из URL-параметра
@@ -123,17 +204,22 @@ func main() {
 
 		// Получаем профиль пользователя из БД
 		var userProfile struct {
-			ID        int       `db:"id" json:"id"`
-			Name      string    `db:"name" json:"name"`
-			LastName  string    `db:"last_name" json:"last_name"`
-			City      string    `db:"city" json:"city"`
-			Avatar    string    `db:"avatar" json:"avatar"`
-			CreatedAt time.Time `db:"created_at" json:"created_at"`
+			ID          int       `db:"id" json:"id"`
+			Name        string    `db:"name" json:"name"`
+			LastName    string    `db:"last_name" json:"last_name"`
+			City        string    `db:"city" json:"city"`
+			Avatar      string    `db:"avatar" json:"avatar"`
+			CreatedAt   time.Time `db:"created_at" json:"created_at"`
+			Rating      float64   `db:"rating" json:"rating"`
+			ReviewCount int       `db:"review_count" json:"review_count"`
 		}
 		query := `
-			SELECT id, name, last_name, city, avatar, created_at
-			FROM users
-			WHERE id = $1
+			SELECT u.id, u.name, u.last_name, u.city, u.avatar, u.created_at,
+			       COALESCE(ROUND(AVG(r.rating), 2), 0) as rating, COUNT(r.id) as review_count
+			FROM users u
+			LEFT JOIN reviews r ON r.target_id = u.id
+			WHERE u.id = $1
+			GROUP BY u.id
 		`
 		err = db.Get(&userProfile, query, userID)
 		if err != nil {
@@ -167,18 +253,45 @@ func main() {
 
 	// Public listing routes
 	publicListings := r.Group("/listings")
+	publicListings.Use(middleware.OptionalAuth(db))
 	listingHandler.RegisterPublicRoutes(publicListings)
 
+	// Public review routes
+	reviewHandler.RegisterPublicRoutes(r.Group(""))
+
 	// Protected API routes (auth required)
 	api := r.Group("/api")
 	api.Use(middleware.AuthRequired(db))
 	{
 		// Register module routes to protected API group
+		authHandler.RegisterProtectedRoutes(api)
 		profileHandler.RegisterRoutes(api)
 		listingHandler.RegisterProtectedRoutes(api)
 		favoriteHandler.RegisterRoutes(api)
 		purchaseHandler.RegisterRoutes(api)
 		chatHandler.RegisterRoutes(api)
+		reviewHandler.RegisterRoutes(api)
+		disputeHandler.RegisterRoutes(api)
+		blockHandler.RegisterRoutes(api)
+
+		// Moderation and management routes, each group allowed to roles with its permission
+		admin := api.Group("/admin")
+
+		disputeModeration := admin.Group("")
+		disputeModeration.Use(middleware.RequirePermission(db, middleware.PermissionModerateDisputes))
+		disputeHandler.RegisterModeratorRoutes(disputeModeration)
+
+		chatModeration := admin.Group("")
+		chatModeration.Use(middleware.RequirePermission(db, middleware.PermissionModerateChats))
+		chatHandler.RegisterModeratorRoutes(chatModeration)
+
+		userViewing := admin.Group("")
+		userViewing.Use(middleware.RequirePermission(db, middleware.PermissionReadUsers))
+		adminHandler.RegisterRoutes(userViewing)
+
+		userManagement := admin.Group("")
+		userManagement.Use(middleware.RequirePermission(db, middleware.PermissionManageUsers))
+		adminHandler.RegisterManagementRoutes(userManagement)
 	}
 
 	// Create HTTP server
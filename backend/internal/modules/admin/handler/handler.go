@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/admin/model"
+	"FurniSwap/internal/modules/admin/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler provides user administration handlers
+type Handler struct {
+	service *service.Service
+}
+
+// NewHandler creates a new admin handler
+func NewHandler(service *service.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers routes for viewing users and roles (users.read permission required)
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/users", h.GetUsers)
+	router.GET("/users/:id", h.GetUser)
+	router.GET("/roles", h.GetRoles)
+}
+
+// RegisterManagementRoutes registers routes for changing users (users.manage permission required)
+func (h *Handler) RegisterManagementRoutes(router *gin.RouterGroup) {
+	router.PUT("/users/:id/role", h.SetUserRole)
+}
+
+// GetUsers handles getting users with search, role filter and pagination
+func (h *Handler) GetUsers(c *gin.Context) {
+	// Parse filter and pagination parameters
+	filter := model.UserFilter{
+		Query: c.Query("q"),
+		Role:  c.Query("role"),
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	users, err := h.service.GetUsers(filter, page, limit)
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUser handles getting a user
+func (h *Handler) GetUser(c *gin.Context) {
+	// Parse user ID
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	user, err := h.service.GetUser(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error getting user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetRoles handles getting the roles with their permissions
+func (h *Handler) GetRoles(c *gin.Context) {
+	roles, err := h.service.GetRoles()
+	if err != nil {
+		log.Printf("Error getting roles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// SetUserRole handles changing the role of a user
+func (h *Handler) SetUserRole(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	actorID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse user ID
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.SetRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	user, err := h.service.SetUserRole(actorID.(int), userID, req)
+	if err != nil {
+		switch err.Error() {
+		case "cannot change own role":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		case "role not found":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			log.Printf("Error setting user role: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error setting user role"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
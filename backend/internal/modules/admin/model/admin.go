@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// User represents a user as seen by administrators
+type User struct {
+	ID          int        `db:"id" json:"id"`
+	Email       string     `db:"email" json:"email"`
+	Name        string     `db:"name" json:"name"`
+	LastName    string     `db:"last_name" json:"last_name"`
+	City        string     `db:"city" json:"city"`
+	Role        string     `db:"role" json:"role"`
+	IsVerified  bool       `db:"is_verified" json:"is_verified"`
+	LockedUntil *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
+
+// UsersResponse represents a list of users with pagination
+type UsersResponse struct {
+	Users       []User `json:"users"`
+	TotalCount  int    `json:"total_count"`
+	CurrentPage int    `json:"current_page"`
+	TotalPages  int    `json:"total_pages"`
+}
+
+// UserFilter filters the user list
+type UserFilter struct {
+	// Query matches the email, name or last name
+	Query string
+	Role  string
+}
+
+// Role represents a role with its permissions
+type Role struct {
+	Name        string         `db:"name" json:"name"`
+	Description string         `db:"description" json:"description"`
+	Permissions pq.StringArray `db:"permissions" json:"permissions"`
+}
+
+// SetRoleRequest represents the data needed to change the role of a user
+type SetRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/admin/model"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository handles database operations for user administration
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new admin repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// userSelect selects users as seen by administrators
+const userSelect = `
+	SELECT id, email, name, last_name, city, role, is_verified, locked_until, created_at
+	FROM users
+`
+
+// userFilter matches users by email, name or last name and by role; empty values match everyone
+const userFilter = `
+	WHERE ($1 = '' OR email ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%' OR last_name ILIKE '%' || $1 || '%')
+	  AND ($2 = '' OR role = $2)
+`
+
+// GetUsers gets users matching the filter with pagination, newest first
+func (r *Repository) GetUsers(filter model.UserFilter, page, limit int) (*model.UsersResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*) FROM users"+userFilter, filter.Query, filter.Role)
+	if err != nil {
+		log.Printf("Error getting users count: %v", err)
+		return nil, fmt.Errorf("error getting users count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	// Get users
+	users := []model.User{}
+	err = r.db.Select(&users, userSelect+userFilter+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, filter.Query, filter.Role, limit, offset)
+	if err != nil {
+		log.Printf("Error getting users: %v", err)
+		return nil, fmt.Errorf("error getting users: %w", err)
+	}
+
+	return &model.UsersResponse{
+		Users:       users,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// GetUser gets a user by ID
+func (r *Repository) GetUser(userID int) (*model.User, error) {
+	var user model.User
+	err := r.db.Get(&user, userSelect+"WHERE id = $1", userID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetRoles gets all roles with their permissions
+func (r *Repository) GetRoles() ([]model.Role, error) {
+	roles := []model.Role{}
+	err := r.db.Select(&roles, `
+		SELECT r.name, r.description,
+		       COALESCE(ARRAY_AGG(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}') AS permissions
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role = r.name
+		GROUP BY r.name, r.description
+		ORDER BY COUNT(rp.permission), r.name
+	`)
+	if err != nil {
+		log.Printf("Error getting roles: %v", err)
+		return nil, fmt.Errorf("error getting roles: %w", err)
+	}
+	return roles, nil
+}
+
+// RoleExists checks if a role exists
+func (r *Repository) RoleExists(role string) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)", role)
+	if err != nil {
+		log.Printf("Error checking if role exists: %v", err)
+		return false, fmt.Errorf("error checking if role exists: %w", err)
+	}
+	return exists, nil
+}
+
+// SetUserRole changes the role of a user; it returns false if there is no such user
+func (r *Repository) SetUserRole(userID int, role string) (bool, error) {
+	result, err := r.db.Exec("UPDATE users SET role = $1 WHERE id = $2", role, userID)
+	if err != nil {
+		log.Printf("Error setting user role: %v", err)
+		return false, fmt.Errorf("error setting user role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
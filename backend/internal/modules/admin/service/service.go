@@ -0,0 +1,88 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/admin/model"
+	"FurniSwap/internal/modules/admin/repository"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Service provides user administration operations
+type Service struct {
+	repo *repository.Repository
+}
+
+// NewService creates a new admin service
+func NewService(repo *repository.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// GetUsers gets users matching the filter with pagination
+func (s *Service) GetUsers(filter model.UserFilter, page, limit int) (*model.UsersResponse, error) {
+	return s.repo.GetUsers(filter, page, limit)
+}
+
+// GetUser gets a user by ID
+func (s *Service) GetUser(userID int) (*model.User, error) {
+	user, err := s.repo.GetUser(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetRoles gets all roles with their permissions
+func (s *Service) GetRoles() ([]model.Role, error) {
+	return s.repo.GetRoles()
+}
+
+// SetUserRole changes the role of a user. Administrators can't change their own role,
+// so there is always an administrator left to undo a mistake. Tokens issued with the
+// old role stop working and the user gets new ones on refresh.
+func (s *Service) SetUserRole(actorID, userID int, req model.SetRoleRequest) (*model.User, error) {
+	if actorID == userID {
+		return nil, errors.New("cannot change own role")
+	}
+
+	exists, err := s.repo.RoleExists(req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("role not found")
+	}
+
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == req.Role {
+		return user, nil
+	}
+
+	updated, err := s.repo.SetUserRole(userID, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("user not found")
+	}
+
+	log.Printf("User ID: %d changed role of user ID: %d from %q to %q", actorID, userID, user.Role, req.Role)
+
+	body := fmt.Sprintf("Your role on FurniSwap was changed from %q to %q.", user.Role, req.Role)
+	if err := utils.SendEmail(user.Email, "Your Role Was Changed", body); err != nil {
+		log.Printf("Error sending role change notification: %v", err)
+	}
+
+	user.Role = req.Role
+	return user, nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrustedDevices handles getting the devices where the user skips the second factor
+func (h *Handler) GetTrustedDevices(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	devices, err := h.service.GetTrustedDevices(userID.(int))
+	if err != nil {
+		log.Printf("Error getting trusted devices: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting trusted devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// RevokeTrustedDevice handles forgetting a trusted device
+func (h *Handler) RevokeTrustedDevice(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse device ID
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	if err := h.service.RevokeTrustedDevice(userID.(int), deviceID); err != nil {
+		if err.Error() == "trusted device not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Trusted device not found"})
+			return
+		}
+		log.Printf("Error revoking trusted device: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking trusted device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device is no longer trusted"})
+}
+
+// RevokeTrustedDevices handles forgetting all trusted devices
+func (h *Handler) RevokeTrustedDevices(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if err := h.service.RevokeTrustedDevices(userID.(int)); err != nil {
+		log.Printf("Error revoking trusted devices: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking trusted devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All devices are no longer trusted"})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestEmailChange handles starting a change of the authenticated user's email
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	change, err := h.service.RequestEmailChange(userID.(int), req, c.ClientIP())
+	if err != nil {
+		if respondEmailChangeError(c, err) {
+			return
+		}
+		log.Printf("Error requesting email change: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email change error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+// ConfirmEmailChange handles switching to the new email with the code sent to it
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	change, err := h.service.ConfirmEmailChange(userID.(int), req)
+	if err != nil {
+		if respondEmailChangeError(c, err) {
+			return
+		}
+		log.Printf("Error confirming email change: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email change error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, change)
+}
+
+// RevokeEmailChange handles undoing an email change with the link sent to the old email
+func (h *Handler) RevokeEmailChange(c *gin.Context) {
+	var req model.RevokeEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	if err := h.service.RevokeEmailChange(req); err != nil {
+		if respondEmailChangeError(c, err) {
+			return
+		}
+		log.Printf("Error revoking email change: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email change error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email change undone; all devices were signed out and a password reset link was sent to your email"})
+}
+
+// respondEmailChangeError writes the response for expected email change errors; it returns false for other errors
+func respondEmailChangeError(c *gin.Context, err error) bool {
+	if respondThrottled(c, err) {
+		return true
+	}
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case "invalid password":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+	case "new email must differ from the current one":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email must differ from the current one"})
+	case "email already taken":
+		c.JSON(http.StatusConflict, gin.H{"error": "This email is not available"})
+	case "no pending email change":
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending email change; request a new one"})
+	case "invalid or expired code":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+	case "invalid or expired revoke token":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired link"})
+	default:
+		return false
+	}
+	return true
+}
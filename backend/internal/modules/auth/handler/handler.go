@@ -3,8 +3,11 @@ package handler
 import (
 	"FurniSwap/internal/modules/auth/model"
 	"FurniSwap/internal/modules/auth/service"
+	"FurniSwap/pkg/utils"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,7 +32,39 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 		auth.POST("/verify", h.Verify)
 		auth.POST("/login", h.Login)
 		auth.POST("/verify-2fa", h.Verify2FA)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/logout", h.Logout)
+		auth.POST("/forgot-password", h.ForgotPassword)
+		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/revoke-email-change", h.RevokeEmailChange)
+
+		auth.GET("/oauth/providers", h.GetOAuthProviders)
+		auth.POST("/oauth/:provider/start", h.StartOAuth)
+		auth.POST("/oauth/:provider/callback", h.OAuthCallback)
+	}
+}
+
+// RegisterProtectedRoutes registers session, password, email, second factor and trusted device routes (auth required)
+func (h *Handler) RegisterProtectedRoutes(router *gin.RouterGroup) {
+	router.GET("/sessions", h.GetSessions)
+	router.DELETE("/sessions/:id", h.RevokeSession)
+	router.PUT("/profile/password", h.ChangePassword)
+	router.POST("/profile/email", h.RequestEmailChange)
+	router.POST("/profile/email/confirm", h.ConfirmEmailChange)
+
+	twoFactor := router.Group("/2fa")
+	{
+		twoFactor.GET("", h.GetTwoFactorStatus)
+		twoFactor.PUT("/method", h.SetTwoFactorMethod)
+		twoFactor.POST("/totp", h.EnrollTOTP)
+		twoFactor.POST("/totp/confirm", h.ConfirmTOTP)
+		twoFactor.DELETE("/totp", h.DisableTOTP)
+		twoFactor.POST("/recovery-codes", h.RegenerateRecoveryCodes)
 	}
+
+	router.GET("/trusted-devices", h.GetTrustedDevices)
+	router.DELETE("/trusted-devices", h.RevokeTrustedDevices)
+	router.DELETE("/trusted-devices/:id", h.RevokeTrustedDevice)
 }
 
 // Register handles user registration
@@ -40,7 +75,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	code, err := h.service.Register(req)
+	err := h.service.Register(req)
 	if err != nil {
 		if err.Error() == "user with this email already exists" {
 			c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
@@ -51,10 +86,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Verification code sent",
-		"code":    code,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "Verification code sent"})
 }
 
 // Verify handles email verification
@@ -65,8 +97,11 @@ func (h *Handler) Verify(c *gin.Context) {
 		return
 	}
 
-	err := h.service.VerifyUser(req)
+	err := h.service.VerifyUser(req, c.ClientIP())
 	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
 		if err.Error() == "invalid or expired verification code" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification code"})
 			return
@@ -87,8 +122,11 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	user, code, err := h.service.Login(req)
+	user, err := h.service.Login(req, clientInfo(c))
 	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
 		if err.Error() == "invalid email or password" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
@@ -102,16 +140,30 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "2FA code sent to your email",
-		"code":    code,
+	// A trusted device skips the second factor and gets the tokens right away
+	if user.Token != "" {
+		c.JSON(http.StatusOK, user)
+		return
+	}
+
+	response := gin.H{
+		"message":           "2FA code sent to your email",
+		"two_factor_method": user.TwoFactorMethod,
 		"user": gin.H{
 			"id":        user.ID,
 			"email":     user.Email,
 			"name":      user.Name,
 			"last_name": user.LastName,
 		},
-	})
+	}
+
+	// With an authenticator app the login token has to be passed to /auth/verify-2fa
+	if user.TwoFactorMethod == model.TwoFactorMethodTOTP {
+		response["message"] = "Enter the code from your authenticator app or a recovery code"
+		response["login_token"] = user.LoginToken
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // Verify2FA handles two-factor authentication
@@ -122,8 +174,11 @@ func (h *Handler) Verify2FA(c *gin.Context) {
 		return
 	}
 
-	user, err := h.service.Verify2FA(req)
+	user, err := h.service.Verify2FA(req, clientInfo(c))
 	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
 		if err.Error() == "invalid or expired 2FA code" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired 2FA code"})
 			return
@@ -135,3 +190,136 @@ func (h *Handler) Verify2FA(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// Refresh handles exchanging a refresh token for new tokens
+func (h *Handler) Refresh(c *gin.Context) {
+	var req model.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	tokens, err := h.service.Refresh(req, clientInfo(c))
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+		log.Printf("Error refreshing session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Session refresh error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout handles ending the session of a refresh token or, without one, of the access token
+func (h *Handler) Logout(c *gin.Context) {
+	// The request body is optional
+	var req model.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+	}
+
+	var err error
+	if req.RefreshToken != "" {
+		err = h.service.Logout(req)
+	} else {
+		// Fall back to the session of the access token
+		claims, parseErr := utils.ParseToken(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if parseErr != nil || claims.SessionID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token or valid access token required"})
+			return
+		}
+		err = h.service.RevokeSession(claims.UserID, claims.SessionID)
+	}
+
+	if err != nil {
+		if err.Error() == "session not found" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found or already ended"})
+			return
+		}
+		log.Printf("Error during logout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Logout error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// GetSessions handles getting the user's active sessions
+func (h *Handler) GetSessions(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	sessions, err := h.service.GetSessions(userID.(int), c.GetInt("sessionID"))
+	if err != nil {
+		log.Printf("Error getting sessions: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession handles ending one of the user's sessions
+func (h *Handler) RevokeSession(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse session ID
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.service.RevokeSession(userID.(int), sessionID); err != nil {
+		if err.Error() == "session not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		log.Printf("Error revoking session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session ended"})
+}
+
+// respondThrottled responds to brute-force protection errors; it returns false for other errors
+func respondThrottled(c *gin.Context, err error) bool {
+	switch err.Error() {
+	case "too many attempts":
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+	case "account temporarily locked":
+		c.JSON(http.StatusLocked, gin.H{"error": "Account temporarily locked after too many wrong passwords, try again later"})
+	default:
+		return false
+	}
+	return true
+}
+
+// clientInfo describes the client of a request for its session
+func clientInfo(c *gin.Context) model.ClientInfo {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	return model.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: userAgent,
+	}
+}
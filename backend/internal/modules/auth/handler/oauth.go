@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetOAuthProviders handles listing the providers users can log in with
+func (h *Handler) GetOAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, model.OAuthProvidersResponse{Providers: h.service.OAuthProviders()})
+}
+
+// StartOAuth handles starting a login with a provider
+func (h *Handler) StartOAuth(c *gin.Context) {
+	response, err := h.service.StartOAuth(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if err.Error() == "unknown provider" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+			return
+		}
+		log.Printf("Error starting oauth login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider is unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// OAuthCallback handles finishing a login with the code and state the provider redirected back with
+func (h *Handler) OAuthCallback(c *gin.Context) {
+	var req model.OAuthCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	user, err := h.service.CompleteOAuth(c.Request.Context(), c.Param("provider"), req, clientInfo(c))
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "unknown provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		case "invalid oauth state":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired or was already used, start again"})
+		case "oauth login failed":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login with the provider failed"})
+		case "email not verified by provider":
+			c.JSON(http.StatusForbidden, gin.H{"error": "The provider hasn't verified your email"})
+		case "email already taken":
+			c.JSON(http.StatusConflict, gin.H{"error": "This email is not available"})
+		default:
+			log.Printf("Error during oauth login: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Login error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
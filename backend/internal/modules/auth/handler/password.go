@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForgotPassword handles requesting a password reset link
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req model.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	if err := h.service.ForgotPassword(req, c.ClientIP()); err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		log.Printf("Error requesting password reset: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset error"})
+		return
+	}
+
+	// The same answer for unknown emails
+	c.JSON(http.StatusOK, gin.H{"message": "If an account with this email exists, a password reset link has been sent"})
+}
+
+// ResetPassword handles setting a new password with a reset token
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req model.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	if err := h.service.ResetPassword(req); err != nil {
+		if err.Error() == "invalid or expired reset token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		log.Printf("Error resetting password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed; sign in with the new password"})
+}
+
+// ChangePassword handles changing the password of the authenticated user
+func (h *Handler) ChangePassword(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	err := h.service.ChangePassword(userID.(int), c.GetInt("sessionID"), req, c.ClientIP())
+	if err != nil {
+		if respondThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "invalid current password":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		case "new password must differ from the current one":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			log.Printf("Error changing password: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Password change error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed; other devices were signed out"})
+}
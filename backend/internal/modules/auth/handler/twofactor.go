@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTwoFactorStatus handles getting the second factor settings of the authenticated user
+func (h *Handler) GetTwoFactorStatus(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	status, err := h.service.GetTwoFactorStatus(userID.(int))
+	if err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error getting two-factor status: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting two-factor settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTOTP handles starting to add an authenticator app
+func (h *Handler) EnrollTOTP(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.PasswordConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	enrollment, err := h.service.EnrollTOTP(userID.(int), req, c.ClientIP())
+	if err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error enrolling authenticator: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error adding authenticator"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTOTP handles finishing adding an authenticator app with a code from it
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.ConfirmTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	codes, err := h.service.ConfirmTOTP(userID.(int), req, c.ClientIP())
+	if err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error confirming authenticator: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error adding authenticator"})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// DisableTOTP handles removing the authenticator app
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.PasswordConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	if err := h.service.DisableTOTP(userID.(int), req, c.ClientIP()); err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error disabling authenticator: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing authenticator"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Authenticator removed; sign-in codes will be sent by email"})
+}
+
+// SetTwoFactorMethod handles choosing the second factor
+func (h *Handler) SetTwoFactorMethod(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.SetTwoFactorMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	if err := h.service.SetTwoFactorMethod(userID.(int), req, c.ClientIP()); err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error setting two-factor method: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error changing two-factor method"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor method changed"})
+}
+
+// RegenerateRecoveryCodes handles replacing the recovery codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req model.PasswordConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(userID.(int), req, c.ClientIP())
+	if err != nil {
+		if respondTwoFactorError(c, err) {
+			return
+		}
+		log.Printf("Error regenerating recovery codes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating recovery codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// respondTwoFactorError responds to expected second factor errors; it returns false for other errors
+func respondTwoFactorError(c *gin.Context, err error) bool {
+	if respondThrottled(c, err) {
+		return true
+	}
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case "invalid password":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+	case "invalid authenticator code":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid authenticator code"})
+	case "authenticator already enabled":
+		c.JSON(http.StatusConflict, gin.H{"error": "Authenticator is already enabled"})
+	case "authenticator enrollment not started":
+		c.JSON(http.StatusConflict, gin.H{"error": "Start adding an authenticator first"})
+	case "authenticator not enabled":
+		c.JSON(http.StatusConflict, gin.H{"error": "Authenticator is not enabled"})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// EmailChange represents a change of a user's email
+type EmailChange struct {
+	ID              int        `db:"id"`
+	UserID          int        `db:"user_id"`
+	OldEmail        string     `db:"old_email"`
+	NewEmail        string     `db:"new_email"`
+	CodeHash        string     `db:"code_hash"`
+	Attempts        int        `db:"attempts"`
+	RevokeTokenHash string     `db:"revoke_token_hash"`
+	CreatedAt       time.Time  `db:"created_at"`
+	CodeExpiresAt   time.Time  `db:"code_expires_at"`
+	RevocableUntil  time.Time  `db:"revocable_until"`
+	ConfirmedAt     *time.Time `db:"confirmed_at"`
+	RevokedAt       *time.Time `db:"revoked_at"`
+}
+
+// ChangeEmailRequest represents the data needed to start changing the email of a signed-in user
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ConfirmEmailChangeRequest represents the code sent to the new email
+type ConfirmEmailChangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// RevokeEmailChangeRequest represents the token from the link sent to the old email
+type RevokeEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailChangeResponse describes a started or confirmed email change
+type EmailChangeResponse struct {
+	Email          string     `json:"email"`
+	NewEmail       string     `json:"new_email,omitempty"`
+	CodeExpiresAt  *time.Time `json:"code_expires_at,omitempty"`
+	RevocableUntil time.Time  `json:"revocable_until"`
+}
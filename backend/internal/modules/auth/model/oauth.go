@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// OAuthState represents a pending login with an OpenID Connect provider
+type OAuthState struct {
+	StateHash    string    `db:"state_hash"`
+	Provider     string    `db:"provider"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	CreatedAt    time.Time `db:"created_at"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// OAuthProvidersResponse lists the providers users can log in with
+type OAuthProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// OAuthStartResponse is returned when a login with a provider starts; the client sends
+// the user to the authorization URL and keeps the state to pass to the callback
+type OAuthStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// OAuthCallbackRequest represents the code and state the provider redirected back with
+type OAuthCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+	// Device is an optional name of the device shown in the session list
+	Device string `json:"device" binding:"max=100"`
+	// RememberDevice trusts the device for 30 days, so password logins from it skip the second factor
+	RememberDevice bool `json:"remember_device"`
+}
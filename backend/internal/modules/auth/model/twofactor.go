@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// Second factor methods
+const (
+	TwoFactorMethodEmail = "email"
+	TwoFactorMethodTOTP  = "totp"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time
+const RecoveryCodeCount = 10
+
+// TOTPCredential represents the authenticator app of a user
+type TOTPCredential struct {
+	UserID          int       `db:"user_id"`
+	SecretEncrypted string    `db:"secret_encrypted"`
+	Confirmed       bool      `db:"confirmed"`
+	LastUsedStep    int64     `db:"last_used_step"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// TwoFactorStatus describes the second factor settings of a user
+type TwoFactorStatus struct {
+	Method            string `json:"method"`
+	TOTPEnabled       bool   `json:"totp_enabled"`
+	RecoveryCodesLeft int    `json:"recovery_codes_left"`
+}
+
+// TOTPEnrollment is returned when a user starts adding an authenticator app.
+// The provisioning URI is shown as a QR code; the secret is for typing in by hand.
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse returns new recovery codes; they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ConfirmTOTPRequest represents the data needed to finish adding an authenticator app
+type ConfirmTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// PasswordConfirmRequest represents the current password confirming a sensitive change
+type PasswordConfirmRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// SetTwoFactorMethodRequest represents the data needed to choose the second factor
+type SetTwoFactorMethodRequest struct {
+	Password string `json:"password" binding:"required"`
+	Method   string `json:"method" binding:"required,oneof=email totp"`
+}
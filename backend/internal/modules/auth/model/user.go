@@ -13,13 +13,51 @@ type User struct {
 	Avatar       string    `db:"avatar" json:"avatar"`
 	IsVerified   bool      `db:"is_verified" json:"is_verified"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+
+	FailedLoginAttempts int        `db:"failed_login_attempts" json:"-"`
+	LockedUntil         *time.Time `db:"locked_until" json:"-"`
+	TwoFactorMethod     string     `db:"two_factor_method" json:"two_factor_method"`
+	Role                string     `db:"role" json:"role"`
+}
+
+// Roles of users; what each role may do is stored in role_permissions
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// IsLocked reports whether the account is temporarily locked after failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && u.LockedUntil.After(time.Now())
 }
 
-// TwoFactorCode represents a 2FA verification code
+// Code purposes; a user has at most one active code per purpose
+const (
+	CodePurposeVerification = "verification"
+	CodePurpose2FA          = "2fa"
+	// CodePurposeTOTPLogin is the login token proving the password step of a TOTP login
+	CodePurposeTOTPLogin = "totp-login"
+)
+
+// Throttled authentication actions
+const (
+	AuthActionLogin     = "login"
+	AuthActionVerify    = "verify"
+	AuthActionVerify2FA = "verify-2fa"
+	// AuthActionForgotPassword counts every password reset request, not only failed ones
+	AuthActionForgotPassword = "forgot-password"
+	// AuthActionReauth counts wrong passwords and codes confirming a sensitive change
+	AuthActionReauth = "reauth"
+)
+
+// TwoFactorCode represents a hashed email verification or 2FA code
 type TwoFactorCode struct {
 	ID        int       `db:"id"`
 	UserID    int       `db:"user_id"`
-	Code      string    `db:"code"`
+	CodeHash  string    `db:"code_hash"`
+	Purpose   string    `db:"purpose"`
+	Attempts  int       `db:"attempts"`
 	ExpiresAt time.Time `db:"expires_at"`
 }
 
@@ -40,6 +78,79 @@ type UserResponse struct {
 	Name     string `json:"name"`
 	LastName string `json:"last_name"`
 	Token    string `json:"token"`
+	Role     string `json:"role,omitempty"`
+
+	// The access token expires quickly; the refresh token gets a new pair from /auth/refresh
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+	RefreshToken   string     `json:"refresh_token,omitempty"`
+
+	// After the password step: the second factor to ask for and, for TOTP, the token to pass to /auth/verify-2fa
+	TwoFactorMethod string `json:"two_factor_method,omitempty"`
+	LoginToken      string `json:"login_token,omitempty"`
+
+	// Issued when the user asks to remember the device; pass it to /auth/login to skip the second factor
+	DeviceToken          string     `json:"device_token,omitempty"`
+	DeviceTokenExpiresAt *time.Time `json:"device_token_expires_at,omitempty"`
+
+	// Set when logging in cancelled the deletion of the account
+	AccountRestored bool `json:"account_restored,omitempty"`
+}
+
+// TokenResponse is returned when a session is refreshed
+type TokenResponse struct {
+	Token          string    `json:"token"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+	RefreshToken   string    `json:"refresh_token"`
+}
+
+// Session represents a login session of a user on a device
+type Session struct {
+	ID               int        `db:"id" json:"id"`
+	UserID           int        `db:"user_id" json:"-"`
+	RefreshTokenHash string     `db:"refresh_token_hash" json:"-"`
+	PreviousHash     *string    `db:"previous_token_hash" json:"-"`
+	Device           string     `db:"device" json:"device"`
+	IP               string     `db:"ip" json:"ip"`
+	UserAgent        string     `db:"user_agent" json:"user_agent"`
+	Provider         string     `db:"provider" json:"provider,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt       time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt        *time.Time `db:"revoked_at" json:"-"`
+	IsCurrent        bool       `db:"-" json:"is_current"`
+}
+
+// TrustedDevice represents a device where a user skips the second factor
+type TrustedDevice struct {
+	ID         int        `db:"id" json:"id"`
+	UserID     int        `db:"user_id" json:"-"`
+	Device     string     `db:"device" json:"device"`
+	IP         string     `db:"ip" json:"ip"`
+	UserAgent  string     `db:"user_agent" json:"user_agent"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	LastUsedAt time.Time  `db:"last_used_at" json:"last_used_at"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at" json:"-"`
+}
+
+// ClientInfo describes the client a session is used from
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+	// Provider is the OpenID Connect provider the user logged in with, empty for a password login
+	Provider string
+}
+
+// RefreshRequest represents the data needed to refresh a session
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest represents the data needed to log out; without a refresh token
+// the session of the access token in the Authorization header is ended
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 // RegisterRequest represents the data needed for user registration
@@ -54,6 +165,10 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// DeviceToken from an earlier /auth/verify-2fa skips the second factor on a trusted device
+	DeviceToken string `json:"device_token"`
+	// Device is an optional name of the device shown in the session list
+	Device string `json:"device" binding:"max=100"`
 }
 
 // VerifyRequest represents the data needed for verification code validation
@@ -62,8 +177,32 @@ type VerifyRequest struct {
 	Code  string `json:"code" binding:"required"`
 }
 
+// ForgotPasswordRequest represents the data needed to request a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the data needed to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePasswordRequest represents the data needed to change the password of a signed-in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
 // Verify2FARequest represents the data needed for two-factor authentication
 type Verify2FARequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Code  string `json:"code" binding:"required"`
+	// Device is an optional name of the device shown in the session list
+	Device string `json:"device" binding:"max=100"`
+	// LoginToken comes from /auth/login when the second factor is TOTP; the code is then
+	// an authenticator code or a recovery code
+	LoginToken string `json:"login_token"`
+	// RememberDevice trusts the device for 30 days, so logins from it skip the second factor
+	RememberDevice bool `json:"remember_device"`
 }
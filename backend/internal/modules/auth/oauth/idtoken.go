@@ -0,0 +1,196 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// IDClaims are the claims of a verified ID token that identify the user
+type IDClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+	Name          string
+}
+
+// idTokenClaims are the raw claims of an ID token
+type idTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+	Name          string      `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// jwk is an RSA key of a JWKS document
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the provider's signing keys by key ID
+type keySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// jwksRefreshInterval limits refetching the keys when a token has an unknown key ID
+const jwksRefreshInterval = time.Minute
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDClaims, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims idTokenClaims
+	token, err := jwt.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unsupported ID token signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error verifying ID token: %w", err)
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid ID token")
+	}
+
+	if !claims.VerifyIssuer(discovery.Issuer, true) {
+		return nil, fmt.Errorf("ID token issuer %q is not %q", claims.Issuer, discovery.Issuer)
+	}
+	if !claims.VerifyAudience(p.config.ClientID, true) {
+		return nil, errors.New("ID token was issued for another client")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("ID token has no expiry")
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("ID token nonce doesn't match")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return &IDClaims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: isTrue(claims.EmailVerified),
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+		Name:          claims.Name,
+	}, nil
+}
+
+// signingKey gets a signing key by ID, refetching the keys if the provider rotated them
+func (p *Provider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	keys := p.keys
+	p.mu.Unlock()
+
+	if keys != nil {
+		if key := keys.find(kid); key != nil {
+			return key, nil
+		}
+		if time.Since(keys.fetchedAt) < jwksRefreshInterval {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if key := keys.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys fetches the provider's JWKS document
+func (p *Provider) fetchKeys(ctx context.Context) (*keySet, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &document); err != nil {
+		return nil, fmt.Errorf("error fetching signing keys: %w", err)
+	}
+
+	keys := &keySet{keys: make(map[string]*rsa.PublicKey), fetchedAt: time.Now()}
+	for _, key := range document.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		publicKey, err := key.rsaPublicKey()
+		if err != nil {
+			continue
+		}
+		keys.keys[key.Kid] = publicKey
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return keys, nil
+}
+
+// find gets a key by ID; without an ID, the only key of the set
+func (k *keySet) find(kid string) *rsa.PublicKey {
+	if kid == "" && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key
+		}
+	}
+	return k.keys[kid]
+}
+
+// rsaPublicKey decodes the modulus and exponent of an RSA JWK
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key exponent: %w", err)
+	}
+
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("invalid key exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
+
+// isTrue reads email_verified, which some providers send as a string
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
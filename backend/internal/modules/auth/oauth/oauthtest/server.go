@@ -0,0 +1,214 @@
+// Package oauthtest provides a local OpenID Connect provider for testing logins with
+// the oauth package, like net/http/httptest does for HTTP servers.
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"FurniSwap/internal/modules/auth/oauth"
+	"FurniSwap/pkg/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Credentials of the client registered with the provider
+const (
+	ClientID     = "furniswap"
+	ClientSecret = "secret"
+	keyID        = "test-key"
+)
+
+// Server is a local OpenID Connect provider. It serves discovery, JWKS and token endpoints;
+// Login plays the user logging in at the authorization endpoint.
+type Server struct {
+	URL string
+
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]pendingCode
+}
+
+// pendingCode is an issued authorization code waiting to be exchanged
+type pendingCode struct {
+	redirectURI   string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+// NewServer starts a provider that is closed when the test ends
+func NewServer(t *testing.T) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating provider key: %v", err)
+	}
+
+	s := &Server{t: t, key: key, codes: make(map[string]pendingCode)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	mux.HandleFunc("/token", s.handleToken)
+	s.server = httptest.NewServer(mux)
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// ProviderConfig configures a provider with the given name for this server
+func (s *Server) ProviderConfig(name string) config.OIDCProviderConfig {
+	return config.OIDCProviderConfig{
+		Name:         name,
+		IssuerURL:    s.URL,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		RedirectURL:  "http://localhost:3000/oauth/" + name + "/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+// Client returns an HTTP client for the server
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Claims returns the claims of a valid ID token for the given nonce, for a verified user
+func (s *Server) Claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            ClientID,
+		"sub":            "user-123",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"given_name":     "Ivan",
+		"family_name":    "Petrov",
+	}
+}
+
+// Sign signs ID token claims with the key published in the server's JWKS
+func (s *Server) Sign(claims jwt.MapClaims) string {
+	s.t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		s.t.Fatalf("signing ID token: %v", err)
+	}
+	return signed
+}
+
+// Login plays the user logging in at the authorization URL. It checks the authorization
+// request and returns the code and state the provider redirects back with; the ID token
+// for the code has the default claims with the overrides applied.
+func (s *Server) Login(authURL string, overrides jwt.MapClaims) (code, state string) {
+	s.t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		s.t.Fatalf("parsing authorization URL: %v", err)
+	}
+	query := parsed.Query()
+	if parsed.Scheme+"://"+parsed.Host != s.URL || parsed.Path != "/authorize" {
+		s.t.Fatalf("authorization URL %s isn't this provider's", authURL)
+	}
+	if query.Get("response_type") != "code" || query.Get("client_id") != ClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" ||
+		query.Get("state") == "" || query.Get("nonce") == "" {
+		s.t.Fatalf("invalid authorization request %s", authURL)
+	}
+
+	claims := s.Claims(query.Get("nonce"))
+	for key, value := range overrides {
+		claims[key] = value
+	}
+
+	code, err = oauth.RandomString()
+	if err != nil {
+		s.t.Fatalf("generating code: %v", err)
+	}
+
+	s.mu.Lock()
+	s.codes[code] = pendingCode{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		claims:        claims,
+	}
+	s.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleToken exchanges a code once, for the client that requested it with the matching PKCE verifier
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	form := r.PostForm
+	if form.Get("client_id") != ClientID || form.Get("client_secret") != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	pending, ok := s.codes[form.Get("code")]
+	delete(s.codes, form.Get("code"))
+	s.mu.Unlock()
+
+	if !ok || form.Get("grant_type") != "authorization_code" || form.Get("redirect_uri") != pending.redirectURI ||
+		oauth.CodeChallenge(form.Get("code_verifier")) != pending.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"id_token":     s.Sign(pending.claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
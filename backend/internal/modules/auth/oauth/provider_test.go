@@ -0,0 +1,161 @@
+package oauth_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"FurniSwap/internal/modules/auth/oauth"
+	"FurniSwap/internal/modules/auth/oauth/oauthtest"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// startLogin starts a login with the provider the way the auth service does and returns the
+// authorization URL with the PKCE verifier and nonce kept by the server
+func startLogin(t *testing.T, p *oauth.Provider) (authURL, verifier, nonce string) {
+	t.Helper()
+
+	verifier, _ = oauth.RandomString()
+	nonce, _ = oauth.RandomString()
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", nonce, oauth.CodeChallenge(verifier))
+	if err != nil {
+		t.Fatalf("building authorization URL: %v", err)
+	}
+	return authURL, verifier, nonce
+}
+
+func TestLoginFlow(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	p := oauth.NewProvider(server.ProviderConfig("mock"), server.Client())
+
+	authURL, verifier, nonce := startLogin(t, p)
+	code, state := server.Login(authURL, nil)
+	if state != "state-1" {
+		t.Errorf("state = %q, want state-1", state)
+	}
+
+	tokens, err := p.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatalf("exchanging code: %v", err)
+	}
+
+	claims, err := p.VerifyIDToken(context.Background(), tokens.IDToken, nonce)
+	if err != nil {
+		t.Fatalf("verifying ID token: %v", err)
+	}
+	if claims.Subject != "user-123" || claims.Email != "user@example.com" || !claims.EmailVerified ||
+		claims.GivenName != "Ivan" || claims.FamilyName != "Petrov" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	// Codes work once
+	if _, err := p.Exchange(context.Background(), code, verifier); err == nil {
+		t.Error("exchanging a used code succeeded")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	p := oauth.NewProvider(server.ProviderConfig("mock"), server.Client())
+
+	authURL, _, _ := startLogin(t, p)
+	code, _ := server.Login(authURL, nil)
+
+	if _, err := p.Exchange(context.Background(), code, "another-verifier"); err == nil {
+		t.Error("exchange with a wrong PKCE verifier succeeded")
+	}
+}
+
+func TestVerifyIDTokenRejectsBadTokens(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	p := oauth.NewProvider(server.ProviderConfig("mock"), server.Client())
+	nonce := "nonce-1"
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		token  func() string
+		wantOK bool
+	}{
+		{"valid", func() string { return server.Sign(server.Claims(nonce)) }, true},
+		{"wrong nonce", func() string { return server.Sign(server.Claims("other")) }, false},
+		{"wrong audience", func() string {
+			claims := server.Claims(nonce)
+			claims["aud"] = "another-client"
+			return server.Sign(claims)
+		}, false},
+		{"wrong issuer", func() string {
+			claims := server.Claims(nonce)
+			claims["iss"] = "https://evil.example.com"
+			return server.Sign(claims)
+		}, false},
+		{"expired", func() string {
+			claims := server.Claims(nonce)
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return server.Sign(claims)
+		}, false},
+		{"no expiry", func() string {
+			claims := server.Claims(nonce)
+			delete(claims, "exp")
+			return server.Sign(claims)
+		}, false},
+		{"signed by another key", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, server.Claims(nonce))
+			token.Header["kid"] = "test-key"
+			signed, _ := token.SignedString(otherKey)
+			return signed
+		}, false},
+		{"HMAC signed", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, server.Claims(nonce))
+			signed, _ := token.SignedString([]byte(oauthtest.ClientSecret))
+			return signed
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := p.VerifyIDToken(context.Background(), tt.token(), nonce)
+			if (err == nil) != tt.wantOK {
+				t.Errorf("VerifyIDToken() error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEmailVerifiedAsString(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	p := oauth.NewProvider(server.ProviderConfig("mock"), server.Client())
+
+	claims := server.Claims("nonce-1")
+	claims["email_verified"] = "true"
+	verified, err := p.VerifyIDToken(context.Background(), server.Sign(claims), "nonce-1")
+	if err != nil || !verified.EmailVerified {
+		t.Errorf("email_verified \"true\": claims %+v, error %v", verified, err)
+	}
+
+	claims["email_verified"] = "false"
+	verified, err = p.VerifyIDToken(context.Background(), server.Sign(claims), "nonce-1")
+	if err != nil || verified.EmailVerified {
+		t.Errorf("email_verified \"false\": claims %+v, error %v", verified, err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oauthtest.NewServer(t)
+	// The same server under another name serves a discovery document of a different issuer
+	cfg := server.ProviderConfig("mock")
+	cfg.IssuerURL = strings.Replace(cfg.IssuerURL, "127.0.0.1", "localhost", 1)
+	p := oauth.NewProvider(cfg, server.Client())
+
+	if _, err := p.AuthCodeURL(context.Background(), "state", "nonce", "challenge"); err == nil {
+		t.Error("discovery with a mismatched issuer succeeded")
+	}
+}
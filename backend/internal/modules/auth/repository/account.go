@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// CancelAccountDeletion restores an account deleted by the user that wasn't purged yet, showing
+// the listings hidden on deletion again. It reports whether there was a deletion to cancel.
+func (r *Repository) CancelAccountDeletion(userID int) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hiddenListingIDs pq.Int64Array
+	err = tx.Get(&hiddenListingIDs, `
+		DELETE FROM account_deletions WHERE user_id = $1 AND purged_at IS NULL
+		RETURNING hidden_listing_ids
+	`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		log.Printf("Error cancelling account deletion: %v", err)
+		return false, fmt.Errorf("error cancelling account deletion: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE listings SET status = 'active', updated_at = NOW()
+		WHERE id = ANY($1) AND user_id = $2 AND status = 'hidden'
+	`, hiddenListingIDs, userID)
+	if err != nil {
+		log.Printf("Error restoring hidden listings: %v", err)
+		return false, fmt.Errorf("error restoring hidden listings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
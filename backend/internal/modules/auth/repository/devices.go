@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"fmt"
+	"log"
+	"time"
+)
+
+// CreateTrustedDevice trusts a device of a user until expiresAt and returns its ID
+func (r *Repository) CreateTrustedDevice(userID int, client model.ClientInfo, expiresAt time.Time) (int, error) {
+	var deviceID int
+	err := r.db.QueryRow(`
+		INSERT INTO trusted_devices (user_id, device, ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id
+	`, userID, client.Device, client.IP, client.UserAgent, expiresAt).Scan(&deviceID)
+	if err != nil {
+		log.Printf("Error creating trusted device: %v", err)
+		return 0, fmt.Errorf("error creating trusted device: %w", err)
+	}
+	return deviceID, nil
+}
+
+// UseTrustedDevice records a login from a trusted device; it returns false if the device isn't trusted anymore
+func (r *Repository) UseTrustedDevice(deviceID, userID int, client model.ClientInfo) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE trusted_devices SET last_used_at = NOW(), ip = $3, user_agent = $4
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, deviceID, userID, client.IP, client.UserAgent)
+	if err != nil {
+		log.Printf("Error using trusted device: %v", err)
+		return false, fmt.Errorf("error using trusted device: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetTrustedDevices gets the trusted devices of a user, most recently used first
+func (r *Repository) GetTrustedDevices(userID int) ([]model.TrustedDevice, error) {
+	devices := []model.TrustedDevice{}
+	err := r.db.Select(&devices, `
+		SELECT * FROM trusted_devices
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting trusted devices: %v", err)
+		return nil, fmt.Errorf("error getting trusted devices: %w", err)
+	}
+	return devices, nil
+}
+
+// RevokeTrustedDevice stops trusting a device of a user; it returns false if the user has no such device
+func (r *Repository) RevokeTrustedDevice(deviceID, userID int) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE trusted_devices SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, deviceID, userID)
+	if err != nil {
+		log.Printf("Error revoking trusted device: %v", err)
+		return false, fmt.Errorf("error revoking trusted device: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeTrustedDevices stops trusting all devices of a user
+func (r *Repository) RevokeTrustedDevices(userID int) error {
+	_, err := r.db.Exec(`
+		UPDATE trusted_devices SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	if err != nil {
+		log.Printf("Error revoking trusted devices: %v", err)
+		return fmt.Errorf("error revoking trusted devices: %w", err)
+	}
+	return nil
+}
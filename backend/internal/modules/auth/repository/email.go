@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// EmailTaken checks if an email belongs to a user or is reserved as the old email of a revocable change
+func (r *Repository) EmailTaken(email string) (bool, error) {
+	var taken bool
+	err := r.db.Get(&taken, `
+		SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)
+		    OR EXISTS(
+		        SELECT 1 FROM email_changes
+		        WHERE old_email = $1 AND revoked_at IS NULL AND revocable_until > NOW()
+		    )
+	`, email)
+	if err != nil {
+		log.Printf("Error checking if email is taken: %v", err)
+		return false, fmt.Errorf("error checking if email is taken: %w", err)
+	}
+	return taken, nil
+}
+
+// CreateEmailChange starts an email change, replacing the user's unconfirmed one
+func (r *Repository) CreateEmailChange(change model.EmailChange) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM email_changes WHERE user_id = $1 AND confirmed_at IS NULL AND revoked_at IS NULL
+	`, change.UserID)
+	if err != nil {
+		log.Printf("Error deleting pending email changes: %v", err)
+		return fmt.Errorf("error deleting pending email changes: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO email_changes (user_id, old_email, new_email, code_hash, revoke_token_hash, code_expires_at, revocable_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, change.UserID, change.OldEmail, change.NewEmail, change.CodeHash, change.RevokeTokenHash,
+		change.CodeExpiresAt, change.RevocableUntil)
+	if err != nil {
+		log.Printf("Error creating email change: %v", err)
+		return fmt.Errorf("error creating email change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ClaimEmailChangeAttempt counts a guess of the code of a user's pending email change before
+// the code is compared, so concurrent guesses share the limit, and returns the change. It
+// returns sql.ErrNoRows if there's no pending change or it has no attempts left.
+func (r *Repository) ClaimEmailChangeAttempt(userID, maxAttempts int) (*model.EmailChange, error) {
+	var change model.EmailChange
+	err := r.db.Get(&change, `
+		UPDATE email_changes SET attempts = attempts + 1
+		WHERE user_id = $1 AND confirmed_at IS NULL AND revoked_at IS NULL AND code_expires_at > NOW()
+		  AND attempts < $2
+		RETURNING *
+	`, userID, maxAttempts)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error counting email change attempt: %v", err)
+		}
+		return nil, fmt.Errorf("error counting email change attempt: %w", err)
+	}
+	return &change, nil
+}
+
+// CancelEmailChange ends an unconfirmed email change
+func (r *Repository) CancelEmailChange(changeID int) error {
+	_, err := r.db.Exec(`
+		UPDATE email_changes SET revoked_at = NOW() WHERE id = $1 AND confirmed_at IS NULL
+	`, changeID)
+	if err != nil {
+		log.Printf("Error cancelling email change: %v", err)
+		return fmt.Errorf("error cancelling email change: %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange switches the user to the new email. It returns false if the change
+// was revoked or the user's email changed in the meantime.
+func (r *Repository) ConfirmEmailChange(change *model.EmailChange) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE email_changes SET confirmed_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL AND revoked_at IS NULL
+	`, change.ID)
+	if err != nil {
+		log.Printf("Error confirming email change: %v", err)
+		return false, fmt.Errorf("error confirming email change: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return false, err
+	}
+
+	result, err = tx.Exec(`
+		UPDATE users SET email = $1 WHERE id = $2 AND email = $3
+	`, change.NewEmail, change.UserID, change.OldEmail)
+	if err != nil {
+		// Someone signed up with the new email after it was checked
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return false, errors.New("email already taken")
+		}
+		log.Printf("Error updating user email: %v", err)
+		return false, fmt.Errorf("error updating user email: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return false, err
+	}
+
+	// Codes were sent to the old email
+	if _, err := tx.Exec("DELETE FROM two_factor_codes WHERE user_id = $1", change.UserID); err != nil {
+		log.Printf("Error deleting codes: %v", err)
+		return false, fmt.Errorf("error deleting codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
+
+// GetRevocableEmailChange gets an email change by the hash of its revoke token while it can be revoked
+func (r *Repository) GetRevocableEmailChange(tokenHash string) (*model.EmailChange, error) {
+	var change model.EmailChange
+	err := r.db.Get(&change, `
+		SELECT * FROM email_changes
+		WHERE revoke_token_hash = $1 AND revoked_at IS NULL AND revocable_until > NOW()
+	`, tokenHash)
+	if err != nil {
+		log.Printf("Error getting email change: %v", err)
+		return nil, fmt.Errorf("error getting email change: %w", err)
+	}
+	return &change, nil
+}
+
+// RevokeEmailChange revokes an email change, switching a confirmed one back to the old email.
+// It returns false if the change was revoked already.
+func (r *Repository) RevokeEmailChange(change *model.EmailChange) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE email_changes SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL
+	`, change.ID)
+	if err != nil {
+		log.Printf("Error revoking email change: %v", err)
+		return false, fmt.Errorf("error revoking email change: %w", err)
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return false, err
+	}
+
+	if change.ConfirmedAt != nil {
+		_, err = tx.Exec(`
+			UPDATE users SET email = $1 WHERE id = $2
+		`, change.OldEmail, change.UserID)
+		if err != nil {
+			log.Printf("Error restoring user email: %v", err)
+			return false, fmt.Errorf("error restoring user email: %w", err)
+		}
+		if _, err := tx.Exec("DELETE FROM two_factor_codes WHERE user_id = $1", change.UserID); err != nil {
+			log.Printf("Error deleting codes: %v", err)
+			return false, fmt.Errorf("error deleting codes: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"fmt"
+	"log"
+)
+
+// SaveOAuthState saves a pending login with a provider and forgets expired ones
+func (r *Repository) SaveOAuthState(state model.OAuthState) error {
+	_, err := r.db.Exec(`
+		WITH cleanup AS (
+			DELETE FROM oauth_states WHERE expires_at < NOW()
+		)
+		INSERT INTO oauth_states (state_hash, provider, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		log.Printf("Error saving oauth state: %v", err)
+		return fmt.Errorf("error saving oauth state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOAuthState deletes an unexpired pending login with a provider and returns it.
+// It returns sql.ErrNoRows if there is no such state, so each state works only once.
+func (r *Repository) ConsumeOAuthState(stateHash, provider string) (*model.OAuthState, error) {
+	var state model.OAuthState
+	err := r.db.Get(&state, `
+		DELETE FROM oauth_states
+		WHERE state_hash = $1 AND provider = $2 AND expires_at > NOW()
+		RETURNING *
+	`, stateHash, provider)
+	if err != nil {
+		log.Printf("Error consuming oauth state: %v", err)
+		return nil, fmt.Errorf("error consuming oauth state: %w", err)
+	}
+	return &state, nil
+}
+
+// GetUserIDByIdentity gets the user linked to an external identity; it returns sql.ErrNoRows if none is
+func (r *Repository) GetUserIDByIdentity(provider, subject string) (int, error) {
+	var userID int
+	err := r.db.Get(&userID, `
+		SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2
+	`, provider, subject)
+	if err != nil {
+		log.Printf("Error getting user by identity: %v", err)
+		return 0, fmt.Errorf("error getting user by identity: %w", err)
+	}
+	return userID, nil
+}
+
+// LinkIdentity links an external identity to a user
+func (r *Repository) LinkIdentity(userID int, provider, subject, email string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)
+	`, userID, provider, subject, email)
+	if err != nil {
+		log.Printf("Error linking identity: %v", err)
+		return fmt.Errorf("error linking identity: %w", err)
+	}
+	return nil
+}
+
+// CreateUserWithIdentity creates a verified user signed up through a provider together with its identity
+func (r *Repository) CreateUserWithIdentity(user model.User, provider, subject string) (int, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(`
+		INSERT INTO users (email, password_hash, name, last_name, city, avatar, is_verified)
+		VALUES ($1, $2, $3, $4, $5, $6, TRUE) RETURNING id
+	`, user.Email, user.PasswordHash, user.Name, user.LastName, user.City, user.Avatar).Scan(&userID)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		return 0, fmt.Errorf("error creating user: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)
+	`, userID, provider, subject, user.Email)
+	if err != nil {
+		log.Printf("Error linking identity: %v", err)
+		return 0, fmt.Errorf("error linking identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return userID, nil
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"FurniSwap/internal/modules/auth/model"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"time"
@@ -57,22 +59,13 @@ func (r *Repository) CreateUser(email, passwordHash, name, lastName, city, avata
 	return userID, nil
 }
 
-// UserExists checks if a user with the given email exists
-func (r *Repository) UserExists(email string) (bool, error) {
-	var exists bool
-	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", email)
-	if err != nil {
-		log.Printf("Error checking if user exists: %v", err)
-		return false, fmt.Errorf("error checking if user exists: %w", err)
-	}
-	return exists, nil
-}
-
-// SaveVerificationCode saves a verification code for a user
-func (r *Repository) SaveVerificationCode(userID int, code string, expiresAt time.Time) error {
+// SaveVerificationCode saves the hash of a code for a user, replacing the user's active code for the same purpose
+func (r *Repository) SaveVerificationCode(userID int, purpose, codeHash string, expiresAt time.Time) error {
 	_, err := r.db.Exec(`
-		INSERT INTO two_factor_codes (user_id, code, expires_at) VALUES ($1, $2, $3)
-	`, userID, code, expiresAt)
+		INSERT INTO two_factor_codes (user_id, purpose, code_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, purpose) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash, expires_at = EXCLUDED.expires_at, attempts = 0
+	`, userID, purpose, codeHash, expiresAt)
 	if err != nil {
 		log.Printf("Error saving verification code: %v", err)
 		return fmt.Errorf("error saving verification code: %w", err)
@@ -80,19 +73,76 @@ func (r *Repository) SaveVerificationCode(userID int, code string, expiresAt tim
 	return nil
 }
 
-// VerifyCode checks if a verification code is valid
-func (r *Repository) VerifyCode(email, code string) (int, error) {
-	var userID int
-	err := r.db.Get(&userID, `
-		SELECT u.id FROM users u
-		JOIN two_factor_codes tfc ON u.id = tfc.user_id
-		WHERE u.email = $1 AND tfc.code = $2 AND tfc.expires_at > NOW()
-	`, email, code)
+// GetActiveCode gets the unexpired code of a user with the given email for a purpose
+func (r *Repository) GetActiveCode(email, purpose string) (*model.TwoFactorCode, error) {
+	var code model.TwoFactorCode
+	err := r.db.Get(&code, `
+		SELECT tfc.id, tfc.user_id, tfc.code_hash, tfc.purpose, tfc.attempts, tfc.expires_at
+		FROM two_factor_codes tfc
+		JOIN users u ON u.id = tfc.user_id
+		WHERE u.email = $1 AND tfc.purpose = $2 AND tfc.expires_at > NOW()
+	`, email, purpose)
 	if err != nil {
-		log.Printf("Error verifying code: %v", err)
-		return 0, fmt.Errorf("error verifying code: %w", err)
+		log.Printf("Error getting verification code: %v", err)
+		return nil, fmt.Errorf("error getting verification code: %w", err)
 	}
-	return userID, nil
+	return &code, nil
+}
+
+// ClaimCodeAttempt counts a guess of the unexpired code of a user with the given email for a
+// purpose and returns the code to compare the guess with. The guess is counted before it's
+// checked, in one statement, so concurrent guesses can't get past maxAttempts. It returns
+// sql.ErrNoRows if there is no such code or it has no attempts left.
+func (r *Repository) ClaimCodeAttempt(email, purpose string, maxAttempts int) (*model.TwoFactorCode, error) {
+	var code model.TwoFactorCode
+	err := r.db.Get(&code, `
+		UPDATE two_factor_codes tfc SET attempts = tfc.attempts + 1
+		FROM users u
+		WHERE u.id = tfc.user_id AND u.email = $1 AND tfc.purpose = $2
+		  AND tfc.expires_at > NOW() AND tfc.attempts < $3
+		RETURNING tfc.id, tfc.user_id, tfc.code_hash, tfc.purpose, tfc.attempts, tfc.expires_at
+	`, email, purpose, maxAttempts)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error counting code attempt: %v", err)
+		}
+		return nil, fmt.Errorf("error counting code attempt: %w", err)
+	}
+	return &code, nil
+}
+
+// AddCodeAttempt counts a guess of a code before it's checked and returns the number of
+// guesses so far. It returns false if the code expired, was used or has no attempts left.
+func (r *Repository) AddCodeAttempt(codeID, maxAttempts int) (int, bool, error) {
+	var attempts int
+	err := r.db.Get(&attempts, `
+		UPDATE two_factor_codes SET attempts = attempts + 1
+		WHERE id = $1 AND expires_at > NOW() AND attempts < $2
+		RETURNING attempts
+	`, codeID, maxAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		log.Printf("Error counting code attempt: %v", err)
+		return 0, false, fmt.Errorf("error counting code attempt: %w", err)
+	}
+	return attempts, true, nil
+}
+
+// DeleteCode deletes a verification code. It returns false if the code was already deleted,
+// so a code is used up only once even when checked concurrently.
+func (r *Repository) DeleteCode(codeID int) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM two_factor_codes WHERE id = $1", codeID)
+	if err != nil {
+		log.Printf("Error deleting verification code: %v", err)
+		return false, fmt.Errorf("error deleting verification code: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
 }
 
 // SetUserVerified sets a user as verified
@@ -105,12 +155,252 @@ func (r *Repository) SetUserVerified(userID int) error {
 	return nil
 }
 
-// DeleteVerificationCodes deletes all verification codes for a user
-func (r *Repository) DeleteVerificationCodes(userID int) error {
-	_, err := r.db.Exec("DELETE FROM two_factor_codes WHERE user_id = $1", userID)
+// RecordAuthFailure records a failed authentication attempt and forgets attempts older than a day
+func (r *Repository) RecordAuthFailure(action, email, ip string) error {
+	_, err := r.db.Exec(`
+		WITH cleanup AS (
+			DELETE FROM auth_failures WHERE created_at < NOW() - INTERVAL '1 day'
+		)
+		INSERT INTO auth_failures (action, email, ip) VALUES ($1, $2, $3)
+	`, action, email, ip)
 	if err != nil {
-		log.Printf("Error deleting verification codes: %v", err)
-		return fmt.Errorf("error deleting verification codes: %w", err)
+		log.Printf("Error recording auth failure: %v", err)
+		return fmt.Errorf("error recording auth failure: %w", err)
 	}
 	return nil
 }
+
+// CountAuthFailures counts failed attempts of an action since a time, for the email and for the IP
+func (r *Repository) CountAuthFailures(action, email, ip string, since time.Time) (int, int, error) {
+	var counts struct {
+		Email int `db:"email"`
+		IP    int `db:"ip"`
+	}
+	err := r.db.Get(&counts, `
+		SELECT COUNT(*) FILTER (WHERE email = $2) AS email,
+		       COUNT(*) FILTER (WHERE ip = $3) AS ip
+		FROM auth_failures
+		WHERE action = $1 AND created_at > $4 AND (email = $2 OR ip = $3)
+	`, action, email, ip, since)
+	if err != nil {
+		log.Printf("Error counting auth failures: %v", err)
+		return 0, 0, fmt.Errorf("error counting auth failures: %w", err)
+	}
+	return counts.Email, counts.IP, nil
+}
+
+// AddFailedLogin counts a wrong password of a user. When the count reaches maxAttempts
+// the account is locked until lockedUntil, the count starts over and true is returned.
+func (r *Repository) AddFailedLogin(userID, maxAttempts int, lockedUntil time.Time) (bool, error) {
+	var locked bool
+	err := r.db.Get(&locked, `
+		UPDATE users
+		SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END,
+		    locked_until = CASE WHEN failed_login_attempts + 1 >= $2 THEN $3 ELSE locked_until END
+		WHERE id = $1
+		RETURNING failed_login_attempts = 0
+	`, userID, maxAttempts, lockedUntil)
+	if err != nil {
+		log.Printf("Error counting failed login: %v", err)
+		return false, fmt.Errorf("error counting failed login: %w", err)
+	}
+	return locked, nil
+}
+
+// ResetFailedLogins clears the wrong password count of a user after a successful login
+func (r *Repository) ResetFailedLogins(userID int) error {
+	_, err := r.db.Exec("UPDATE users SET failed_login_attempts = 0 WHERE id = $1 AND failed_login_attempts > 0", userID)
+	if err != nil {
+		log.Printf("Error resetting failed logins: %v", err)
+		return fmt.Errorf("error resetting failed logins: %w", err)
+	}
+	return nil
+}
+
+// UpdatePassword sets a new password hash for a user and lifts a lockout
+func (r *Repository) UpdatePassword(userID int, passwordHash string) error {
+	_, err := r.db.Exec(`
+		UPDATE users SET password_hash = $1, failed_login_attempts = 0, locked_until = NULL WHERE id = $2
+	`, passwordHash, userID)
+	if err != nil {
+		log.Printf("Error updating password: %v", err)
+		return fmt.Errorf("error updating password: %w", err)
+	}
+	return nil
+}
+
+// SavePasswordResetToken saves the hash of a password reset token, replacing the user's earlier tokens
+func (r *Repository) SavePasswordResetToken(userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_reset_tokens WHERE user_id = $1", userID); err != nil {
+		log.Printf("Error deleting password reset tokens: %v", err)
+		return fmt.Errorf("error deleting password reset tokens: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Error saving password reset token: %v", err)
+		return fmt.Errorf("error saving password reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// ConsumePasswordResetToken deletes an unexpired password reset token and returns its user ID.
+// It returns sql.ErrNoRows if there is no such token, so each token works only once.
+func (r *Repository) ConsumePasswordResetToken(tokenHash string) (int, error) {
+	var userID int
+	err := r.db.Get(&userID, `
+		DELETE FROM password_reset_tokens
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash)
+	if err != nil {
+		log.Printf("Error consuming password reset token: %v", err)
+		return 0, fmt.Errorf("error consuming password reset token: %w", err)
+	}
+	return userID, nil
+}
+
+// CreateSession creates a login session with the hash of its refresh token
+func (r *Repository) CreateSession(userID int, tokenHash string, client model.ClientInfo, expiresAt time.Time) (int, error) {
+	var sessionID int
+	err := r.db.QueryRow(`
+		INSERT INTO sessions (user_id, refresh_token_hash, device, ip, user_agent, provider, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+	`, userID, tokenHash, client.Device, client.IP, client.UserAgent, client.Provider, expiresAt).Scan(&sessionID)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return 0, fmt.Errorf("error creating session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// IsRecentProviderSession checks if an active session of a user was started with a provider login after since
+func (r *Repository) IsRecentProviderSession(sessionID, userID int, since time.Time) (bool, error) {
+	var recent bool
+	err := r.db.Get(&recent, `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND user_id = $2 AND provider <> '' AND created_at > $3
+			  AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`, sessionID, userID, since)
+	if err != nil {
+		log.Printf("Error checking session provider: %v", err)
+		return false, fmt.Errorf("error checking session provider: %w", err)
+	}
+	return recent, nil
+}
+
+// RotateRefreshToken replaces the refresh token of an active session and returns the session.
+// It returns sql.ErrNoRows if no active session has the token.
+func (r *Repository) RotateRefreshToken(oldHash, newHash string, client model.ClientInfo) (*model.Session, error) {
+	var session model.Session
+	err := r.db.Get(&session, `
+		UPDATE sessions
+		SET refresh_token_hash = $2, previous_token_hash = $1, ip = $3, user_agent = $4, last_used_at = NOW()
+		WHERE refresh_token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING *
+	`, oldHash, newHash, client.IP, client.UserAgent)
+	if err != nil {
+		log.Printf("Error rotating refresh token: %v", err)
+		return nil, fmt.Errorf("error rotating refresh token: %w", err)
+	}
+	return &session, nil
+}
+
+// RevokeSessionByPreviousToken revokes the session whose refresh token was rotated away from the given hash.
+// It returns false if no such session is active.
+func (r *Repository) RevokeSessionByPreviousToken(tokenHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE previous_token_hash = $1 AND revoked_at IS NULL
+	`, tokenHash)
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		return false, fmt.Errorf("error revoking session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeSessionByToken revokes the session with the given refresh token hash; it returns false if there is none
+func (r *Repository) RevokeSessionByToken(tokenHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE refresh_token_hash = $1 AND revoked_at IS NULL
+	`, tokenHash)
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		return false, fmt.Errorf("error revoking session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeSession revokes a session of a user; it returns false if the user has no such active session
+func (r *Repository) RevokeSession(sessionID, userID int) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, sessionID, userID)
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		return false, fmt.Errorf("error revoking session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeOtherSessions revokes all active sessions of a user except one; pass 0 to revoke them all
+func (r *Repository) RevokeOtherSessions(userID, exceptSessionID int) error {
+	_, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL
+	`, userID, exceptSessionID)
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return nil
+}
+
+// GetActiveSessions gets the active sessions of a user, most recently used first
+func (r *Repository) GetActiveSessions(userID int) ([]model.Session, error) {
+	sessions := []model.Session{}
+	err := r.db.Select(&sessions, `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting sessions: %v", err)
+		return nil, fmt.Errorf("error getting sessions: %w", err)
+	}
+	return sessions, nil
+}
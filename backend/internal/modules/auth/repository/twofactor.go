@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// GetTOTPCredential gets the authenticator of a user, confirmed or not
+func (r *Repository) GetTOTPCredential(userID int) (*model.TOTPCredential, error) {
+	var credential model.TOTPCredential
+	err := r.db.Get(&credential, "SELECT * FROM totp_credentials WHERE user_id = $1", userID)
+	if err != nil {
+		log.Printf("Error getting TOTP credential: %v", err)
+		return nil, fmt.Errorf("error getting TOTP credential: %w", err)
+	}
+	return &credential, nil
+}
+
+// SaveTOTPCredential saves an unconfirmed authenticator secret, replacing an earlier unconfirmed one.
+// It returns false if the user already has a confirmed authenticator.
+func (r *Repository) SaveTOTPCredential(userID int, secretEncrypted string) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO totp_credentials (user_id, secret_encrypted) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = NOW()
+		WHERE totp_credentials.confirmed = false
+	`, userID, secretEncrypted)
+	if err != nil {
+		log.Printf("Error saving TOTP credential: %v", err)
+		return false, fmt.Errorf("error saving TOTP credential: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// EnableTOTP confirms the authenticator of a user, makes it the second factor
+// and replaces the user's recovery codes
+func (r *Repository) EnableTOTP(userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE totp_credentials SET confirmed = true, last_used_step = $2 WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		log.Printf("Error confirming TOTP credential: %v", err)
+		return fmt.Errorf("error confirming TOTP credential: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE users SET two_factor_method = $2 WHERE id = $1", userID, model.TwoFactorMethodTOTP)
+	if err != nil {
+		log.Printf("Error setting two-factor method: %v", err)
+		return fmt.Errorf("error setting two-factor method: %w", err)
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// DisableTOTP removes the authenticator and recovery codes of a user and returns to email codes
+func (r *Repository) DisableTOTP(userID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM totp_credentials WHERE user_id = $1", userID); err != nil {
+		log.Printf("Error deleting TOTP credential: %v", err)
+		return fmt.Errorf("error deleting TOTP credential: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		log.Printf("Error deleting recovery codes: %v", err)
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE users SET two_factor_method = $2 WHERE id = $1", userID, model.TwoFactorMethodEmail)
+	if err != nil {
+		log.Printf("Error setting two-factor method: %v", err)
+		return fmt.Errorf("error setting two-factor method: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records the time step of an accepted code; it returns false if
+// a code of this or a later step was already used
+func (r *Repository) UseTOTPStep(userID int, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE totp_credentials SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed = true AND last_used_step < $2
+	`, userID, step)
+	if err != nil {
+		log.Printf("Error using TOTP step: %v", err)
+		return false, fmt.Errorf("error using TOTP step: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// SetTwoFactorMethod sets the second factor of a user
+func (r *Repository) SetTwoFactorMethod(userID int, method string) error {
+	_, err := r.db.Exec("UPDATE users SET two_factor_method = $2 WHERE id = $1", userID, method)
+	if err != nil {
+		log.Printf("Error setting two-factor method: %v", err)
+		return fmt.Errorf("error setting two-factor method: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user
+func (r *Repository) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *sqlx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userID); err != nil {
+		log.Printf("Error deleting recovery codes: %v", err)
+		return fmt.Errorf("error deleting recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		_, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, hash)
+		if err != nil {
+			log.Printf("Error saving recovery code: %v", err)
+			return fmt.Errorf("error saving recovery code: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used; it returns false if there is none
+func (r *Repository) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		log.Printf("Error using recovery code: %v", err)
+		return false, fmt.Errorf("error using recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *Repository) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, "SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL", userID)
+	if err != nil {
+		log.Printf("Error counting recovery codes: %v", err)
+		return 0, fmt.Errorf("error counting recovery codes: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/pkg/utils"
+	"errors"
+	"log"
+	"time"
+)
+
+// trustDevice trusts the client's device and adds its token to a login response
+func (s *Service) trustDevice(userID int, client model.ClientInfo, response *model.UserResponse) error {
+	expiresAt := time.Now().Add(utils.DeviceTokenTTL)
+	deviceID, err := s.repo.CreateTrustedDevice(userID, nameDevice(client), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateDeviceToken(userID, deviceID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	response.DeviceToken = token
+	response.DeviceTokenExpiresAt = &expiresAt
+	return nil
+}
+
+// checkTrustedDevice checks a device token of a user and records its use
+func (s *Service) checkTrustedDevice(userID int, token string, client model.ClientInfo) (bool, error) {
+	claims, err := utils.ParseDeviceToken(token)
+	if err != nil || claims.UserID != userID {
+		log.Printf("Invalid device token presented for user %d", userID)
+		return false, nil
+	}
+
+	return s.repo.UseTrustedDevice(claims.DeviceID, userID, client)
+}
+
+// GetTrustedDevices gets the trusted devices of a user
+func (s *Service) GetTrustedDevices(userID int) ([]model.TrustedDevice, error) {
+	return s.repo.GetTrustedDevices(userID)
+}
+
+// RevokeTrustedDevice stops trusting a device; logins from it need the second factor again
+func (s *Service) RevokeTrustedDevice(userID, deviceID int) error {
+	revoked, err := s.repo.RevokeTrustedDevice(deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("trusted device not found")
+	}
+	return nil
+}
+
+// RevokeTrustedDevices stops trusting all devices of a user
+func (s *Service) RevokeTrustedDevices(userID int) error {
+	return s.repo.RevokeTrustedDevices(userID)
+}
+
+// nameDevice names a device after the user agent if the client didn't name it
+func nameDevice(client model.ClientInfo) model.ClientInfo {
+	if client.Device == "" {
+		client.Device = client.UserAgent
+	}
+	if len(client.Device) > 100 {
+		client.Device = client.Device[:100]
+	}
+	return client
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/pkg/config"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+// emailChangeRevokeWindow is how long the old email can revoke a change
+const emailChangeRevokeWindow = 7 * 24 * time.Hour
+
+// RequestEmailChange starts changing the email of a signed-in user who knows the password.
+// A code goes to the new email and a notice with a revoke link to the old one; the email
+// changes only after the code is confirmed.
+func (s *Service) RequestEmailChange(userID int, req model.ChangeEmailRequest, ip string) (*model.EmailChangeResponse, error) {
+	user, err := s.checkPassword(userID, req.Password, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.NewEmail == user.Email {
+		return nil, errors.New("new email must differ from the current one")
+	}
+
+	taken, err := s.repo.EmailTaken(req.NewEmail)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, errors.New("email already taken")
+	}
+
+	code, err := utils.GenerateCode()
+	if err != nil {
+		return nil, err
+	}
+	revokeToken, revokeTokenHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	change := model.EmailChange{
+		UserID:          user.ID,
+		OldEmail:        user.Email,
+		NewEmail:        req.NewEmail,
+		CodeHash:        utils.HashCode(code),
+		RevokeTokenHash: revokeTokenHash,
+		CodeExpiresAt:   now.Add(codeTTL),
+		RevocableUntil:  now.Add(emailChangeRevokeWindow),
+	}
+	if err := s.repo.CreateEmailChange(change); err != nil {
+		return nil, err
+	}
+
+	err = utils.SendEmail(req.NewEmail, "Confirm Your New Email", "Your code to confirm the new email of your FurniSwap account: "+code)
+	if err != nil {
+		log.Printf("Error sending email change code: %v", err)
+	}
+
+	body := "A change of the email of your FurniSwap account to " + req.NewEmail + " was requested on " + now.Format("02.01.2006 15:04") + ".\n\n" +
+		"If it wasn't you, cancel the change and secure your account: " + revokeLink(revokeToken) + "\n" +
+		"The link works until " + change.RevocableUntil.Format("02.01.2006 15:04") + ", also after the change is confirmed."
+	if err := utils.SendEmail(user.Email, "Email Change Requested", body); err != nil {
+		log.Printf("Error sending email change notice: %v", err)
+	}
+
+	return &model.EmailChangeResponse{
+		Email:          user.Email,
+		NewEmail:       req.NewEmail,
+		CodeExpiresAt:  &change.CodeExpiresAt,
+		RevocableUntil: change.RevocableUntil,
+	}, nil
+}
+
+// ConfirmEmailChange switches a user to the new email with the code sent to it.
+// The change is cancelled after too many wrong codes.
+func (s *Service) ConfirmEmailChange(userID int, req model.ConfirmEmailChangeRequest) (*model.EmailChangeResponse, error) {
+	// The guess is counted before the code is compared, so concurrent guesses share the limit
+	change, err := s.repo.ClaimEmailChangeAttempt(userID, maxCodeAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("no pending email change")
+		}
+		return nil, err
+	}
+
+	if !utils.CodeMatches(req.Code, change.CodeHash) {
+		if change.Attempts >= maxCodeAttempts {
+			if err := s.repo.CancelEmailChange(change.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, errors.New("invalid or expired code")
+	}
+
+	// Someone may have signed up with the new email in the meantime
+	taken, err := s.repo.EmailTaken(change.NewEmail)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, s.cancelTakenEmailChange(change)
+	}
+
+	confirmed, err := s.repo.ConfirmEmailChange(change)
+	if err != nil {
+		if err.Error() == "email already taken" {
+			return nil, s.cancelTakenEmailChange(change)
+		}
+		return nil, err
+	}
+	if !confirmed {
+		return nil, errors.New("no pending email change")
+	}
+
+	body := "The email of your FurniSwap account was changed to " + change.NewEmail + ". Use it to sign in from now on."
+	if err := utils.SendEmail(change.NewEmail, "Your Email Was Changed", body); err != nil {
+		log.Printf("Error sending email change confirmation: %v", err)
+	}
+
+	body = "The email of your FurniSwap account was changed to " + change.NewEmail + " on " + time.Now().Format("02.01.2006 15:04") + ".\n\n" +
+		"If it wasn't you, use the link from the earlier email to undo the change until " +
+		change.RevocableUntil.Format("02.01.2006 15:04") + "."
+	if err := utils.SendEmail(change.OldEmail, "Your Email Was Changed", body); err != nil {
+		log.Printf("Error sending email change notice: %v", err)
+	}
+
+	return &model.EmailChangeResponse{
+		Email:          change.NewEmail,
+		RevocableUntil: change.RevocableUntil,
+	}, nil
+}
+
+// cancelTakenEmailChange cancels an email change to an email another user has taken
+func (s *Service) cancelTakenEmailChange(change *model.EmailChange) error {
+	if err := s.repo.CancelEmailChange(change.ID); err != nil {
+		return err
+	}
+	return errors.New("email already taken")
+}
+
+// RevokeEmailChange undoes an email change with the link sent to the old email. Whoever
+// requested the change knew the password, so all sessions and trusted devices are ended
+// and a password reset link is sent to the old email.
+func (s *Service) RevokeEmailChange(req model.RevokeEmailChangeRequest) error {
+	change, err := s.repo.GetRevocableEmailChange(utils.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("invalid or expired revoke token")
+		}
+		return err
+	}
+
+	revoked, err := s.repo.RevokeEmailChange(change)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("invalid or expired revoke token")
+	}
+
+	if err := s.repo.RevokeOtherSessions(change.UserID, 0); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeTrustedDevices(change.UserID); err != nil {
+		return err
+	}
+
+	if change.ConfirmedAt != nil {
+		body := "The change of the FurniSwap account email to this address was undone by the owner of the previous email."
+		if err := utils.SendEmail(change.NewEmail, "Email Change Undone", body); err != nil {
+			log.Printf("Error sending email change revocation notice: %v", err)
+		}
+	}
+
+	if err := s.sendPasswordReset(change.OldEmail); err != nil {
+		return fmt.Errorf("error sending password reset link: %w", err)
+	}
+	return nil
+}
+
+// revokeLink builds the link that lets the old email undo an email change
+func revokeLink(token string) string {
+	return config.Config.FrontendURL + "/revoke-email-change?token=" + url.QueryEscape(token)
+}
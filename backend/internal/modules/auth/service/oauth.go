@@ -0,0 +1,256 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/internal/modules/auth/oauth"
+	"FurniSwap/pkg/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oauthStateTTL is how long a user has to log in with the provider
+const oauthStateTTL = 10 * time.Minute
+
+// OAuthProviders lists the names of the configured providers
+func (s *Service) OAuthProviders() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartOAuth starts a login with a provider. The state is stored hashed together with the
+// nonce and the PKCE verifier, which never leave the server.
+func (s *Service) StartOAuth(ctx context.Context, providerName string) (*model.OAuthStartResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	state, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oauth.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oauth.CodeChallenge(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("error starting login with %s: %w", providerName, err)
+	}
+
+	err = s.repo.SaveOAuthState(model.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.OAuthStartResponse{AuthorizationURL: authURL, State: state}, nil
+}
+
+// CompleteOAuth finishes a login with a provider and starts a session. The user is found by
+// the linked identity; a new identity is linked to the user with the same email, or a new
+// user is created, but only if the provider verified the email. Users with an authenticator
+// app get a login token for the second step instead of a session.
+func (s *Service) CompleteOAuth(ctx context.Context, providerName string, req model.OAuthCallbackRequest, client model.ClientInfo) (*model.UserResponse, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.New("unknown provider")
+	}
+
+	state, err := s.repo.ConsumeOAuthState(utils.HashToken(req.State), providerName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("invalid oauth state")
+		}
+		return nil, err
+	}
+
+	tokens, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		log.Printf("Error exchanging %s authorization code: %v", providerName, err)
+		return nil, errors.New("oauth login failed")
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, state.Nonce)
+	if err != nil {
+		log.Printf("Error verifying %s ID token: %v", providerName, err)
+		return nil, errors.New("oauth login failed")
+	}
+
+	user, err := s.findOAuthUser(providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsLocked() {
+		return nil, errors.New("account temporarily locked")
+	}
+
+	// The provider stands in for the password only; an authenticator app is still required,
+	// and the login is finished with /auth/verify-2fa like a password login
+	if user.TwoFactorMethod == model.TwoFactorMethodTOTP {
+		return s.startTOTPLogin(user)
+	}
+
+	client.Device = req.Device
+	client.Provider = providerName
+	response, err := s.signIn(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RememberDevice {
+		if err := s.trustDevice(user.ID, client, response); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// findOAuthUser gets the user of an external identity, linking it or signing the user up on first login
+func (s *Service) findOAuthUser(providerName string, claims *oauth.IDClaims) (*model.User, error) {
+	userID, err := s.repo.GetUserIDByIdentity(providerName, claims.Subject)
+	if err == nil {
+		return s.repo.GetUserByID(userID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// Linking by an unverified email would let anyone take over the account with that email
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errors.New("email not verified by provider")
+	}
+
+	user, err := s.repo.GetUserByEmail(claims.Email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("error getting user: %w", err)
+		}
+		return s.createOAuthUser(providerName, claims)
+	}
+
+	// Whoever registered an unverified account never proved they own the email, so their
+	// password and sessions must not keep working once the owner logs in
+	if !user.IsVerified {
+		if err := s.secureUnverifiedUser(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.LinkIdentity(user.ID, providerName, claims.Subject, claims.Email); err != nil {
+		return nil, err
+	}
+
+	body := "Your FurniSwap account was linked to your " + providerName + " account on " + time.Now().Format("02.01.2006 15:04") + ".\n\n" +
+		"You can now log in with it. If it wasn't you, reset your password right away."
+	if err := utils.SendEmail(user.Email, "Account Linked", body); err != nil {
+		log.Printf("Error sending account link notification: %v", err)
+	}
+
+	return user, nil
+}
+
+// createOAuthUser signs up a user with a verified email from a provider. The user has a random
+// password and can set one with the password reset flow.
+func (s *Service) createOAuthUser(providerName string, claims *oauth.IDClaims) (*model.User, error) {
+	// The old email of a revocable email change stays reserved for its user
+	taken, err := s.repo.EmailTaken(claims.Email)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, errors.New("email already taken")
+	}
+
+	passwordHash, err := randomPasswordHash()
+	if err != nil {
+		return nil, err
+	}
+
+	name, lastName := claims.GivenName, claims.FamilyName
+	if name == "" {
+		name, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	user := model.User{
+		Email:        claims.Email,
+		PasswordHash: passwordHash,
+		Name:         name,
+		LastName:     strings.TrimSpace(lastName),
+		City:         defaultCity,
+		Avatar:       defaultAvatar,
+	}
+	userID, err := s.repo.CreateUserWithIdentity(user, providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetUserByID(userID)
+}
+
+// secureUnverifiedUser verifies an account whose email a provider confirmed, replacing the password
+// and ending the sessions of whoever registered it
+func (s *Service) secureUnverifiedUser(user *model.User) error {
+	passwordHash, err := randomPasswordHash()
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(user.ID, passwordHash); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeOtherSessions(user.ID, 0); err != nil {
+		return err
+	}
+	if err := s.repo.RevokeTrustedDevices(user.ID); err != nil {
+		return err
+	}
+	if err := s.repo.SetUserVerified(user.ID); err != nil {
+		return err
+	}
+
+	user.PasswordHash = passwordHash
+	user.IsVerified = true
+	return nil
+}
+
+// randomPasswordHash hashes a random password nobody knows
+func randomPasswordHash() (string, error) {
+	password, err := oauth.RandomString()
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password: %w", err)
+	}
+	return string(hashedPassword), nil
+}
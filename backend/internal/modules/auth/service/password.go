@@ -0,0 +1,129 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/pkg/config"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL is how long a password reset link works
+const passwordResetTTL = time.Hour
+
+// ForgotPassword emails a password reset link to the user with the given email.
+// It succeeds for unknown emails too, so the endpoint doesn't reveal who is registered.
+// Every request counts towards the per-email and per-IP limits, as each one sends an email.
+func (s *Service) ForgotPassword(req model.ForgotPasswordRequest, ip string) error {
+	if err := s.checkThrottle(model.AuthActionForgotPassword, req.Email, ip); err != nil {
+		return err
+	}
+	s.recordFailure(model.AuthActionForgotPassword, req.Email, ip)
+
+	return s.sendPasswordReset(req.Email)
+}
+
+// sendPasswordReset emails a password reset link if a user with the email exists
+func (s *Service) sendPasswordReset(email string) error {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	token, tokenHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return err
+	}
+
+	// A new token replaces any earlier one
+	err = s.repo.SavePasswordResetToken(user.ID, tokenHash, time.Now().Add(passwordResetTTL))
+	if err != nil {
+		return fmt.Errorf("error saving password reset token: %w", err)
+	}
+
+	link := config.Config.FrontendURL + "/reset-password?token=" + url.QueryEscape(token)
+	body := "To set a new password, follow the link: " + link + "\n\n" +
+		"The link works once within an hour. If you didn't request a password reset, ignore this email."
+	err = utils.SendEmail(user.Email, "Password Reset", body)
+	if err != nil {
+		log.Printf("Error sending password reset email: %v", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and ends all sessions of the user
+func (s *Service) ResetPassword(req model.ResetPasswordRequest) error {
+	userID, err := s.repo.ConsumePasswordResetToken(utils.HashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("invalid or expired reset token")
+		}
+		return fmt.Errorf("error checking reset token: %w", err)
+	}
+
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	return s.setPassword(user, req.NewPassword, 0)
+}
+
+// ChangePassword changes the password of a signed-in user who knows the current one.
+// Sessions other than the current one are ended.
+func (s *Service) ChangePassword(userID, currentSessionID int, req model.ChangePasswordRequest, ip string) error {
+	user, err := s.checkPassword(userID, req.CurrentPassword, ip)
+	if err != nil {
+		if err.Error() == "invalid password" {
+			return errors.New("invalid current password")
+		}
+		return err
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("new password must differ from the current one")
+	}
+
+	return s.setPassword(user, req.NewPassword, currentSessionID)
+}
+
+// setPassword stores a new password, ends the user's sessions except keepSessionID,
+// forgets trusted devices and notifies the user by email
+func (s *Service) setPassword(user *model.User, password string, keepSessionID int) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeOtherSessions(user.ID, keepSessionID); err != nil {
+		return err
+	}
+
+	// Devices trusted with the old password need the second factor again
+	if err := s.repo.RevokeTrustedDevices(user.ID); err != nil {
+		return err
+	}
+
+	body := "The password of your FurniSwap account was changed on " + time.Now().Format("02.01.2006 15:04") + ".\n\n" +
+		"All other devices were signed out. If it wasn't you, reset your password right away."
+	err = utils.SendEmail(user.Email, "Your Password Was Changed", body)
+	if err != nil {
+		log.Printf("Error sending password change notification: %v", err)
+	}
+
+	return nil
+}
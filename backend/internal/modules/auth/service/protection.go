@@ -0,0 +1,121 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Brute-force protection limits
+const (
+	// codeTTL is how long an emailed code works
+	codeTTL = 10 * time.Minute
+	// maxCodeAttempts is how many wrong guesses invalidate a code
+	maxCodeAttempts = 5
+
+	// throttleWindow is the period failed attempts are counted over
+	throttleWindow = 15 * time.Minute
+	// maxEmailFailures is how many failed attempts of an action an email may have within the window
+	maxEmailFailures = 10
+	// maxIPFailures is how many failed attempts of an action an IP may have within the window
+	maxIPFailures = 50
+
+	// maxFailedLogins is how many wrong passwords in a row lock an account
+	maxFailedLogins = 5
+	// lockoutDuration is how long an account stays locked
+	lockoutDuration = 15 * time.Minute
+)
+
+// checkThrottle refuses an action for an email or IP with too many recent failures
+func (s *Service) checkThrottle(action, email, ip string) error {
+	emailFailures, ipFailures, err := s.repo.CountAuthFailures(action, email, ip, time.Now().Add(-throttleWindow))
+	if err != nil {
+		return err
+	}
+
+	if emailFailures >= maxEmailFailures || ipFailures >= maxIPFailures {
+		log.Printf("Throttled %s for email %s from IP %s", action, email, ip)
+		return errors.New("too many attempts")
+	}
+	return nil
+}
+
+// recordFailure records a failed attempt of an action for throttling
+func (s *Service) recordFailure(action, email, ip string) {
+	if err := s.repo.RecordAuthFailure(action, email, ip); err != nil {
+		log.Printf("Error recording failed %s: %v", action, err)
+	}
+}
+
+// issueCode generates a code for a user, replacing the user's active code for the purpose
+func (s *Service) issueCode(userID int, purpose string) (string, error) {
+	code, err := utils.GenerateCode()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.SaveVerificationCode(userID, purpose, utils.HashCode(code), time.Now().Add(codeTTL))
+	if err != nil {
+		return "", fmt.Errorf("error saving verification code: %w", err)
+	}
+	return code, nil
+}
+
+// checkCode checks a code for the user with the email and returns the user ID. A correct code
+// is used up; a code guessed too many times stops working. Each guess is counted before the
+// code is compared, so concurrent guesses share the same limit. It returns false if the code
+// is wrong, expired, used up or missing.
+func (s *Service) checkCode(email, purpose, code string) (int, bool, error) {
+	stored, err := s.repo.ClaimCodeAttempt(email, purpose, maxCodeAttempts)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("error verifying code: %w", err)
+	}
+
+	if !utils.CodeMatches(code, stored.CodeHash) {
+		if stored.Attempts >= maxCodeAttempts {
+			if _, err := s.repo.DeleteCode(stored.ID); err != nil {
+				return 0, false, err
+			}
+		}
+		return 0, false, nil
+	}
+
+	// A concurrent check may have used the code up already
+	used, err := s.repo.DeleteCode(stored.ID)
+	if err != nil {
+		return 0, false, err
+	}
+	if !used {
+		return 0, false, nil
+	}
+	return stored.UserID, true, nil
+}
+
+// addFailedLogin counts a wrong password and locks the account after too many, alerting the user by email.
+// It returns true if the account got locked.
+func (s *Service) addFailedLogin(user *model.User, ip string) (bool, error) {
+	locked, err := s.repo.AddFailedLogin(user.ID, maxFailedLogins, time.Now().Add(lockoutDuration))
+	if err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+
+	log.Printf("Account %d locked after %d failed logins, last from IP %s", user.ID, maxFailedLogins, ip)
+	body := fmt.Sprintf("Someone entered a wrong password for your FurniSwap account %d times in a row, "+
+		"the last time from IP address %s. Sign-in is locked for %d minutes.\n\n"+
+		"If it wasn't you, we recommend resetting your password.",
+		maxFailedLogins, ip, int(lockoutDuration.Minutes()))
+	if err := utils.SendEmail(user.Email, "Sign-in Locked", body); err != nil {
+		log.Printf("Error sending lockout alert: %v", err)
+	}
+	return true, nil
+}
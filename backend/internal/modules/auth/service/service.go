@@ -2,6 +2,7 @@ package service
 
 import (
 	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/internal/modules/auth/oauth"
 	"FurniSwap/internal/modules/auth/repository"
 	"FurniSwap/pkg/utils"
 	"database/sql"
@@ -13,52 +14,53 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Default values for city and avatar of new users
+const (
+	defaultCity   = "Москва"
+	defaultAvatar = "https://cs12.pikabu.ru/post_img/2021/05/08/12/1620504640126273687.jpg"
+)
+
 // Service provides authentication operations
 type Service struct {
-	repo *repository.Repository
+	repo      *repository.Repository
+	providers map[string]*oauth.Provider
 }
 
-// NewService creates a new auth service
-func NewService(repo *repository.Repository) *Service {
+// NewService creates a new auth service with the OpenID Connect providers users can log in with
+func NewService(repo *repository.Repository, providers map[string]*oauth.Provider) *Service {
 	return &Service{
-		repo: repo,
+		repo:      repo,
+		providers: providers,
 	}
 }
 
-// Register handles user registration
-func (s *Service) Register(req model.RegisterRequest) (string, error) {
-	// Check if user already exists
-	exists, err := s.repo.UserExists(req.Email)
+// Register handles user registration; the verification code is sent only by email
+func (s *Service) Register(req model.RegisterRequest) error {
+	// Check if user already exists or the email is reserved by a revocable email change
+	exists, err := s.repo.EmailTaken(req.Email)
 	if err != nil {
-		return "", fmt.Errorf("error checking user existence: %w", err)
+		return fmt.Errorf("error checking user existence: %w", err)
 	}
 	if exists {
-		return "", errors.New("user with this email already exists")
+		return errors.New("user with this email already exists")
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return "", fmt.Errorf("error hashing password: %w", err)
+		return fmt.Errorf("error hashing password: %w", err)
 	}
 
-	// Default values for city and avatar
-	defaultCity := "Москва"
-	defaultAvatar := "https://cs12.pikabu.ru/post_img/2021/05/08/12/1620504640126273687.jpg"
-
 	// Create user
 	userID, err := s.repo.CreateUser(req.Email, string(hashedPassword), req.Name, req.LastName, defaultCity, defaultAvatar)
 	if err != nil {
-		return "", fmt.Errorf("error creating user: %w", err)
+		return fmt.Errorf("error creating user: %w", err)
 	}
 
 	// Generate verification code
-	code := utils.GenerateCode()
-
-	// Save code in database with 10-minute expiration
-	err = s.repo.SaveVerificationCode(userID, code, time.Now().Add(10*time.Minute))
+	code, err := s.issueCode(userID, model.CodePurposeVerification)
 	if err != nil {
-		return "", fmt.Errorf("error saving verification code: %w", err)
+		return err
 	}
 
 	// Send verification email
@@ -67,18 +69,23 @@ func (s *Service) Register(req model.RegisterRequest) (string, error) {
 		log.Printf("Error sending verification email: %v", err)
 	}
 
-	return code, nil
+	return nil
 }
 
 // VerifyUser verifies a user's email
-func (s *Service) VerifyUser(req model.VerifyRequest) error {
+func (s *Service) VerifyUser(req model.VerifyRequest, ip string) error {
+	if err := s.checkThrottle(model.AuthActionVerify, req.Email, ip); err != nil {
+		return err
+	}
+
 	// Verify the code
-	userID, err := s.repo.VerifyCode(req.Email, req.Code)
+	userID, ok, err := s.checkCode(req.Email, model.CodePurposeVerification, req.Code)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errors.New("invalid or expired verification code")
-		}
-		return fmt.Errorf("error verifying code: %w", err)
+		return err
+	}
+	if !ok {
+		s.recordFailure(model.AuthActionVerify, req.Email, ip)
+		return errors.New("invalid or expired verification code")
 	}
 
 	// Mark user as verified
@@ -87,41 +94,55 @@ func (s *Service) VerifyUser(req model.VerifyRequest) error {
 		return fmt.Errorf("error setting user as verified: %w", err)
 	}
 
-	// Delete used verification codes
-	err = s.repo.DeleteVerificationCodes(userID)
-	if err != nil {
-		log.Printf("Error deleting verification codes: %v", err)
-	}
-
 	return nil
 }
 
-// Login authenticates a user and returns user info with token
-func (s *Service) Login(req model.LoginRequest) (*model.UserResponse, string, error) {
+// Login checks the password of a user and emails a 2FA code; the token is issued by Verify2FA.
+// On a trusted device the second factor is skipped and the token is issued right away.
+func (s *Service) Login(req model.LoginRequest, client model.ClientInfo) (*model.UserResponse, error) {
+	ip := client.IP
+	if err := s.checkThrottle(model.AuthActionLogin, req.Email, ip); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, "", errors.New("invalid email or password")
+			s.recordFailure(model.AuthActionLogin, req.Email, ip)
+			return nil, errors.New("invalid email or password")
 		}
-		return nil, "", fmt.Errorf("error getting user: %w", err)
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	if user.IsLocked() {
+		return nil, errors.New("account temporarily locked")
 	}
 
 	// Check password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
-		return nil, "", errors.New("invalid email or password")
+		s.recordFailure(model.AuthActionLogin, req.Email, ip)
+		locked, err := s.addFailedLogin(user, ip)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			return nil, errors.New("account temporarily locked")
+		}
+		return nil, errors.New("invalid email or password")
+	}
+
+	if err := s.repo.ResetFailedLogins(user.ID); err != nil {
+		log.Printf("Error resetting failed logins: %v", err)
 	}
 
 	// Check if user is verified
 	if !user.IsVerified {
-		// Generate new verification code
-		code := utils.GenerateCode()
-
-		// Save code in database with 10-minute expiration
-		err = s.repo.SaveVerificationCode(user.ID, code, time.Now().Add(10*time.Minute))
+		// Generate new verification code, replacing the previous one
+		code, err := s.issueCode(user.ID, model.CodePurposeVerification)
 		if err != nil {
-			return nil, "", fmt.Errorf("error saving verification code: %w", err)
+			return nil, err
 		}
 
 		// Send verification email
@@ -130,16 +151,30 @@ func (s *Service) Login(req model.LoginRequest) (*model.UserResponse, string, er
 			log.Printf("Error sending verification email: %v", err)
 		}
 
-		return nil, "", errors.New("account not verified; new verification code sent")
+		return nil, errors.New("account not verified; new verification code sent")
 	}
 
-	// Generate 2FA code for login
-	code := utils.GenerateCode()
+	// A trusted device skips the second factor; an invalid device token just doesn't
+	if req.DeviceToken != "" {
+		trusted, err := s.checkTrustedDevice(user.ID, req.DeviceToken, client)
+		if err != nil {
+			return nil, err
+		}
+		if trusted {
+			client.Device = req.Device
+			return s.signIn(user, client)
+		}
+	}
+
+	// With an authenticator app the second step needs a login token instead of an emailed code
+	if user.TwoFactorMethod == model.TwoFactorMethodTOTP {
+		return s.startTOTPLogin(user)
+	}
 
-	// Save code in database with 10-minute expiration
-	err = s.repo.SaveVerificationCode(user.ID, code, time.Now().Add(10*time.Minute))
+	// Generate 2FA code for login, replacing the previous one
+	code, err := s.issueCode(user.ID, model.CodePurpose2FA)
 	if err != nil {
-		return nil, "", fmt.Errorf("error saving 2FA code: %w", err)
+		return nil, err
 	}
 
 	// Send 2FA code via email
@@ -150,22 +185,35 @@ func (s *Service) Login(req model.LoginRequest) (*model.UserResponse, string, er
 
 	// Return partial user info without token (will be completed after 2FA)
 	return &model.UserResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		Name:     user.Name,
-		LastName: user.LastName,
-	}, code, nil
+		ID:              user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		LastName:        user.LastName,
+		TwoFactorMethod: model.TwoFactorMethodEmail,
+	}, nil
 }
 
-// Verify2FA verifies the two-factor authentication code
-func (s *Service) Verify2FA(req model.Verify2FARequest) (*model.UserResponse, error) {
-	// Verify the code
-	userID, err := s.repo.VerifyCode(req.Email, req.Code)
+// Verify2FA verifies the two-factor authentication code and starts a session on the client
+func (s *Service) Verify2FA(req model.Verify2FARequest, client model.ClientInfo) (*model.UserResponse, error) {
+	if err := s.checkThrottle(model.AuthActionVerify2FA, req.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	// Verify the emailed code, or the authenticator or recovery code of a TOTP login
+	var userID int
+	var ok bool
+	var err error
+	if req.LoginToken != "" {
+		userID, ok, err = s.checkTOTPLogin(req.Email, req.LoginToken, req.Code)
+	} else {
+		userID, ok, err = s.checkCode(req.Email, model.CodePurpose2FA, req.Code)
+	}
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("invalid or expired 2FA code")
-		}
-		return nil, fmt.Errorf("error verifying 2FA code: %w", err)
+		return nil, err
+	}
+	if !ok {
+		s.recordFailure(model.AuthActionVerify2FA, req.Email, client.IP)
+		return nil, errors.New("invalid or expired 2FA code")
 	}
 
 	// Get user by ID
@@ -174,24 +222,162 @@ func (s *Service) Verify2FA(req model.Verify2FARequest) (*model.UserResponse, er
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID)
+	if user.IsLocked() {
+		return nil, errors.New("account temporarily locked")
+	}
+
+	client.Device = req.Device
+	response, err := s.signIn(user, client)
 	if err != nil {
-		return nil, fmt.Errorf("error generating token: %w", err)
+		return nil, err
+	}
+
+	// Remember the device, so the next logins from it skip the second factor
+	if req.RememberDevice {
+		if err := s.trustDevice(user.ID, client, response); err != nil {
+			return nil, err
+		}
 	}
 
-	// Delete used verification codes
-	err = s.repo.DeleteVerificationCodes(user.ID)
+	return response, nil
+}
+
+// signIn starts a session for a user who passed both factors
+func (s *Service) signIn(user *model.User, client model.ClientInfo) (*model.UserResponse, error) {
+	// Logging in during the grace period of an account deletion restores the account
+	restored, err := s.repo.CancelAccountDeletion(user.ID)
 	if err != nil {
-		log.Printf("Error deleting verification codes: %v", err)
+		return nil, err
+	}
+	if restored {
+		body := "You logged in to your FurniSwap account, so it won't be deleted. Your listings are visible again."
+		if err := utils.SendEmail(user.Email, "Account Restored", body); err != nil {
+			log.Printf("Error sending account restore notification: %v", err)
+		}
+	}
+
+	// Start a session with access and refresh tokens
+	tokens, err := s.startSession(user, client)
+	if err != nil {
+		return nil, err
 	}
 
-	// Return user info with token
+	// Return user info with tokens
 	return &model.UserResponse{
-		ID:       user.ID,
-		Email:    user.Email,
-		Name:     user.Name,
-		LastName: user.LastName,
-		Token:    token,
+		ID:             user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		LastName:       user.LastName,
+		Token:          tokens.Token,
+		Role:           user.Role,
+		TokenExpiresAt: &tokens.TokenExpiresAt,
+		RefreshToken:   tokens.RefreshToken,
+
+		AccountRestored: restored,
 	}, nil
 }
+
+// startSession creates a session for a user and issues its tokens
+func (s *Service) startSession(user *model.User, client model.ClientInfo) (*model.TokenResponse, error) {
+	refreshToken, refreshHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID, err := s.repo.CreateSession(user.ID, refreshHash, nameDevice(client), time.Now().Add(utils.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := utils.GenerateToken(user.ID, sessionID, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	return &model.TokenResponse{
+		Token:          token,
+		TokenExpiresAt: expiresAt,
+		RefreshToken:   refreshToken,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Each refresh token works once; using a rotated one again revokes the session,
+// as either the client or an attacker holds a stolen copy.
+func (s *Service) Refresh(req model.RefreshRequest, client model.ClientInfo) (*model.TokenResponse, error) {
+	oldHash := utils.HashToken(req.RefreshToken)
+	refreshToken, newHash, err := utils.GenerateSecureToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.RotateRefreshToken(oldHash, newHash, client)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		reused, err := s.repo.RevokeSessionByPreviousToken(oldHash)
+		if err != nil {
+			return nil, err
+		}
+		if reused {
+			log.Printf("Refresh token reuse detected, session revoked")
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// The new token carries the current role, so a role change takes effect on refresh
+	user, err := s.repo.GetUserByID(session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	token, expiresAt, err := utils.GenerateToken(user.ID, session.ID, user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("error generating token: %w", err)
+	}
+
+	return &model.TokenResponse{
+		Token:          token,
+		TokenExpiresAt: expiresAt,
+		RefreshToken:   refreshToken,
+	}, nil
+}
+
+// Logout ends the session of a refresh token
+func (s *Service) Logout(req model.LogoutRequest) error {
+	revoked, err := s.repo.RevokeSessionByToken(utils.HashToken(req.RefreshToken))
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+// GetSessions gets the active sessions of a user, marking the one the request came from
+func (s *Service) GetSessions(userID, currentSessionID int) ([]model.Session, error) {
+	sessions, err := s.repo.GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].IsCurrent = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends a session of a user; its tokens stop working immediately
+func (s *Service) RevokeSession(userID, sessionID int) error {
+	revoked, err := s.repo.RevokeSession(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("session not found")
+	}
+	return nil
+}
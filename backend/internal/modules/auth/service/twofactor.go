@@ -0,0 +1,435 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/auth/model"
+	"FurniSwap/pkg/utils"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// totpIssuer names the account in authenticator apps
+const totpIssuer = "FurniSwap"
+
+// reauthWindow is how recent a provider login has to be to confirm a sensitive change without a password
+const reauthWindow = 10 * time.Minute
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GetTwoFactorStatus gets the second factor settings of a user
+func (s *Service) GetTwoFactorStatus(userID int) (*model.TwoFactorStatus, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	totpEnabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodesLeft, err := s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorStatus{
+		Method:            user.TwoFactorMethod,
+		TOTPEnabled:       totpEnabled,
+		RecoveryCodesLeft: recoveryCodesLeft,
+	}, nil
+}
+
+// EnrollTOTP starts adding an authenticator app; it is used only after ConfirmTOTP
+func (s *Service) EnrollTOTP(userID int, req model.PasswordConfirmRequest, ip string) (*model.TOTPEnrollment, error) {
+	user, err := s.checkPassword(userID, req.Password, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SaveTOTPCredential(userID, encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, errors.New("authenticator already enabled")
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP finishes adding an authenticator app with a code from it, makes TOTP
+// the second factor and returns new recovery codes
+func (s *Service) ConfirmTOTP(userID int, req model.ConfirmTOTPRequest, ip string) (*model.RecoveryCodesResponse, error) {
+	if _, err := s.checkPassword(userID, req.Password, ip); err != nil {
+		return nil, err
+	}
+
+	credential, err := s.repo.GetTOTPCredential(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("authenticator enrollment not started")
+		}
+		return nil, err
+	}
+	if credential.Confirmed {
+		return nil, errors.New("authenticator already enabled")
+	}
+
+	secret, err := utils.DecryptSecret(credential.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	step, err := utils.ValidateTOTP(secret, req.Code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if step == 0 {
+		return nil, errors.New("invalid authenticator code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.EnableTOTP(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP removes the authenticator app of a user, who goes back to email codes
+func (s *Service) DisableTOTP(userID int, req model.PasswordConfirmRequest, ip string) error {
+	user, err := s.checkPassword(userID, req.Password, ip)
+	if err != nil {
+		return err
+	}
+
+	enabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return errors.New("authenticator not enabled")
+	}
+
+	if err := s.repo.DisableTOTP(userID); err != nil {
+		return err
+	}
+
+	body := "The authenticator app was removed from your FurniSwap account. Sign-in codes will be sent by email.\n\n" +
+		"If it wasn't you, reset your password right away."
+	if err := utils.SendEmail(user.Email, "Authenticator Removed", body); err != nil {
+		log.Printf("Error sending authenticator removal notification: %v", err)
+	}
+	return nil
+}
+
+// SetTwoFactorMethod chooses between email codes and the authenticator app as the second factor
+func (s *Service) SetTwoFactorMethod(userID int, req model.SetTwoFactorMethodRequest, ip string) error {
+	if _, err := s.checkPassword(userID, req.Password, ip); err != nil {
+		return err
+	}
+
+	if req.Method == model.TwoFactorMethodTOTP {
+		enabled, err := s.totpEnabled(userID)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			return errors.New("authenticator not enabled")
+		}
+	}
+
+	return s.repo.SetTwoFactorMethod(userID, req.Method)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user with an authenticator app
+func (s *Service) RegenerateRecoveryCodes(userID int, req model.PasswordConfirmRequest, ip string) (*model.RecoveryCodesResponse, error) {
+	if _, err := s.checkPassword(userID, req.Password, ip); err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.totpEnabled(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, errors.New("authenticator not enabled")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &model.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// startTOTPLogin ends the first step of a login for a user with an authenticator app. The
+// response has no tokens, only the login token to pass to /auth/verify-2fa with the code.
+func (s *Service) startTOTPLogin(user *model.User) (*model.UserResponse, error) {
+	loginToken, err := s.issueTOTPLoginToken(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Name:            user.Name,
+		LastName:        user.LastName,
+		TwoFactorMethod: model.TwoFactorMethodTOTP,
+		LoginToken:      loginToken,
+	}, nil
+}
+
+// issueTOTPLoginToken issues the token that lets a user who passed the first step, with the
+// password or a provider, continue with an authenticator or recovery code
+func (s *Service) issueTOTPLoginToken(userID int) (string, error) {
+	token, _, err := utils.GenerateSecureToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.SaveVerificationCode(userID, model.CodePurposeTOTPLogin, utils.HashCode(token), time.Now().Add(codeTTL))
+	if err != nil {
+		return "", fmt.Errorf("error saving login token: %w", err)
+	}
+	return token, nil
+}
+
+// checkTOTPLogin checks the second step of a TOTP login: the login token from the password
+// step and an authenticator or recovery code. Wrong codes count against the login token,
+// which stops working after too many. It returns false if the login token or code is wrong.
+func (s *Service) checkTOTPLogin(email, loginToken, code string) (int, bool, error) {
+	stored, err := s.repo.GetActiveCode(email, model.CodePurposeTOTPLogin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("error checking login token: %w", err)
+	}
+	if !utils.CodeMatches(loginToken, stored.CodeHash) {
+		return 0, false, nil
+	}
+
+	// Count the guess before checking it, so concurrent guesses share the same limit
+	attempts, counted, err := s.repo.AddCodeAttempt(stored.ID, maxCodeAttempts)
+	if err != nil {
+		return 0, false, err
+	}
+	if !counted {
+		return 0, false, nil
+	}
+
+	ok, err := s.checkSecondFactorCode(stored.UserID, code)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !ok {
+		if attempts >= maxCodeAttempts {
+			if _, err := s.repo.DeleteCode(stored.ID); err != nil {
+				return 0, false, err
+			}
+		}
+		return 0, false, nil
+	}
+
+	// A concurrent check may have used the login token up already
+	used, err := s.repo.DeleteCode(stored.ID)
+	if err != nil {
+		return 0, false, err
+	}
+	if !used {
+		return 0, false, nil
+	}
+	return stored.UserID, true, nil
+}
+
+// checkSecondFactorCode checks an authenticator code, which works once, or uses up a recovery code
+func (s *Service) checkSecondFactorCode(userID int, code string) (bool, error) {
+	credential, err := s.repo.GetTOTPCredential(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !credential.Confirmed {
+		return false, nil
+	}
+
+	secret, err := utils.DecryptSecret(credential.SecretEncrypted)
+	if err != nil {
+		return false, err
+	}
+
+	step, err := utils.ValidateTOTP(secret, code, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if step > 0 {
+		return s.repo.UseTOTPStep(userID, step)
+	}
+
+	used, err := s.repo.UseRecoveryCode(userID, utils.HashCode(normalizeRecoveryCode(code)))
+	if err != nil || !used {
+		return false, err
+	}
+
+	s.notifyRecoveryCodeUsed(userID)
+	return true, nil
+}
+
+// notifyRecoveryCodeUsed tells a user by email that a recovery code was used to sign in
+func (s *Service) notifyRecoveryCodeUsed(userID int) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error getting user for recovery code notification: %v", err)
+		return
+	}
+
+	left, err := s.repo.CountRecoveryCodes(userID)
+	if err != nil {
+		log.Printf("Error counting recovery codes: %v", err)
+		return
+	}
+
+	body := fmt.Sprintf("A recovery code was used to sign in to your FurniSwap account. You have %d recovery codes left.\n\n"+
+		"If it wasn't you, reset your password and generate new recovery codes.", left)
+	if err := utils.SendEmail(user.Email, "Recovery Code Used", body); err != nil {
+		log.Printf("Error sending recovery code notification: %v", err)
+	}
+}
+
+// totpEnabled checks if a user has a confirmed authenticator app
+func (s *Service) totpEnabled(userID int) (bool, error) {
+	credential, err := s.repo.GetTOTPCredential(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return credential.Confirmed, nil
+}
+
+// ConfirmIdentity re-authenticates a logged in user before a sensitive change such as deleting
+// the account. The password works for everyone. Users who signed up with a provider only have a
+// random password, so a code from the authenticator app (or a recovery code) works instead, and
+// so does a session started with a provider login within reauthWindow. Wrong passwords and codes
+// are throttled like logins.
+func (s *Service) ConfirmIdentity(userID, sessionID int, password, code, ip string) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("error getting user: %w", err)
+	}
+
+	if password == "" && code == "" {
+		recent, err := s.repo.IsRecentProviderSession(sessionID, userID, time.Now().Add(-reauthWindow))
+		if err != nil {
+			return err
+		}
+		if !recent {
+			return errors.New("reauthentication required")
+		}
+		return nil
+	}
+
+	if password != "" {
+		_, err := s.checkPassword(userID, password, ip)
+		return err
+	}
+
+	if err := s.checkThrottle(model.AuthActionReauth, user.Email, ip); err != nil {
+		return err
+	}
+	ok, err := s.checkSecondFactorCode(userID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.recordFailure(model.AuthActionReauth, user.Email, ip)
+		return errors.New("invalid authenticator code")
+	}
+	return nil
+}
+
+// checkPassword gets a user and checks their password before a sensitive change. Wrong
+// passwords are throttled like logins, so a stolen access token can't be used to guess it.
+func (s *Service) checkPassword(userID int, password, ip string) (*model.User, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	if err := s.checkThrottle(model.AuthActionReauth, user.Email, ip); err != nil {
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		s.recordFailure(model.AuthActionReauth, user.Email, ip)
+		return nil, errors.New("invalid password")
+	}
+	return user, nil
+}
+
+// generateRecoveryCodes generates recovery codes like "k3jd-x9qa" and their hashes for storage
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, model.RecoveryCodeCount)
+	hashes := make([]string, 0, model.RecoveryCodeCount)
+
+	for i := 0; i < model.RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("error generating recovery code: %w", err)
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, utils.HashCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode lets users type recovery codes in any case, with or without the dash
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/block/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler provides block handlers
+type Handler struct {
+	service *service.Service
+}
+
+// NewHandler creates a new block handler
+func NewHandler(service *service.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers block routes to router
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/users/blocked", h.GetBlockedUsers)
+	router.POST("/users/:id/block", h.BlockUser)
+	router.DELETE("/users/:id/block", h.UnblockUser)
+}
+
+// BlockUser handles blocking a user
+func (h *Handler) BlockUser(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse blocked user ID
+	blockedID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Block user
+	err = h.service.BlockUser(userID.(int), blockedID)
+	if err != nil {
+		if err.Error() == "cannot block yourself" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot block yourself"})
+			return
+		}
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("Error blocking user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error blocking user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked successfully"})
+}
+
+// UnblockUser handles unblocking a user
+func (h *Handler) UnblockUser(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse blocked user ID
+	blockedID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Unblock user
+	err = h.service.UnblockUser(userID.(int), blockedID)
+	if err != nil {
+		if err.Error() == "user is not blocked" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not blocked"})
+			return
+		}
+		log.Printf("Error unblocking user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unblocking user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked successfully"})
+}
+
+// GetBlockedUsers handles getting the users blocked by the current user
+func (h *Handler) GetBlockedUsers(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	blocks, err := h.service.GetBlockedUsers(userID.(int))
+	if err != nil {
+		log.Printf("Error getting blocked users: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting blocked users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocked_users": blocks})
+}
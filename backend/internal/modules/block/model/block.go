@@ -0,0 +1,14 @@
+package model
+
+import (
+	"time"
+)
+
+// Block represents a user blocked by another user
+type Block struct {
+	BlockerID   int       `db:"blocker_id" json:"blocker_id"`
+	BlockedID   int       `db:"blocked_id" json:"blocked_id"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	BlockedName string    `db:"blocked_name" json:"blocked_name,omitempty"`
+	Avatar      string    `db:"avatar" json:"avatar,omitempty"`
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/block/model"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository handles database operations for the block module
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new block repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// BlockUser blocks a user; blocking an already blocked user is a no-op
+func (r *Repository) BlockUser(blockerID, blockedID int) error {
+	_, err := r.db.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`, blockerID, blockedID, time.Now())
+	if err != nil {
+		log.Printf("Error blocking user: %v", err)
+		return fmt.Errorf("error blocking user: %w", err)
+	}
+	return nil
+}
+
+// UnblockUser unblocks a user; it returns false if the user was not blocked
+func (r *Repository) UnblockUser(blockerID, blockedID int) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2", blockerID, blockedID)
+	if err != nil {
+		log.Printf("Error unblocking user: %v", err)
+		return false, fmt.Errorf("error unblocking user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetBlockedUsers gets the users blocked by a user
+func (r *Repository) GetBlockedUsers(blockerID int) ([]model.Block, error) {
+	blocks := []model.Block{}
+	err := r.db.Select(&blocks, `
+		SELECT b.blocker_id, b.blocked_id, b.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as blocked_name,
+		       COALESCE(u.avatar, '') as avatar
+		FROM user_blocks b
+		JOIN users u ON b.blocked_id = u.id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`, blockerID)
+	if err != nil {
+		log.Printf("Error getting blocked users: %v", err)
+		return nil, fmt.Errorf("error getting blocked users: %w", err)
+	}
+	return blocks, nil
+}
+
+// IsBlocked checks if either of two users has blocked the other
+func (r *Repository) IsBlocked(user1ID, user2ID int) (bool, error) {
+	var blocked bool
+	err := r.db.Get(&blocked, `
+		SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`, user1ID, user2ID)
+	if err != nil {
+		log.Printf("Error checking user block: %v", err)
+		return false, fmt.Errorf("error checking user block: %w", err)
+	}
+	return blocked, nil
+}
+
+// UserExists checks if a user exists
+func (r *Repository) UserExists(userID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID)
+	if err != nil {
+		log.Printf("Error checking user existence: %v", err)
+		return false, fmt.Errorf("error checking user existence: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/block/model"
+	"FurniSwap/internal/modules/block/repository"
+	"errors"
+	"fmt"
+)
+
+// Service provides user blocking operations
+type Service struct {
+	repo *repository.Repository
+}
+
+// NewService creates a new block service
+func NewService(repo *repository.Repository) *Service {
+	return &Service{
+		repo: repo,
+	}
+}
+
+// BlockUser blocks a user. Blocked users can't message the blocker and their
+// listings are hidden from the blocker's catalog.
+func (s *Service) BlockUser(blockerID, blockedID int) error {
+	if blockerID == blockedID {
+		return errors.New("cannot block yourself")
+	}
+
+	exists, err := s.repo.UserExists(blockedID)
+	if err != nil {
+		return fmt.Errorf("error checking user: %w", err)
+	}
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	return s.repo.BlockUser(blockerID, blockedID)
+}
+
+// UnblockUser unblocks a user
+func (s *Service) UnblockUser(blockerID, blockedID int) error {
+	unblocked, err := s.repo.UnblockUser(blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if !unblocked {
+		return errors.New("user is not blocked")
+	}
+	return nil
+}
+
+// GetBlockedUsers gets the users blocked by a user
+func (s *Service) GetBlockedUsers(blockerID int) ([]model.Block, error) {
+	return s.repo.GetBlockedUsers(blockerID)
+}
@@ -3,9 +3,14 @@ package handler
 import (
 	"FurniSwap/internal/modules/chat/model"
 	"FurniSwap/internal/modules/chat/service"
+	"fmt"
 	"log"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,8 +31,35 @@ func NewHandler(service *service.Service) *Handler {
 func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/chats", h.InitiateChat)
 	router.GET("/chats", h.GetChats)
+	router.GET("/chats/ws", h.ServeWS)
+	router.GET("/chats/unread", h.GetUnreadCount)
+	router.GET("/chats/search", h.SearchMessages)
 	router.GET("/chats/:id", h.GetChatMessages)
+	router.DELETE("/chats/:id", h.DeleteChat)
+	router.POST("/chats/:id/archive", h.ArchiveChat)
+	router.DELETE("/chats/:id/archive", h.UnarchiveChat)
+	router.POST("/chats/:id/pin", h.PinChat)
+	router.DELETE("/chats/:id/pin", h.UnpinChat)
+	router.POST("/chats/:id/mute", h.MuteChat)
+	router.DELETE("/chats/:id/mute", h.UnmuteChat)
 	router.POST("/chats/:id/messages", h.SendMessage)
+	router.PUT("/chats/:id/messages/:messageId", h.EditMessage)
+	router.DELETE("/chats/:id/messages/:messageId", h.DeleteMessage)
+	router.POST("/chats/:id/read", h.MarkRead)
+	router.POST("/chats/:id/report", h.ReportChat)
+	router.POST("/chats/:id/attachments", h.UploadAttachment)
+	router.GET("/chats/:id/attachments/:attachmentId", h.GetAttachment)
+}
+
+// RegisterModeratorRoutes registers chat moderation routes (chats.moderate permission required)
+func (h *Handler) RegisterModeratorRoutes(router *gin.RouterGroup) {
+	router.GET("/chat-reports", h.GetReports)
+	router.GET("/chat-reports/:id", h.GetReport)
+	router.POST("/chat-reports/:id/resolve", h.ResolveReport)
+	router.GET("/chat-screening/events", h.GetScreeningEvents)
+	router.GET("/chat-screening/held", h.GetHeldMessages)
+	router.POST("/chat-screening/held/:messageId/approve", h.ApproveHeldMessage)
+	router.POST("/chat-screening/held/:messageId/reject", h.RejectHeldMessage)
 }
 
 // InitiateChat handles starting a new chat
@@ -58,6 +90,18 @@ func (h *Handler) InitiateChat(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot initiate chat with yourself"})
 			return
 		}
+		if err.Error() == "recipient not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+			return
+		}
+		if err.Error() == "messaging is blocked between these users" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can't message this user"})
+			return
+		}
+		if err.Error() == "message rejected by screening" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Your message was blocked by the spam filter"})
+			return
+		}
 		log.Printf("Error initiating chat: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error initiating chat"})
 		return
@@ -86,8 +130,11 @@ func (h *Handler) GetChats(c *gin.Context) {
 		limit = 10
 	}
 
+	// Archived chats are listed separately from the main list
+	archived := c.Query("archived") == "true"
+
 	// Get chats
-	chats, err := h.service.GetUserChats(userID.(int), page, limit)
+	chats, err := h.service.GetUserChats(userID.(int), archived, page, limit)
 	if err != nil {
 		log.Printf("Error getting chats: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting chats"})
@@ -97,7 +144,10 @@ func (h *Handler) GetChats(c *gin.Context) {
 	c.JSON(http.StatusOK, chats)
 }
 
-// GetChatMessages handles getting messages in a chat
+// GetChatMessages handles getting messages in a chat.
+// By default it returns the newest messages first and pages through the history with
+// the before/after message ID cursors. Passing page switches to the legacy
+// oldest-first offset pagination.
 func (h *Handler) GetChatMessages(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("userID")
@@ -113,12 +163,6 @@ func (h *Handler) GetChatMessages(c *gin.Context) {
 		return
 	}
 
-	// Parse pagination parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if page < 1 {
-		page = 1
-	}
-
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	if limit < 1 || limit > 100 {
 		limit = 50
@@ -136,8 +180,72 @@ func (h *Handler) GetChatMessages(c *gin.Context) {
 		return
 	}
 
+	if _, legacy := c.GetQuery("page"); legacy {
+		h.getChatMessagesPage(c, chat, userID.(int), limit)
+		return
+	}
+
+	// Parse cursors
+	cursor := model.MessageCursor{Limit: limit}
+	if before := c.Query("before"); before != "" {
+		if cursor.Before, err = strconv.Atoi(before); err != nil || cursor.Before < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before cursor"})
+			return
+		}
+	}
+	if after := c.Query("after"); after != "" {
+		if cursor.After, err = strconv.Atoi(after); err != nil || cursor.After < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after cursor"})
+			return
+		}
+	}
+
 	// Get messages
-	messages, err := h.service.GetChatMessages(chatID, userID.(int), page, limit)
+	window, err := h.service.GetChatMessagesWindow(chatID, userID.(int), cursor)
+	if err != nil {
+		if err.Error() == "you don't have access to this chat" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			return
+		}
+		if err.Error() == "only one of before and after can be set" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only one of before and after can be set"})
+			return
+		}
+		log.Printf("Error getting chat messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting chat messages"})
+		return
+	}
+
+	// Cursors for the next windows in both directions
+	pagination := gin.H{
+		"has_older": window.HasOlder,
+		"has_newer": window.HasNewer,
+	}
+	if n := len(window.Messages); n > 0 {
+		if window.HasOlder {
+			pagination["older_cursor"] = window.Messages[n-1].ID
+		}
+		if window.HasNewer {
+			pagination["newer_cursor"] = window.Messages[0].ID
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"chat":       chat,
+		"messages":   window.Messages,
+		"pagination": pagination,
+	})
+}
+
+// getChatMessagesPage responds with a page of chat messages, oldest first
+func (h *Handler) getChatMessagesPage(c *gin.Context, chat *model.Chat, userID, limit int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	// Get messages
+	messages, err := h.service.GetChatMessages(chat.ID, userID, page, limit)
 	if err != nil {
 		if err.Error() == "you don't have access to this chat" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
@@ -162,6 +270,33 @@ func (h *Handler) GetChatMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// SearchMessages handles full-text search across all of the user's chats
+func (h *Handler) SearchMessages(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	results, err := h.service.SearchMessages(userID.(int), c.Query("q"), page, limit)
+	if err != nil {
+		if err.Error() == "search query is empty" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+			return
+		}
+		log.Printf("Error searching messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
 // SendMessage handles sending a message in a chat
 func (h *Handler) SendMessage(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -178,20 +313,48 @@ func (h *Handler) SendMessage(c *gin.Context) {
 		return
 	}
 
-	// Parse request body
+	// Parse request body: JSON, or multipart form with attached files
 	var req model.SendMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var files []*multipart.FileHeader
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+		if form, err := c.MultipartForm(); err == nil {
+			files = form.File["files"]
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
 		return
 	}
 
 	// Send message
-	messageID, err := h.service.SendMessage(chatID, userID.(int), req)
+	messageID, err := h.service.SendMessage(chatID, userID.(int), req, files)
 	if err != nil {
 		if err.Error() == "you don't have access to this chat" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
 			return
 		}
+		if err.Error() == "messaging is blocked between these users" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You can't message this user"})
+			return
+		}
+		if err.Error() == "message rejected by screening" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Your message was blocked by the spam filter"})
+			return
+		}
+		if err.Error() == "message is empty" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Message must have content or attachments"})
+			return
+		}
+		if err.Error() == "replied message not found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Replied message not found in this chat"})
+			return
+		}
+		if h.respondAttachmentError(c, err) {
+			return
+		}
 		log.Printf("Error sending message: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending message"})
 		return
@@ -199,3 +362,426 @@ func (h *Handler) SendMessage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"id": messageID, "message": "Message sent successfully"})
 }
+
+// MarkRead handles marking messages in a chat as read
+func (h *Handler) MarkRead(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat ID
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	// Parse optional request body
+	var req model.MarkReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+	}
+
+	// Mark messages as read
+	err = h.service.MarkRead(chatID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "you don't have access to this chat" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			return
+		}
+		log.Printf("Error marking messages as read: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error marking messages as read"})
+		return
+	}
+
+	// Return the updated total for the navbar badge
+	count, err := h.service.GetUnreadCount(userID.(int))
+	if err != nil {
+		log.Printf("Error getting unread count: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Messages marked as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Messages marked as read", "unread_count": count})
+}
+
+// GetUnreadCount handles getting the total number of unread messages
+func (h *Handler) GetUnreadCount(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	count, err := h.service.GetUnreadCount(userID.(int))
+	if err != nil {
+		log.Printf("Error getting unread count: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting unread count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// EditMessage handles editing a message
+func (h *Handler) EditMessage(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat and message IDs
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Edit message
+	err = h.service.EditMessage(chatID, messageID, userID.(int), req)
+	if err != nil {
+		if h.respondMessageError(c, err) {
+			return
+		}
+		if err.Error() == "message is empty" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Message content cannot be empty"})
+			return
+		}
+		if err.Error() == "edit window has expired" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Messages can only be edited within %d minutes", int(model.MessageEditWindow.Minutes()))})
+			return
+		}
+		log.Printf("Error editing message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error editing message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message edited successfully"})
+}
+
+// DeleteMessage handles deleting a message
+func (h *Handler) DeleteMessage(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat and message IDs
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	messageID, err := strconv.Atoi(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	// Delete message
+	err = h.service.DeleteMessage(chatID, messageID, userID.(int))
+	if err != nil {
+		if h.respondMessageError(c, err) {
+			return
+		}
+		log.Printf("Error deleting message: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted successfully"})
+}
+
+// respondMessageError writes the response for errors of changing a message.
+// It returns false if the error is not one of them.
+func (h *Handler) respondMessageError(c *gin.Context, err error) bool {
+	switch err.Error() {
+	case "you don't have access to this chat":
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+	case "you can only change your own messages":
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only change your own messages"})
+	case "system messages cannot be changed":
+		c.JSON(http.StatusForbidden, gin.H{"error": "System messages cannot be changed"})
+	case "message rejected by screening":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Your message was blocked by the spam filter"})
+	case "message not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	default:
+		return false
+	}
+	return true
+}
+
+// UploadAttachment handles uploading an attachment to be sent with a later message
+func (h *Handler) UploadAttachment(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat ID
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	// Get file from form
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	// Upload attachment
+	attachment, err := h.service.UploadAttachment(chatID, userID.(int), file)
+	if err != nil {
+		if err.Error() == "you don't have access to this chat" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			return
+		}
+		if h.respondAttachmentError(c, err) {
+			return
+		}
+		log.Printf("Error uploading attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error uploading attachment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// GetAttachment handles downloading a chat attachment
+func (h *Handler) GetAttachment(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat and attachment IDs
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(c.Param("attachmentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	// Get attachment
+	attachment, err := h.service.GetAttachment(chatID, attachmentID, userID.(int))
+	if err != nil {
+		if err.Error() == "you don't have access to this chat" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			return
+		}
+		if err.Error() == "attachment not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return
+		}
+		log.Printf("Error getting attachment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting attachment"})
+		return
+	}
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.File(filepath.Join("uploads", attachment.FilePath))
+}
+
+// respondAttachmentError writes the response for attachment validation errors.
+// It returns false if the error is not an attachment error.
+func (h *Handler) respondAttachmentError(c *gin.Context, err error) bool {
+	switch err.Error() {
+	case "too many attachments":
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A message can have at most %d attachments", model.MaxAttachmentsPerMessage)})
+	case "attachment is too large":
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Attachment must not exceed %d MB", model.MaxAttachmentSize>>20)})
+	case "unsupported attachment type":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Attachment must be an image (JPEG, PNG, GIF, WebP) or a PDF"})
+	case "attachment not found":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment IDs"})
+	default:
+		return false
+	}
+	return true
+}
+
+// ReportChat handles reporting abuse in a chat
+func (h *Handler) ReportChat(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat ID
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Report chat
+	reportID, err := h.service.ReportChat(chatID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "you don't have access to this chat" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+			return
+		}
+		if err.Error() == "reported message not found" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reported message not found in this chat"})
+			return
+		}
+		log.Printf("Error reporting chat: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reporting chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": reportID, "message": "Report submitted successfully"})
+}
+
+// GetReports handles getting the moderation queue of chat reports
+func (h *Handler) GetReports(c *gin.Context) {
+	// Parse filter and pagination parameters
+	status := c.DefaultQuery("status", model.ReportStatusOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	// Get reports
+	reports, err := h.service.GetReports(status, page, limit)
+	if err != nil {
+		log.Printf("Error getting chat reports: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting chat reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetReport handles getting a chat report with the chat's messages
+func (h *Handler) GetReport(c *gin.Context) {
+	// Parse report ID
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	// Get report
+	report, err := h.service.GetReport(reportID)
+	if err != nil {
+		if err.Error() == "report not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		log.Printf("Error getting chat report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting chat report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ResolveReport handles a moderator closing a chat report
+func (h *Handler) ResolveReport(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse report ID
+	reportID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.ResolveReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Resolve report
+	err = h.service.ResolveReport(reportID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "report not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+			return
+		}
+		if err.Error() == "report is already closed" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Report is already closed"})
+			return
+		}
+		log.Printf("Error resolving chat report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resolving chat report"})
+		return
+	}
+
+	// Get the updated report
+	report, err := h.service.GetReport(reportID)
+	if err != nil {
+		log.Printf("Error getting resolved chat report: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Report resolved successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
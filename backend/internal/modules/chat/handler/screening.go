@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetScreeningEvents handles getting the audit log of triggered screening rules (`rule` and `action` filters)
+func (h *Handler) GetScreeningEvents(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	events, err := h.service.GetScreeningEvents(c.Query("rule"), c.Query("action"), page, limit)
+	if err != nil {
+		log.Printf("Error getting screening events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting screening events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// GetHeldMessages handles getting the messages held for review
+func (h *Handler) GetHeldMessages(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	messages, err := h.service.GetHeldMessages(page, limit)
+	if err != nil {
+		log.Printf("Error getting held messages: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting held messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// ApproveHeldMessage handles delivering a held message to the recipient
+func (h *Handler) ApproveHeldMessage(c *gin.Context) {
+	h.reviewHeldMessage(c, "Message approved", h.service.ApproveHeldMessage)
+}
+
+// RejectHeldMessage handles deleting a held message
+func (h *Handler) RejectHeldMessage(c *gin.Context) {
+	h.reviewHeldMessage(c, "Message rejected", h.service.RejectHeldMessage)
+}
+
+// reviewHeldMessage applies a moderator decision to a held message and writes the response
+func (h *Handler) reviewHeldMessage(c *gin.Context, message string, review func(messageID int) error) {
+	// Parse message ID
+	messageID, err := strconv.Atoi(c.Param("messageId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	if err := review(messageID); err != nil {
+		switch err.Error() {
+		case "message not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		case "message is not held":
+			c.JSON(http.StatusConflict, gin.H{"error": "Message is not held for review"})
+		default:
+			log.Printf("Error reviewing held message: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reviewing message"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
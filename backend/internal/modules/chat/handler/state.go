@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ArchiveChat handles moving a chat to the user's archive
+func (h *Handler) ArchiveChat(c *gin.Context) {
+	h.updateChatState(c, "Chat archived", func(chatID, userID int) error {
+		return h.service.ArchiveChat(chatID, userID, true)
+	})
+}
+
+// UnarchiveChat handles moving a chat out of the user's archive
+func (h *Handler) UnarchiveChat(c *gin.Context) {
+	h.updateChatState(c, "Chat unarchived", func(chatID, userID int) error {
+		return h.service.ArchiveChat(chatID, userID, false)
+	})
+}
+
+// PinChat handles pinning a chat to the top of the user's chat list
+func (h *Handler) PinChat(c *gin.Context) {
+	h.updateChatState(c, "Chat pinned", func(chatID, userID int) error {
+		return h.service.PinChat(chatID, userID, true)
+	})
+}
+
+// UnpinChat handles unpinning a chat
+func (h *Handler) UnpinChat(c *gin.Context) {
+	h.updateChatState(c, "Chat unpinned", func(chatID, userID int) error {
+		return h.service.PinChat(chatID, userID, false)
+	})
+}
+
+// MuteChat handles muting a chat, indefinitely or until the optional time in the request body
+func (h *Handler) MuteChat(c *gin.Context) {
+	// The request body is optional
+	var req model.MuteChatRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+	}
+
+	h.updateChatState(c, "Chat muted", func(chatID, userID int) error {
+		return h.service.MuteChat(chatID, userID, req)
+	})
+}
+
+// UnmuteChat handles unmuting a chat
+func (h *Handler) UnmuteChat(c *gin.Context) {
+	h.updateChatState(c, "Chat unmuted", func(chatID, userID int) error {
+		return h.service.UnmuteChat(chatID, userID)
+	})
+}
+
+// DeleteChat handles deleting a chat for the user only
+func (h *Handler) DeleteChat(c *gin.Context) {
+	h.updateChatState(c, "Chat deleted", func(chatID, userID int) error {
+		return h.service.DeleteChat(chatID, userID)
+	})
+}
+
+// updateChatState applies a change of the user's chat state and writes the response
+func (h *Handler) updateChatState(c *gin.Context, message string, update func(chatID, userID int) error) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse chat ID
+	chatID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID"})
+		return
+	}
+
+	if err := update(chatID, userID.(int)); err != nil {
+		switch err.Error() {
+		case "you don't have access to this chat":
+			c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this chat"})
+		case "too many pinned chats":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You can pin up to " + strconv.Itoa(model.MaxPinnedChats) + " chats"})
+		case "mute end must be in the future":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mute end must be in the future"})
+		default:
+			log.Printf("Error updating chat state: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating chat"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/chat/hub"
+	"FurniSwap/internal/modules/chat/model"
+	"FurniSwap/pkg/config"
+	"FurniSwap/pkg/middleware"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Time allowed to write an event to the client
+	writeWait = 10 * time.Second
+	// Time allowed to read the next pong from the client
+	pongWait = 60 * time.Second
+	// Ping period, must be less than pongWait
+	pingPeriod = pongWait * 9 / 10
+	// Maximum size of an event sent by the client
+	maxClientEventSize = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     checkOrigin,
+	// A browser passes the token after this subprotocol; choosing it completes the handshake
+	Subprotocols: []string{middleware.WebSocketAuthProtocol},
+}
+
+// checkOrigin allows WebSocket connections from the same origins as CORS
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range config.Config.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	log.Printf("Rejected chat WebSocket from origin %s", origin)
+	return false
+}
+
+// ServeWS handles a WebSocket connection delivering real-time chat events
+func (h *Handler) ServeWS(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// The upgrader writes the error response itself
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading chat connection: %v", err)
+		return
+	}
+
+	client := h.service.Connect(userID.(int), c.GetInt("sessionID"))
+	go h.writeEvents(conn, client)
+	h.readEvents(conn, client)
+}
+
+// readEvents handles events sent by the client until the connection is closed
+func (h *Handler) readEvents(conn *websocket.Conn, client *hub.Client) {
+	defer func() {
+		h.service.Disconnect(client)
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(maxClientEventSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var event model.ClientEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Error reading chat event: %v", err)
+			}
+			return
+		}
+
+		switch event.Type {
+		case model.EventTyping:
+			if err := h.service.SendTyping(event.ChatID, client.UserID, event.IsTyping); err != nil {
+				log.Printf("Error sending typing event: %v", err)
+			}
+		default:
+			log.Printf("Unknown chat event type %q from user %d", event.Type, client.UserID)
+		}
+	}
+}
+
+// writeEvents writes events from the hub to the connection and keeps it alive with pings.
+// With each ping the session of the connection is checked, and the connection is closed once it ends.
+func (h *Handler) writeEvents(conn *websocket.Conn, client *hub.Client) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-client.Send():
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				// The hub closed the channel
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			// A revoked session (logout, password change, account deletion) stops receiving events
+			active, err := h.service.SessionActive(client)
+			if err != nil {
+				log.Printf("Error checking session of chat connection: %v", err)
+			} else if !active {
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session ended"))
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package hub
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the Postgres channel used to exchange chat events between replicas
+const notifyChannel = "chat_events"
+
+// MessageLoader loads messages referenced by notifications from other replicas
+type MessageLoader interface {
+	GetMessageByID(messageID int) (*model.Message, error)
+}
+
+// notification is the NOTIFY payload. Messages are sent by ID to stay under the
+// Postgres payload limit and loaded from the database by the receiving replica.
+type notification struct {
+	Origin    string `json:"origin"`
+	UserIDs   []int  `json:"user_ids"`
+	Type      string `json:"type"`
+	ChatID    int    `json:"chat_id"`
+	MessageID int    `json:"message_id,omitempty"`
+	UserID    int    `json:"user_id,omitempty"`
+	IsTyping  bool   `json:"is_typing,omitempty"`
+	ReadUpTo  int    `json:"read_up_to_id,omitempty"`
+}
+
+// Bridge relays chat events between backend replicas over Postgres LISTEN/NOTIFY
+type Bridge struct {
+	hub      *Hub
+	db       *sqlx.DB
+	listener *pq.Listener
+	loader   MessageLoader
+	origin   string
+}
+
+// StartBridge subscribes the hub to events published by other replicas.
+// It must be called before the hub is used.
+func (h *Hub) StartBridge(db *sqlx.DB, connectionString string, loader MessageLoader) error {
+	listener := pq.NewListener(connectionString, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Chat events listener error: %v", err)
+		}
+	})
+
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return fmt.Errorf("error listening to chat events: %w", err)
+	}
+
+	h.bridge = &Bridge{
+		hub:      h,
+		db:       db,
+		listener: listener,
+		loader:   loader,
+		origin:   uuid.New().String(),
+	}
+	go h.bridge.run()
+
+	log.Println("Chat events bridge started")
+	return nil
+}
+
+// Close stops the bridge
+func (h *Hub) Close() {
+	if h.bridge != nil {
+		if err := h.bridge.listener.Close(); err != nil {
+			log.Printf("Error closing chat events listener: %v", err)
+		}
+	}
+}
+
+// notify publishes an event to the other replicas
+func (b *Bridge) notify(userIDs []int, event model.Event) {
+	n := notification{
+		Origin:   b.origin,
+		UserIDs:  userIDs,
+		Type:     event.Type,
+		ChatID:   event.ChatID,
+		UserID:   event.UserID,
+		IsTyping: event.IsTyping,
+		ReadUpTo: event.ReadUpToID,
+	}
+	if event.Message != nil {
+		n.MessageID = event.Message.ID
+	}
+
+	payload, err := json.Marshal(n)
+	if err != nil {
+		log.Printf("Error encoding chat notification: %v", err)
+		return
+	}
+
+	if _, err := b.db.Exec("SELECT pg_notify($1, $2)", notifyChannel, string(payload)); err != nil {
+		log.Printf("Error publishing chat notification: %v", err)
+	}
+}
+
+// run delivers notifications from other replicas to local clients
+func (b *Bridge) run() {
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			// A nil notification means the connection was re-established
+			if n == nil {
+				continue
+			}
+			b.handle(n.Extra)
+		case <-time.After(90 * time.Second):
+			go func() {
+				if err := b.listener.Ping(); err != nil {
+					log.Printf("Chat events listener ping error: %v", err)
+				}
+			}()
+		}
+	}
+}
+
+// handle decodes a notification and delivers it to local clients
+func (b *Bridge) handle(payload string) {
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		log.Printf("Error decoding chat notification: %v", err)
+		return
+	}
+
+	// Events published by this replica were already delivered locally
+	if n.Origin == b.origin {
+		return
+	}
+
+	event := model.Event{
+		Type:       n.Type,
+		ChatID:     n.ChatID,
+		UserID:     n.UserID,
+		IsTyping:   n.IsTyping,
+		ReadUpToID: n.ReadUpTo,
+	}
+	if n.MessageID != 0 {
+		message, err := b.loader.GetMessageByID(n.MessageID)
+		if err != nil {
+			log.Printf("Error loading message %d for chat notification: %v", n.MessageID, err)
+			return
+		}
+		event.Message = message
+	}
+
+	b.hub.deliver(n.UserIDs, event)
+}
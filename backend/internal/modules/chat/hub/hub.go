@@ -0,0 +1,131 @@
+package hub
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// sendBufferSize is the number of events buffered per client before it is considered too slow
+const sendBufferSize = 64
+
+// Client represents a single WebSocket connection of a user
+type Client struct {
+	UserID int
+	// SessionID is the login session the connection was opened with
+	SessionID int
+	send      chan []byte
+}
+
+// NewClient creates a new client for a session of a user
+func NewClient(userID, sessionID int) *Client {
+	return &Client{
+		UserID:    userID,
+		SessionID: sessionID,
+		send:      make(chan []byte, sendBufferSize),
+	}
+}
+
+// Send returns the channel of encoded events to write to the connection.
+// The channel is closed when the client is unregistered.
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+// Hub keeps track of connected clients and fans out chat events to them.
+// With a bridge attached, events are also delivered to clients of other backend replicas.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[int]map[*Client]struct{}
+	bridge  *Bridge
+}
+
+// NewHub creates a new hub
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[int]map[*Client]struct{}),
+	}
+}
+
+// Register adds a client to the hub
+func (h *Hub) Register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[client.UserID] == nil {
+		h.clients[client.UserID] = make(map[*Client]struct{})
+	}
+	h.clients[client.UserID][client] = struct{}{}
+}
+
+// Unregister removes a client from the hub and closes its send channel
+func (h *Hub) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	userClients, ok := h.clients[client.UserID]
+	if !ok {
+		return
+	}
+	if _, ok := userClients[client]; !ok {
+		return
+	}
+
+	delete(userClients, client)
+	if len(userClients) == 0 {
+		delete(h.clients, client.UserID)
+	}
+	close(client.send)
+}
+
+// Publish delivers an event to the given users on this replica and, if a bridge is attached, on the other replicas
+func (h *Hub) Publish(userIDs []int, event model.Event) {
+	h.deliver(userIDs, event)
+
+	if h.bridge != nil {
+		h.bridge.notify(userIDs, event)
+	}
+}
+
+// deliver sends an event to the clients of the given users connected to this replica
+func (h *Hub) deliver(userIDs []int, event model.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding chat event: %v", err)
+		return
+	}
+
+	var slow []*Client
+
+	h.mu.RLock()
+	for _, userID := range uniqueIDs(userIDs) {
+		for client := range h.clients[userID] {
+			select {
+			case client.send <- data:
+			default:
+				slow = append(slow, client)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	// Drop clients that don't keep up; they will reconnect and reload the chat
+	for _, client := range slow {
+		log.Printf("Dropping slow chat client of user %d", client.UserID)
+		h.Unregister(client)
+	}
+}
+
+// uniqueIDs removes duplicate user IDs, e.g. when a user publishes to themselves
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -16,16 +17,151 @@ type Chat struct {
 	User2Name     string    `db:"user2_name" json:"user2_name,omitempty"`
 	ListingTitle  string    `db:"listing_title" json:"listing_title,omitempty"`
 	LastMessage   string    `db:"last_message" json:"last_message,omitempty"`
+	UnreadCount   int       `db:"unread_count" json:"unread_count"`
+
+	// Last message metadata, maintained when a message is added
+	LastMessageID         *int   `db:"last_message_id" json:"last_message_id,omitempty"`
+	LastMessageSenderID   *int   `db:"last_message_sender_id" json:"last_message_sender_id,omitempty"`
+	LastMessageSenderName string `db:"last_message_sender_name" json:"last_message_sender_name,omitempty"`
+
+	// State of the chat for the user viewing it
+	IsArchived bool       `db:"is_archived" json:"is_archived"`
+	IsPinned   bool       `db:"is_pinned" json:"is_pinned"`
+	IsMuted    bool       `db:"is_muted" json:"is_muted"`
+	MutedUntil *time.Time `db:"muted_until" json:"muted_until,omitempty"`
+}
+
+// MaxPinnedChats is the maximum number of chats a user can pin
+const MaxPinnedChats = 5
+
+// MuteChatRequest represents the data needed to mute a chat
+type MuteChatRequest struct {
+	// Until is when the chat is unmuted; the chat is muted indefinitely if it is empty
+	Until *time.Time `json:"until"`
 }
 
 // Message represents a chat message
 type Message struct {
-	ID         int       `db:"id" json:"id"`
-	ChatID     int       `db:"chat_id" json:"chat_id"`
-	SenderID   int       `db:"sender_id" json:"sender_id"`
-	Content    string    `db:"content" json:"content"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
-	SenderName string    `db:"sender_name" json:"sender_name,omitempty"`
+	ID         int        `db:"id" json:"id"`
+	ChatID     int        `db:"chat_id" json:"chat_id"`
+	SenderID   int        `db:"sender_id" json:"sender_id"`
+	Content    string     `db:"content" json:"content"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	SenderName string     `db:"sender_name" json:"sender_name,omitempty"`
+	ReadAt     *time.Time `db:"read_at" json:"read_at"`
+
+	// Editing and soft deletion; deleted messages have empty content and no attachments
+	EditedAt  *time.Time `db:"edited_at" json:"edited_at,omitempty"`
+	IsDeleted bool       `db:"is_deleted" json:"is_deleted"`
+
+	// Message this one replies to
+	ReplyToID *int            `db:"reply_to_id" json:"reply_to_id,omitempty"`
+	ReplyTo   *MessagePreview `json:"reply_to,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Kind is MessageKindText for messages written by users; system messages generated by
+	// listing and purchase events have their own kind and a JSON payload with the details
+	Kind    string           `db:"kind" json:"kind"`
+	Payload *json.RawMessage `db:"payload" json:"payload,omitempty"`
+
+	// Screening results: held messages are visible only to the sender until a moderator
+	// approves them, a warning tells the recipient the message looks like a scam
+	IsHeld  bool   `db:"held" json:"is_held,omitempty"`
+	Warning string `db:"warning" json:"warning,omitempty"`
+}
+
+// MessageScreening holds how a new message is delivered after screening
+type MessageScreening struct {
+	Held    bool
+	Warning string
+}
+
+// ScreeningEvent represents a screening rule triggered by a message, for the audit log
+type ScreeningEvent struct {
+	ID        int       `db:"id" json:"id"`
+	ChatID    *int      `db:"chat_id" json:"chat_id,omitempty"`
+	SenderID  int       `db:"sender_id" json:"sender_id"`
+	MessageID *int      `db:"message_id" json:"message_id,omitempty"`
+	Rule      string    `db:"rule" json:"rule"`
+	Action    string    `db:"action" json:"action"`
+	Detail    string    `db:"detail" json:"detail"`
+	Content   string    `db:"content" json:"content"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+
+	SenderName string `db:"sender_name" json:"sender_name,omitempty"`
+}
+
+// ScreeningEventResponse represents the screening audit log with pagination
+type ScreeningEventResponse struct {
+	Events      []ScreeningEvent `json:"events"`
+	TotalCount  int              `json:"total_count"`
+	CurrentPage int              `json:"current_page"`
+	TotalPages  int              `json:"total_pages"`
+}
+
+// Message kinds
+const (
+	MessageKindText = "text"
+	// System messages about the listing the chat is about
+	MessageKindPriceChanged    = "price_changed"
+	MessageKindListingReserved = "listing_reserved"
+	MessageKindSoldToYou       = "sold_to_you"
+	MessageKindSoldToOther     = "sold_to_other"
+	MessageKindListingRemoved  = "listing_removed"
+)
+
+// ListingEventPayload is the payload of system messages about a listing
+type ListingEventPayload struct {
+	ListingID    int      `json:"listing_id"`
+	ListingTitle string   `json:"listing_title"`
+	OldPrice     *float64 `json:"old_price,omitempty"`
+	NewPrice     *float64 `json:"new_price,omitempty"`
+	BuyerID      int      `json:"buyer_id,omitempty"`
+}
+
+// MessagePreview represents a quoted message in a reply
+type MessagePreview struct {
+	ID         int    `db:"id" json:"id"`
+	SenderID   int    `db:"sender_id" json:"sender_id"`
+	SenderName string `db:"sender_name" json:"sender_name"`
+	Content    string `db:"content" json:"content"`
+	IsDeleted  bool   `db:"is_deleted" json:"is_deleted"`
+}
+
+// MessageEditWindow is how long after sending a message its author can edit it
+const MessageEditWindow = 15 * time.Minute
+
+// Attachment represents an image or file attached to a chat message.
+// Files are served only to chat participants through the URL.
+type Attachment struct {
+	ID          int       `db:"id" json:"id"`
+	ChatID      int       `db:"chat_id" json:"chat_id"`
+	MessageID   *int      `db:"message_id" json:"message_id,omitempty"`
+	UploaderID  int       `db:"uploader_id" json:"uploader_id"`
+	FilePath    string    `db:"file_path" json:"-"`
+	FileName    string    `db:"file_name" json:"file_name"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Size        int64     `db:"size" json:"size"`
+	URL         string    `db:"url" json:"url"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Attachment limits
+const (
+	// MaxAttachmentSize is the maximum size of an attachment in bytes
+	MaxAttachmentSize = 10 << 20
+	// MaxAttachmentsPerMessage is the maximum number of attachments in a message
+	MaxAttachmentsPerMessage = 5
+)
+
+// AllowedAttachmentTypes are the content types accepted as attachments
+var AllowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
 }
 
 // InitiateChatRequest represents the data needed to start a chat
@@ -35,11 +171,111 @@ type InitiateChatRequest struct {
 	Message     string `json:"message" binding:"required"`
 }
 
-// SendMessageRequest represents the data needed to send a message
+// SendMessageRequest represents the data needed to send a message.
+// A message must have content, attachments or both.
 type SendMessageRequest struct {
+	Content       string `json:"content" form:"content"`
+	AttachmentIDs []int  `json:"attachment_ids" form:"attachment_ids"`
+	ReplyToID     *int   `json:"reply_to_id" form:"reply_to_id"`
+}
+
+// EditMessageRequest represents the data needed to edit a message
+type EditMessageRequest struct {
 	Content string `json:"content" binding:"required"`
 }
 
+// MarkReadRequest represents the data needed to mark messages as read
+type MarkReadRequest struct {
+	// MessageID is the last message to mark as read; all messages are marked if it is empty
+	MessageID int `json:"message_id"`
+}
+
+// MessageCursor selects a window of chat messages relative to a message ID.
+// Without Before and After the newest messages are returned.
+type MessageCursor struct {
+	Before int
+	After  int
+	Limit  int
+	// HiddenUpToID hides messages up to this ID, e.g. after the user deleted the chat for themselves
+	HiddenUpToID int
+	// ViewerID is the user reading the chat; held messages are visible only to their sender
+	ViewerID int
+}
+
+// MessageWindow represents a window of chat messages, newest first
+type MessageWindow struct {
+	Messages []Message `json:"messages"`
+	HasOlder bool      `db:"has_older" json:"has_older"`
+	HasNewer bool      `db:"has_newer" json:"has_newer"`
+}
+
+// MessageSearchResult represents a message matching a chat search
+type MessageSearchResult struct {
+	MessageID    int       `db:"message_id" json:"message_id"`
+	ChatID       int       `db:"chat_id" json:"chat_id"`
+	SenderID     int       `db:"sender_id" json:"sender_id"`
+	SenderName   string    `db:"sender_name" json:"sender_name"`
+	ListingTitle string    `db:"listing_title" json:"listing_title,omitempty"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	// Highlight is an HTML-escaped fragment of the message with matches wrapped in <mark> tags
+	Highlight string `db:"highlight" json:"highlight"`
+}
+
+// MessageSearchResponse represents chat search results with pagination
+type MessageSearchResponse struct {
+	Results     []MessageSearchResult `json:"results"`
+	TotalCount  int                   `json:"total_count"`
+	CurrentPage int                   `json:"current_page"`
+	TotalPages  int                   `json:"total_pages"`
+}
+
+// ChatReport represents a report of abuse in a chat
+type ChatReport struct {
+	ID               int        `db:"id" json:"id"`
+	ChatID           int        `db:"chat_id" json:"chat_id"`
+	ReporterID       int        `db:"reporter_id" json:"reporter_id"`
+	ReportedUserID   int        `db:"reported_user_id" json:"reported_user_id"`
+	MessageID        *int       `db:"message_id" json:"message_id,omitempty"`
+	Reason           string     `db:"reason" json:"reason"`
+	Comment          string     `db:"comment" json:"comment,omitempty"`
+	Status           string     `db:"status" json:"status"`
+	Resolution       string     `db:"resolution" json:"resolution,omitempty"`
+	ResolvedBy       *int       `db:"resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt       *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	ReporterName     string     `db:"reporter_name" json:"reporter_name,omitempty"`
+	ReportedUserName string     `db:"reported_user_name" json:"reported_user_name,omitempty"`
+	Messages         []Message  `json:"messages,omitempty"`
+}
+
+// Chat report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+// ReportRequest represents the data needed to report a chat
+type ReportRequest struct {
+	Reason    string `json:"reason" binding:"required,oneof=spam harassment scam other"`
+	MessageID *int   `json:"message_id"`
+	Comment   string `json:"comment" binding:"max=2000"`
+}
+
+// ResolveReportRequest represents a moderator's decision on a chat report
+type ResolveReportRequest struct {
+	Status     string `json:"status" binding:"required,oneof=resolved dismissed"`
+	Resolution string `json:"resolution" binding:"max=2000"`
+}
+
+// ReportResponse represents a list of chat reports with pagination
+type ReportResponse struct {
+	Reports     []ChatReport `json:"reports"`
+	TotalCount  int          `json:"total_count"`
+	CurrentPage int          `json:"current_page"`
+	TotalPages  int          `json:"total_pages"`
+}
+
 // ChatResponse represents a list of chats with pagination
 type ChatResponse struct {
 	Chats       []Chat `json:"chats"`
@@ -55,3 +291,35 @@ type MessageResponse struct {
 	CurrentPage int       `json:"current_page"`
 	TotalPages  int       `json:"total_pages"`
 }
+
+// Real-time event types sent over the chat WebSocket
+const (
+	// EventMessage is sent to both participants when a new message is created
+	EventMessage = "message"
+	// EventTyping is sent to the other participant when a user starts or stops typing
+	EventTyping = "typing"
+	// EventRead is sent to the other participant when a user reads their messages
+	EventRead = "read"
+	// EventMessageEdited is sent to both participants when a message is edited
+	EventMessageEdited = "message_edited"
+	// EventMessageDeleted is sent to both participants when a message is deleted
+	EventMessageDeleted = "message_deleted"
+)
+
+// Event represents a real-time chat event delivered to connected clients
+type Event struct {
+	Type     string   `json:"type"`
+	ChatID   int      `json:"chat_id"`
+	Message  *Message `json:"message,omitempty"`
+	UserID   int      `json:"user_id,omitempty"`
+	IsTyping bool     `json:"is_typing,omitempty"`
+	// ReadUpToID is the last message read by UserID in read events
+	ReadUpToID int `json:"read_up_to_id,omitempty"`
+}
+
+// ClientEvent represents an event sent by a client over the chat WebSocket
+type ClientEvent struct {
+	Type     string `json:"type"`
+	ChatID   int    `json:"chat_id"`
+	IsTyping bool   `json:"is_typing"`
+}
@@ -2,13 +2,16 @@ package repository
 
 import (
 	"FurniSwap/internal/modules/chat/model"
+	"FurniSwap/pkg/middleware"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // Repository handles database operations for the chat module
@@ -31,7 +34,7 @@ func (r *Repository) GetChatByUsers(user1ID, user2ID int, listingID *int) (*mode
 	if listingID != nil {
 		err = r.db.Get(&chat, `
 			SELECT id, buyer_id as user1_id, seller_id as user2_id, listing_id,
-                   created_at, created_at as last_message_at
+                   created_at, last_message_at, last_message_id
 			FROM chats
 			WHERE (buyer_id = $1 AND seller_id = $2 AND listing_id = $3)
 			   OR (buyer_id = $2 AND seller_id = $1 AND listing_id = $3)
@@ -39,7 +42,7 @@ func (r *Repository) GetChatByUsers(user1ID, user2ID int, listingID *int) (*mode
 	} else {
 		err = r.db.Get(&chat, `
 			SELECT id, buyer_id as user1_id, seller_id as user2_id, listing_id,
-                   created_at, created_at as last_message_at
+                   created_at, last_message_at, last_message_id
 			FROM chats
 			WHERE ((buyer_id = $1 AND seller_id = $2) OR (buyer_id = $2 AND seller_id = $1))
 			AND listing_id IS NULL
@@ -74,34 +77,145 @@ func (r *Repository) CreateChat(buyerID, sellerID int, listingID *int) (int, err
 	return chatID, nil
 }
 
-// AddMessage adds a message to a chat
-func (r *Repository) AddMessage(chatID, senderID int, content string) (int, error) {
+// AddMessage adds a message to a chat, links the sender's pending attachments to it
+// and updates the chat's last message
+// Held messages don't become the chat's last message until they are released.
+func (r *Repository) AddMessage(chatID, senderID int, content string, replyToID *int, attachmentIDs []int, screening model.MessageScreening) (int, error) {
+	// Begin transaction
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error beginning transaction: %v", err)
+		return 0, fmt.Errorf("error beginning transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	now := time.Now()
+
 	var messageID int
-	err := r.db.QueryRow(`
-		INSERT INTO messages (chat_id, user_id, content, created_at)
-		VALUES ($1, $2, $3, $4)
+	err = tx.QueryRow(`
+		INSERT INTO messages (chat_id, user_id, content, reply_to_id, created_at, held, warning)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id
-	`, chatID, senderID, content, time.Now()).Scan(&messageID)
-
+	`, chatID, senderID, content, replyToID, now, screening.Held, screening.Warning).Scan(&messageID)
 	if err != nil {
+		tx.Rollback()
 		log.Printf("Error adding message: %v", err)
 		return 0, fmt.Errorf("error adding message: %w", err)
 	}
 
+	if len(attachmentIDs) > 0 {
+		result, err := tx.Exec(`
+			UPDATE message_attachments SET message_id = $1
+			WHERE id = ANY($2) AND chat_id = $3 AND uploader_id = $4 AND message_id IS NULL
+		`, messageID, pq.Array(attachmentIDs), chatID, senderID)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error linking attachments: %v", err)
+			return 0, fmt.Errorf("error linking attachments: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("error getting rows affected: %w", err)
+		}
+		if int(rowsAffected) != len(attachmentIDs) {
+			tx.Rollback()
+			return 0, errors.New("attachment not found")
+		}
+	}
+
+	if !screening.Held {
+		_, err = tx.Exec(`
+			UPDATE chats SET last_message_id = $1, last_message_at = $2
+			WHERE id = $3
+		`, messageID, now, chatID)
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Error updating chat last message: %v", err)
+			return 0, fmt.Errorf("error updating chat last message: %w", err)
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
 	return messageID, nil
 }
 
-// GetUserChats gets a user's chats with pagination
-func (r *Repository) GetUserChats(userID, page, limit int) (*model.ChatResponse, error) {
+// AddSystemMessage adds a system message of the given kind to a chat and makes it the chat's last message
+func (r *Repository) AddSystemMessage(chatID, senderID int, kind, content string, payload []byte) (int, error) {
+	var messageID int
+	err := r.db.QueryRow(`
+		WITH m AS (
+			INSERT INTO messages (chat_id, user_id, content, kind, payload, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, chat_id, created_at
+		)
+		UPDATE chats SET last_message_id = m.id, last_message_at = m.created_at
+		FROM m
+		WHERE chats.id = m.chat_id
+		RETURNING m.id
+	`, chatID, senderID, content, kind, string(payload), time.Now()).Scan(&messageID)
+	if err != nil {
+		log.Printf("Error adding system message: %v", err)
+		return 0, fmt.Errorf("error adding system message: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// GetListingChats gets all chats about a listing
+func (r *Repository) GetListingChats(listingID int) ([]model.Chat, error) {
+	var chats []model.Chat
+	err := r.db.Select(&chats, `
+		SELECT id, buyer_id as user1_id, seller_id as user2_id, listing_id,
+		       created_at, last_message_at, last_message_id
+		FROM chats
+		WHERE listing_id = $1
+		ORDER BY id
+	`, listingID)
+	if err != nil {
+		log.Printf("Error getting listing chats: %v", err)
+		return nil, fmt.Errorf("error getting listing chats: %w", err)
+	}
+
+	return chats, nil
+}
+
+// chatMuted is the condition of a chat being muted by the user of the joined chat_user_states row s
+const chatMuted = `COALESCE(s.muted AND (s.muted_until IS NULL OR s.muted_until > NOW()), FALSE)`
+
+// userChatsFrom joins the chats with the state of user $1
+const userChatsFrom = `
+	FROM chats c
+	LEFT JOIN chat_user_states s ON s.chat_id = c.id AND s.user_id = $1`
+
+// userChatsWhere selects the chats of user $1 in the archive ($2 = true) or the main list.
+// Chats deleted by the user are hidden until a new message arrives.
+const userChatsWhere = `
+	WHERE (c.buyer_id = $1 OR c.seller_id = $1)
+	  AND COALESCE(s.archived, FALSE) = $2
+	  AND (s.deleted_up_to_id IS NULL OR c.last_message_id > s.deleted_up_to_id)`
+
+// GetUserChats gets a user's chats with pagination, pinned chats first.
+// With archived set it gets the archived chats instead.
+func (r *Repository) GetUserChats(userID int, archived bool, page, limit int) (*model.ChatResponse, error) {
 	// Calculate offset
 	offset := (page - 1) * limit
 
 	// Get total count
 	var totalCount int
-	err := r.db.Get(&totalCount, `
-		SELECT COUNT(*) FROM chats
-		WHERE buyer_id = $1 OR seller_id = $1
-	`, userID)
+	err := r.db.Get(&totalCount, "SELECT COUNT(*)"+userChatsFrom+userChatsWhere, userID, archived)
 	if err != nil {
 		log.Printf("Error getting chat count: %v", err)
 		return nil, fmt.Errorf("error getting chat count: %w", err)
@@ -118,21 +232,31 @@ func (r *Repository) GetUserChats(userID, page, limit int) (*model.ChatResponse,
 			   c.seller_id as user2_id, 
 			   c.listing_id,
 			   c.created_at,
-			   c.created_at as last_message_at,
+			   c.last_message_at,
+			   c.last_message_id,
 			   u1.name || ' ' || COALESCE(u1.last_name, '') as user1_name,
 			   u2.name || ' ' || COALESCE(u2.last_name, '') as user2_name,
 			   l.title as listing_title,
-			   COALESCE((SELECT content FROM messages 
-				WHERE chat_id = c.id 
-				ORDER BY created_at DESC LIMIT 1), '') as last_message
-		FROM chats c
+			   CASE WHEN lm.deleted_at IS NULL THEN COALESCE(LEFT(lm.content, 100), '') ELSE '' END as last_message,
+			   lm.user_id as last_message_sender_id,
+			   COALESCE(lu.name || ' ' || COALESCE(lu.last_name, ''), '') as last_message_sender_name,
+			   (SELECT COUNT(*) FROM messages
+				WHERE chat_id = c.id AND user_id <> $1 AND is_read = FALSE AND NOT held
+				  AND id > COALESCE(s.deleted_up_to_id, 0)) as unread_count,
+			   COALESCE(s.archived, FALSE) as is_archived,
+			   COALESCE(s.pinned, FALSE) as is_pinned,
+			   `+chatMuted+` as is_muted,
+			   CASE WHEN `+chatMuted+` THEN s.muted_until END as muted_until
+		`+userChatsFrom+`
 		JOIN users u1 ON c.buyer_id = u1.id
 		JOIN users u2 ON c.seller_id = u2.id
 		LEFT JOIN listings l ON c.listing_id = l.id
-		WHERE c.buyer_id = $1 OR c.seller_id = $1
-		ORDER BY c.created_at DESC
-		LIMIT $2 OFFSET $3
-	`, userID, limit, offset)
+		LEFT JOIN messages lm ON c.last_message_id = lm.id
+		LEFT JOIN users lu ON lm.user_id = lu.id
+		`+userChatsWhere+`
+		ORDER BY is_pinned DESC, c.last_message_at DESC, c.id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, archived, limit, offset)
 
 	if err != nil {
 		log.Printf("Error getting chats: %v", err)
@@ -147,14 +271,112 @@ func (r *Repository) GetUserChats(userID, page, limit int) (*model.ChatResponse,
 	}, nil
 }
 
+// GetHiddenUpToID gets the ID up to which messages of a chat are hidden from the user; 0 if none are
+func (r *Repository) GetHiddenUpToID(chatID, userID int) (int, error) {
+	var hiddenUpToID int
+	err := r.db.Get(&hiddenUpToID, `
+		SELECT COALESCE(MAX(deleted_up_to_id), 0) FROM chat_user_states
+		WHERE chat_id = $1 AND user_id = $2
+	`, chatID, userID)
+	if err != nil {
+		log.Printf("Error getting chat state: %v", err)
+		return 0, fmt.Errorf("error getting chat state: %w", err)
+	}
+
+	return hiddenUpToID, nil
+}
+
+// updateChatState creates the user's state of a chat if needed and applies the SET clause to it.
+// The chat and user IDs are $1 and $2, the clause arguments start at $3.
+func (r *Repository) updateChatState(chatID, userID int, set string, args ...interface{}) error {
+	_, err := r.db.Exec(`
+		INSERT INTO chat_user_states (chat_id, user_id) VALUES ($1, $2)
+		ON CONFLICT (chat_id, user_id) DO NOTHING
+	`, chatID, userID)
+	if err != nil {
+		log.Printf("Error creating chat state: %v", err)
+		return fmt.Errorf("error creating chat state: %w", err)
+	}
+
+	_, err = r.db.Exec("UPDATE chat_user_states SET "+set+" WHERE chat_id = $1 AND user_id = $2",
+		append([]interface{}{chatID, userID}, args...)...)
+	if err != nil {
+		log.Printf("Error updating chat state: %v", err)
+		return fmt.Errorf("error updating chat state: %w", err)
+	}
+
+	return nil
+}
+
+// SetChatArchived archives or unarchives a chat for the user; archived chats are unpinned
+func (r *Repository) SetChatArchived(chatID, userID int, archived bool) error {
+	return r.updateChatState(chatID, userID, "archived = $3, pinned = pinned AND NOT $3", archived)
+}
+
+// SetChatPinned pins or unpins a chat for the user; pinned chats are unarchived
+func (r *Repository) SetChatPinned(chatID, userID int, pinned bool) error {
+	return r.updateChatState(chatID, userID, "pinned = $3, archived = archived AND NOT $3", pinned)
+}
+
+// SetChatMuted mutes a chat for the user until the given time or indefinitely if it is nil
+func (r *Repository) SetChatMuted(chatID, userID int, until *time.Time) error {
+	return r.updateChatState(chatID, userID, "muted = TRUE, muted_until = $3", until)
+}
+
+// UnmuteChat unmutes a chat for the user
+func (r *Repository) UnmuteChat(chatID, userID int) error {
+	return r.updateChatState(chatID, userID, "muted = FALSE, muted_until = NULL")
+}
+
+// DeleteChatForUser hides a chat and its current messages from the user.
+// The chat shows up again with the messages that arrive later.
+func (r *Repository) DeleteChatForUser(chatID, userID int) error {
+	return r.updateChatState(chatID, userID, `
+		archived = FALSE, pinned = FALSE,
+		deleted_up_to_id = (SELECT COALESCE(MAX(id), 0) FROM messages WHERE chat_id = $1)`)
+}
+
+// CountPinnedChats counts the chats pinned by the user other than the given chat
+func (r *Repository) CountPinnedChats(userID, exceptChatID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, `
+		SELECT COUNT(*) FROM chat_user_states
+		WHERE user_id = $1 AND pinned AND chat_id <> $2
+	`, userID, exceptChatID)
+	if err != nil {
+		log.Printf("Error counting pinned chats: %v", err)
+		return 0, fmt.Errorf("error counting pinned chats: %w", err)
+	}
+
+	return count, nil
+}
+
+// UnarchiveChat brings a chat back from the archive of its participants, except for those who muted it
+func (r *Repository) UnarchiveChat(chatID int) error {
+	_, err := r.db.Exec(`
+		UPDATE chat_user_states s SET archived = FALSE
+		WHERE s.chat_id = $1 AND s.archived AND NOT `+chatMuted+`
+	`, chatID)
+	if err != nil {
+		log.Printf("Error unarchiving chat: %v", err)
+		return fmt.Errorf("error unarchiving chat: %w", err)
+	}
+
+	return nil
+}
+
 // GetChatMessages gets messages for a chat with pagination
-func (r *Repository) GetChatMessages(chatID, page, limit int) (*model.MessageResponse, error) {
+// Messages up to hiddenUpToID and messages held from the viewer are left out.
+func (r *Repository) GetChatMessages(chatID, viewerID, hiddenUpToID, page, limit int) (*model.MessageResponse, error) {
 	// Calculate offset
 	offset := (page - 1) * limit
 
 	// Get total count
 	var totalCount int
-	err := r.db.Get(&totalCount, "SELECT COUNT(*) FROM messages WHERE chat_id = $1", chatID)
+	err := r.db.Get(&totalCount, `
+		SELECT COUNT(*) FROM messages
+		WHERE chat_id = $1 AND id > $2 AND (NOT held OR user_id = $3)
+	`, chatID, hiddenUpToID, viewerID)
 	if err != nil {
 		log.Printf("Error getting message count: %v", err)
 		return nil, fmt.Errorf("error getting message count: %w", err)
@@ -165,21 +387,21 @@ func (r *Repository) GetChatMessages(chatID, page, limit int) (*model.MessageRes
 
 	// Get messages
 	var messages []model.Message
-	err = r.db.Select(&messages, `
-		SELECT m.id, m.chat_id, m.user_id as sender_id, m.content, m.created_at,
-			   u.name || ' ' || COALESCE(u.last_name, '') as sender_name
-		FROM messages m
-		JOIN users u ON m.user_id = u.id
-		WHERE m.chat_id = $1
+	err = r.db.Select(&messages, messageSelect+`
+		WHERE m.chat_id = $1 AND m.id > $4 AND (NOT m.held OR m.user_id = $5)
 		ORDER BY m.created_at ASC
 		LIMIT $2 OFFSET $3
-	`, chatID, limit, offset)
+	`, chatID, limit, offset, hiddenUpToID, viewerID)
 
 	if err != nil {
 		log.Printf("Error getting messages: %v", err)
 		return nil, fmt.Errorf("error getting messages: %w", err)
 	}
 
+	if err := r.loadMessageDetails(messages); err != nil {
+		return nil, err
+	}
+
 	return &model.MessageResponse{
 		Messages:    messages,
 		TotalCount:  totalCount,
@@ -188,19 +410,146 @@ func (r *Repository) GetChatMessages(chatID, page, limit int) (*model.MessageRes
 	}, nil
 }
 
+// GetChatMessagesWindow gets a window of chat messages around a cursor, newest first
+func (r *Repository) GetChatMessagesWindow(chatID int, cursor model.MessageCursor) (*model.MessageWindow, error) {
+	var messages []model.Message
+	var err error
+
+	switch {
+	case cursor.Before > 0:
+		err = r.db.Select(&messages, messageSelect+`
+			WHERE m.chat_id = $1 AND m.id < $2 AND m.id > $4 AND (NOT m.held OR m.user_id = $5)
+			ORDER BY m.id DESC
+			LIMIT $3
+		`, chatID, cursor.Before, cursor.Limit, cursor.HiddenUpToID, cursor.ViewerID)
+	case cursor.After > 0:
+		err = r.db.Select(&messages, messageSelect+`
+			WHERE m.chat_id = $1 AND m.id > $2 AND m.id > $4 AND (NOT m.held OR m.user_id = $5)
+			ORDER BY m.id ASC
+			LIMIT $3
+		`, chatID, cursor.After, cursor.Limit, cursor.HiddenUpToID, cursor.ViewerID)
+		// Return the window newest first like the others
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	default:
+		err = r.db.Select(&messages, messageSelect+`
+			WHERE m.chat_id = $1 AND m.id > $3 AND (NOT m.held OR m.user_id = $4)
+			ORDER BY m.id DESC
+			LIMIT $2
+		`, chatID, cursor.Limit, cursor.HiddenUpToID, cursor.ViewerID)
+	}
+
+	if err != nil {
+		log.Printf("Error getting messages window: %v", err)
+		return nil, fmt.Errorf("error getting messages: %w", err)
+	}
+
+	window := &model.MessageWindow{Messages: []model.Message{}}
+	if len(messages) == 0 {
+		// An empty window after a cursor still has older messages, and vice versa
+		window.HasOlder = cursor.After > cursor.HiddenUpToID
+		window.HasNewer = cursor.Before > 0
+		return window, nil
+	}
+
+	if err := r.loadMessageDetails(messages); err != nil {
+		return nil, err
+	}
+	window.Messages = messages
+
+	// Check whether there are more messages on both sides of the window
+	newestID := messages[0].ID
+	oldestID := messages[len(messages)-1].ID
+	err = r.db.Get(window, `
+		SELECT EXISTS(SELECT 1 FROM messages WHERE chat_id = $1 AND id < $2 AND id > $4
+		                AND (NOT held OR user_id = $5)) as has_older,
+		       EXISTS(SELECT 1 FROM messages WHERE chat_id = $1 AND id > $3
+		                AND (NOT held OR user_id = $5)) as has_newer
+	`, chatID, oldestID, newestID, cursor.HiddenUpToID, cursor.ViewerID)
+	if err != nil {
+		log.Printf("Error checking messages around window: %v", err)
+		return nil, fmt.Errorf("error checking messages around window: %w", err)
+	}
+
+	return window, nil
+}
+
+// SearchMessages performs a full-text search across the messages of all of the user's chats
+func (r *Repository) SearchMessages(userID int, query string, page, limit int) (*model.MessageSearchResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	const searchCondition = `
+		FROM messages m
+		JOIN chats c ON m.chat_id = c.id
+		JOIN users u ON m.user_id = u.id
+		LEFT JOIN listings l ON c.listing_id = l.id
+		LEFT JOIN chat_user_states s ON s.chat_id = c.id AND s.user_id = $1
+		WHERE (c.buyer_id = $1 OR c.seller_id = $1)
+		  AND m.id > COALESCE(s.deleted_up_to_id, 0)
+		  AND (NOT m.held OR m.user_id = $1)
+		  AND m.deleted_at IS NULL
+		  AND m.kind = 'text'
+		  AND to_tsvector('russian', m.content) @@ plainto_tsquery('russian', $2)`
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*)"+searchCondition, userID, query)
+	if err != nil {
+		log.Printf("Error getting message search count: %v", err)
+		return nil, fmt.Errorf("error getting message search count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	// Get results; content is HTML-escaped before highlighting so only <mark> tags are markup
+	results := []model.MessageSearchResult{}
+	err = r.db.Select(&results, `
+		SELECT m.id as message_id, m.chat_id, m.user_id as sender_id, m.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as sender_name,
+		       COALESCE(l.title, '') as listing_title,
+		       ts_headline('russian',
+		           replace(replace(replace(m.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		           plainto_tsquery('russian', $2),
+		           'StartSel=<mark>, StopSel=</mark>, MaxWords=30, MinWords=10, MaxFragments=2'
+		       ) as highlight
+		`+searchCondition+`
+		ORDER BY m.created_at DESC
+		LIMIT $3 OFFSET $4
+	`, userID, query, limit, offset)
+	if err != nil {
+		log.Printf("Error searching messages: %v", err)
+		return nil, fmt.Errorf("error searching messages: %w", err)
+	}
+
+	return &model.MessageSearchResponse{
+		Results:     results,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
 // GetChatByID retrieves a chat by ID
 func (r *Repository) GetChatByID(chatID int) (*model.Chat, error) {
 	var chat model.Chat
 	err := r.db.Get(&chat, `
 		SELECT c.id, c.buyer_id as user1_id, c.seller_id as user2_id, c.listing_id,
-			   c.created_at, c.created_at as last_message_at,
+			   c.created_at, c.last_message_at, c.last_message_id,
 			   u1.name || ' ' || COALESCE(u1.last_name, '') as user1_name,
 			   u2.name || ' ' || COALESCE(u2.last_name, '') as user2_name,
-			   l.title as listing_title
+			   l.title as listing_title,
+			   CASE WHEN lm.deleted_at IS NULL THEN COALESCE(LEFT(lm.content, 100), '') ELSE '' END as last_message,
+			   lm.user_id as last_message_sender_id,
+			   COALESCE(lu.name || ' ' || COALESCE(lu.last_name, ''), '') as last_message_sender_name
 		FROM chats c
 		JOIN users u1 ON c.buyer_id = u1.id
 		JOIN users u2 ON c.seller_id = u2.id
 		LEFT JOIN listings l ON c.listing_id = l.id
+		LEFT JOIN messages lm ON c.last_message_id = lm.id
+		LEFT JOIN users lu ON lm.user_id = lu.id
 		WHERE c.id = $1
 	`, chatID)
 
@@ -227,3 +576,481 @@ func (r *Repository) CheckChatAccess(chatID, userID int) (bool, error) {
 
 	return count > 0, nil
 }
+
+// GetMessageByID retrieves a message by ID
+func (r *Repository) GetMessageByID(messageID int) (*model.Message, error) {
+	var message model.Message
+	err := r.db.Get(&message, messageSelect+" WHERE m.id = $1", messageID)
+
+	if err != nil {
+		log.Printf("Error getting message by ID: %v", err)
+		return nil, fmt.Errorf("error getting message: %w", err)
+	}
+
+	messages := []model.Message{message}
+	if err := r.loadMessageDetails(messages); err != nil {
+		return nil, err
+	}
+
+	return &messages[0], nil
+}
+
+// MarkMessagesRead marks the messages the user received in a chat as read, up to upToID
+// (all messages if upToID is 0). It returns the ID of the last message marked, or 0 if none were unread.
+func (r *Repository) MarkMessagesRead(chatID, userID, upToID int) (int, error) {
+	var lastID int
+	err := r.db.Get(&lastID, `
+		WITH updated AS (
+			UPDATE messages SET is_read = TRUE, read_at = $4
+			WHERE chat_id = $1 AND user_id <> $2 AND is_read = FALSE AND NOT held
+			  AND ($3 = 0 OR id <= $3)
+			RETURNING id
+		)
+		SELECT COALESCE(MAX(id), 0) FROM updated
+	`, chatID, userID, upToID, time.Now())
+
+	if err != nil {
+		log.Printf("Error marking messages as read: %v", err)
+		return 0, fmt.Errorf("error marking messages as read: %w", err)
+	}
+
+	return lastID, nil
+}
+
+// GetUnreadCount gets the number of unread messages the user received across all chats
+func (r *Repository) GetUnreadCount(userID int) (int, error) {
+	var count int
+	err := r.db.Get(&count, `
+		SELECT COUNT(*) FROM messages m
+		JOIN chats c ON m.chat_id = c.id
+		LEFT JOIN chat_user_states s ON s.chat_id = c.id AND s.user_id = $1
+		WHERE (c.buyer_id = $1 OR c.seller_id = $1)
+		  AND m.user_id <> $1 AND m.is_read = FALSE AND NOT m.held
+		  AND m.id > COALESCE(s.deleted_up_to_id, 0)
+		  AND NOT `+chatMuted+`
+	`, userID)
+
+	if err != nil {
+		log.Printf("Error getting unread count: %v", err)
+		return 0, fmt.Errorf("error getting unread count: %w", err)
+	}
+
+	return count, nil
+}
+
+// messageSelect selects message columns; the content of deleted messages is blanked out
+const messageSelect = `
+	SELECT m.id, m.chat_id, m.user_id as sender_id,
+	       CASE WHEN m.deleted_at IS NULL THEN m.content ELSE '' END as content,
+	       m.created_at, m.read_at, m.edited_at, m.deleted_at IS NOT NULL as is_deleted, m.reply_to_id,
+	       m.kind, m.payload, m.held, m.warning,
+	       u.name || ' ' || COALESCE(u.last_name, '') as sender_name
+	FROM messages m
+	JOIN users u ON m.user_id = u.id`
+
+// UpdateMessageContent edits the content and screening warning of a message; it returns false if the message is deleted
+func (r *Repository) UpdateMessageContent(messageID int, content, warning string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE messages SET content = $1, warning = $2, edited_at = $3
+		WHERE id = $4 AND deleted_at IS NULL
+	`, content, warning, time.Now(), messageID)
+	if err != nil {
+		log.Printf("Error updating message: %v", err)
+		return false, fmt.Errorf("error updating message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// DeleteMessage soft-deletes a message; it returns false if the message is already deleted
+func (r *Repository) DeleteMessage(messageID int) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE messages SET deleted_at = $1
+		WHERE id = $2 AND deleted_at IS NULL
+	`, time.Now(), messageID)
+	if err != nil {
+		log.Printf("Error deleting message: %v", err)
+		return false, fmt.Errorf("error deleting message: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// loadMessageDetails fills in the attachments and quoted replies of the given messages
+func (r *Repository) loadMessageDetails(messages []model.Message) error {
+	if err := r.loadAttachments(messages); err != nil {
+		return err
+	}
+	return r.loadReplies(messages)
+}
+
+// loadReplies fills in previews of the messages the given messages reply to
+func (r *Repository) loadReplies(messages []model.Message) error {
+	var replyIDs []int
+	for _, message := range messages {
+		if message.ReplyToID != nil {
+			replyIDs = append(replyIDs, *message.ReplyToID)
+		}
+	}
+	if len(replyIDs) == 0 {
+		return nil
+	}
+
+	var previews []model.MessagePreview
+	err := r.db.Select(&previews, `
+		SELECT m.id, m.user_id as sender_id,
+		       u.name || ' ' || COALESCE(u.last_name, '') as sender_name,
+		       CASE WHEN m.deleted_at IS NULL THEN LEFT(m.content, 100) ELSE '' END as content,
+		       m.deleted_at IS NOT NULL as is_deleted
+		FROM messages m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.id = ANY($1)
+	`, pq.Array(replyIDs))
+	if err != nil {
+		log.Printf("Error getting replied messages: %v", err)
+		return fmt.Errorf("error getting replied messages: %w", err)
+	}
+
+	byID := make(map[int]model.MessagePreview, len(previews))
+	for _, preview := range previews {
+		byID[preview.ID] = preview
+	}
+	for i := range messages {
+		if messages[i].ReplyToID == nil {
+			continue
+		}
+		if preview, ok := byID[*messages[i].ReplyToID]; ok {
+			messages[i].ReplyTo = &preview
+		}
+	}
+
+	return nil
+}
+
+// attachmentSelect selects attachment columns with the access-controlled download URL
+const attachmentSelect = `
+	SELECT id, chat_id, message_id, uploader_id, file_path, file_name, content_type, size, created_at,
+	       '/api/chats/' || chat_id || '/attachments/' || id as url
+	FROM message_attachments`
+
+// CreateAttachment saves an uploaded attachment that is not yet linked to a message
+func (r *Repository) CreateAttachment(chatID, uploaderID int, filePath, fileName, contentType string, size int64) (int, error) {
+	var attachmentID int
+	err := r.db.QueryRow(`
+		INSERT INTO message_attachments (chat_id, uploader_id, file_path, file_name, content_type, size, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, chatID, uploaderID, filePath, fileName, contentType, size, time.Now()).Scan(&attachmentID)
+
+	if err != nil {
+		log.Printf("Error creating attachment: %v", err)
+		return 0, fmt.Errorf("error creating attachment: %w", err)
+	}
+
+	return attachmentID, nil
+}
+
+// GetAttachment retrieves an attachment by ID
+func (r *Repository) GetAttachment(attachmentID int) (*model.Attachment, error) {
+	var attachment model.Attachment
+	// Attachments of deleted messages are hidden
+	err := r.db.Get(&attachment, attachmentSelect+`
+		WHERE id = $1 AND (message_id IS NULL OR message_id IN (SELECT id FROM messages WHERE deleted_at IS NULL))
+	`, attachmentID)
+	if err != nil {
+		log.Printf("Error getting attachment: %v", err)
+		return nil, fmt.Errorf("error getting attachment: %w", err)
+	}
+
+	return &attachment, nil
+}
+
+// loadAttachments fills in the attachments of the given messages, except deleted ones
+func (r *Repository) loadAttachments(messages []model.Message) error {
+	var messageIDs []int
+	for _, message := range messages {
+		if !message.IsDeleted {
+			messageIDs = append(messageIDs, message.ID)
+		}
+	}
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	var attachments []model.Attachment
+	err := r.db.Select(&attachments, attachmentSelect+" WHERE message_id = ANY($1) ORDER BY id", pq.Array(messageIDs))
+	if err != nil {
+		log.Printf("Error getting message attachments: %v", err)
+		return fmt.Errorf("error getting message attachments: %w", err)
+	}
+
+	byMessage := make(map[int][]model.Attachment)
+	for _, attachment := range attachments {
+		byMessage[*attachment.MessageID] = append(byMessage[*attachment.MessageID], attachment)
+	}
+	for i := range messages {
+		messages[i].Attachments = byMessage[messages[i].ID]
+	}
+
+	return nil
+}
+
+// UserExists checks if a user exists
+func (r *Repository) UserExists(userID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID)
+	if err != nil {
+		log.Printf("Error checking user existence: %v", err)
+		return false, fmt.Errorf("error checking user existence: %w", err)
+	}
+	return exists, nil
+}
+
+// reportSelect selects chat report columns with the names of the users involved
+const reportSelect = `
+	SELECT r.id, r.chat_id, r.reporter_id, r.reported_user_id, r.message_id, r.reason, r.comment,
+	       r.status, r.resolution, r.resolved_by, r.resolved_at, r.created_at,
+	       u1.name || ' ' || COALESCE(u1.last_name, '') as reporter_name,
+	       u2.name || ' ' || COALESCE(u2.last_name, '') as reported_user_name
+	FROM chat_reports r
+	JOIN users u1 ON r.reporter_id = u1.id
+	JOIN users u2 ON r.reported_user_id = u2.id`
+
+// CreateReport creates a chat report
+func (r *Repository) CreateReport(chatID, reporterID, reportedUserID int, req model.ReportRequest) (int, error) {
+	var reportID int
+	err := r.db.QueryRow(`
+		INSERT INTO chat_reports (chat_id, reporter_id, reported_user_id, message_id, reason, comment, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, chatID, reporterID, reportedUserID, req.MessageID, req.Reason, req.Comment, model.ReportStatusOpen, time.Now()).Scan(&reportID)
+
+	if err != nil {
+		log.Printf("Error creating chat report: %v", err)
+		return 0, fmt.Errorf("error creating chat report: %w", err)
+	}
+
+	return reportID, nil
+}
+
+// GetReportByID retrieves a chat report by ID
+func (r *Repository) GetReportByID(reportID int) (*model.ChatReport, error) {
+	var report model.ChatReport
+	err := r.db.Get(&report, reportSelect+" WHERE r.id = $1", reportID)
+	if err != nil {
+		log.Printf("Error getting chat report: %v", err)
+		return nil, fmt.Errorf("error getting chat report: %w", err)
+	}
+	return &report, nil
+}
+
+// GetReports gets chat reports for the moderation queue, oldest first; an empty status returns all reports
+func (r *Repository) GetReports(status string, page, limit int) (*model.ReportResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*) FROM chat_reports WHERE ($1 = '' OR status = $1)", status)
+	if err != nil {
+		log.Printf("Error getting chat report count: %v", err)
+		return nil, fmt.Errorf("error getting chat report count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	// Get reports
+	reports := []model.ChatReport{}
+	err = r.db.Select(&reports, reportSelect+`
+		WHERE ($1 = '' OR r.status = $1)
+		ORDER BY r.created_at ASC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		log.Printf("Error getting chat reports: %v", err)
+		return nil, fmt.Errorf("error getting chat reports: %w", err)
+	}
+
+	return &model.ReportResponse{
+		Reports:     reports,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// ResolveReport closes an open chat report; it returns false if the report is not open
+func (r *Repository) ResolveReport(reportID, moderatorID int, status, resolution string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE chat_reports SET status = $1, resolution = $2, resolved_by = $3, resolved_at = $4
+		WHERE id = $5 AND status = $6
+	`, status, resolution, moderatorID, time.Now(), reportID, model.ReportStatusOpen)
+	if err != nil {
+		log.Printf("Error resolving chat report: %v", err)
+		return false, fmt.Errorf("error resolving chat report: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CountRecentMessages counts the messages a user sent across all chats since the given time
+func (r *Repository) CountRecentMessages(senderID int, since time.Time) (int, error) {
+	var count int
+	err := r.db.Get(&count, `
+		SELECT COUNT(*) FROM messages
+		WHERE user_id = $1 AND created_at >= $2 AND kind = 'text'
+	`, senderID, since)
+	if err != nil {
+		log.Printf("Error counting recent messages: %v", err)
+		return 0, fmt.Errorf("error counting recent messages: %w", err)
+	}
+
+	return count, nil
+}
+
+// LogScreeningEvents adds triggered screening rules to the audit log
+func (r *Repository) LogScreeningEvents(events []model.ScreeningEvent) error {
+	for _, event := range events {
+		_, err := r.db.Exec(`
+			INSERT INTO message_screening_events (chat_id, sender_id, message_id, rule, action, detail, content, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, event.ChatID, event.SenderID, event.MessageID, event.Rule, event.Action, event.Detail, event.Content, time.Now())
+		if err != nil {
+			log.Printf("Error logging screening event: %v", err)
+			return fmt.Errorf("error logging screening event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetScreeningEvents gets the screening audit log, newest first, optionally filtered by rule and action
+func (r *Repository) GetScreeningEvents(rule, action string, page, limit int) (*model.ScreeningEventResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	const condition = `
+		FROM message_screening_events e
+		JOIN users u ON e.sender_id = u.id
+		WHERE ($1 = '' OR e.rule = $1) AND ($2 = '' OR e.action = $2)`
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*)"+condition, rule, action)
+	if err != nil {
+		log.Printf("Error getting screening event count: %v", err)
+		return nil, fmt.Errorf("error getting screening event count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	events := []model.ScreeningEvent{}
+	err = r.db.Select(&events, `
+		SELECT e.id, e.chat_id, e.sender_id, e.message_id, e.rule, e.action, e.detail, e.content, e.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as sender_name
+		`+condition+`
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $3 OFFSET $4
+	`, rule, action, limit, offset)
+	if err != nil {
+		log.Printf("Error getting screening events: %v", err)
+		return nil, fmt.Errorf("error getting screening events: %w", err)
+	}
+
+	return &model.ScreeningEventResponse{
+		Events:      events,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// GetHeldMessages gets the messages held for review, oldest first
+func (r *Repository) GetHeldMessages(page, limit int) (*model.MessageResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*) FROM messages WHERE held AND deleted_at IS NULL")
+	if err != nil {
+		log.Printf("Error getting held message count: %v", err)
+		return nil, fmt.Errorf("error getting held message count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	messages := []model.Message{}
+	err = r.db.Select(&messages, messageSelect+`
+		WHERE m.held AND m.deleted_at IS NULL
+		ORDER BY m.created_at ASC
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		log.Printf("Error getting held messages: %v", err)
+		return nil, fmt.Errorf("error getting held messages: %w", err)
+	}
+
+	if err := r.loadMessageDetails(messages); err != nil {
+		return nil, err
+	}
+
+	return &model.MessageResponse{
+		Messages:    messages,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// ReleaseMessage delivers a held message and makes it the chat's last message if it is the newest one.
+// It returns false if the message is not held.
+func (r *Repository) ReleaseMessage(messageID int) (bool, error) {
+	var released int
+	err := r.db.Get(&released, `
+		WITH released AS (
+			UPDATE messages SET held = FALSE
+			WHERE id = $1 AND held AND deleted_at IS NULL
+			RETURNING id, chat_id, created_at
+		), last_message AS (
+			UPDATE chats c SET last_message_id = m.id, last_message_at = m.created_at
+			FROM released m
+			WHERE c.id = m.chat_id AND (c.last_message_id IS NULL OR c.last_message_id < m.id)
+		)
+		SELECT COUNT(*) FROM released
+	`, messageID)
+	if err != nil {
+		log.Printf("Error releasing message: %v", err)
+		return false, fmt.Errorf("error releasing message: %w", err)
+	}
+
+	return released > 0, nil
+}
+
+// SessionActive checks if a login session of a user wasn't revoked and hasn't expired
+func (r *Repository) SessionActive(userID, sessionID int) (bool, error) {
+	active, err := middleware.SessionActive(r.db, userID, sessionID)
+	if err != nil {
+		log.Printf("Error checking session: %v", err)
+		return false, fmt.Errorf("error checking session: %w", err)
+	}
+	return active, nil
+}
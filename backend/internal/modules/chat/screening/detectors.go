@@ -0,0 +1,148 @@
+package screening
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// Links, messenger addresses and @usernames used to move the conversation elsewhere
+	linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+` +
+		`|\b(?:t\.me|telegram\.me|wa\.me|vk\.com|vk\.me|ok\.ru|instagram\.com|viber\.click)(?:/\S*)?` +
+		`|\b[a-z0-9][a-z0-9-]*\.(?:ru|su|com|net|org|info|me|io|cc)\b` +
+		`|(?:^|\s)@[a-z][a-z0-9_]{4,}`)
+	// Digit sequences with the separators used in phone numbers
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s\-().]{8,18}\d`)
+	// Digit sequences with the separators used in card numbers
+	cardPattern = regexp.MustCompile(`\d(?:[ -]?\d){12,18}`)
+)
+
+// LinkDetector finds links and messenger contacts
+type LinkDetector struct{}
+
+// Detect implements Detector
+func (LinkDetector) Detect(msg Message) (string, error) {
+	if linkPattern.MatchString(msg.Content) {
+		return "link or messenger contact", nil
+	}
+	return "", nil
+}
+
+// PhoneDetector finds phone numbers: Russian numbers in any common notation and international numbers starting with +
+type PhoneDetector struct{}
+
+// Detect implements Detector
+func (PhoneDetector) Detect(msg Message) (string, error) {
+	for _, candidate := range phonePattern.FindAllString(msg.Content, -1) {
+		digits := onlyDigits(candidate)
+		switch {
+		case strings.HasPrefix(candidate, "+") && len(digits) >= 10 && len(digits) <= 13:
+			return "phone number", nil
+		case len(digits) == 11 && (digits[0] == '7' || digits[0] == '8'):
+			return "phone number", nil
+		case len(digits) == 10 && digits[0] == '9':
+			return "phone number", nil
+		}
+	}
+	return "", nil
+}
+
+// CardDetector finds payment card numbers
+type CardDetector struct{}
+
+// Detect implements Detector
+func (CardDetector) Detect(msg Message) (string, error) {
+	for _, candidate := range cardPattern.FindAllString(msg.Content, -1) {
+		if luhnValid(onlyDigits(candidate)) {
+			return "card number", nil
+		}
+	}
+	return "", nil
+}
+
+// PhraseDetector finds banned phrases, ignoring case and the difference between ё and е
+type PhraseDetector struct {
+	Phrases []string
+}
+
+// Detect implements Detector
+func (d PhraseDetector) Detect(msg Message) (string, error) {
+	content := normalizeText(msg.Content)
+	for _, phrase := range d.Phrases {
+		if p := normalizeText(phrase); p != "" && strings.Contains(content, p) {
+			return fmt.Sprintf("banned phrase %q", phrase), nil
+		}
+	}
+	return "", nil
+}
+
+// MessageCounter counts the messages a user sent recently
+type MessageCounter interface {
+	CountRecentMessages(senderID int, since time.Time) (int, error)
+}
+
+// BurstDetector finds users sending more than Limit messages within Window across all chats.
+// Edits aren't new messages, so they are never counted as a burst.
+type BurstDetector struct {
+	Counter MessageCounter
+	Limit   int
+	Window  time.Duration
+}
+
+// Detect implements Detector
+func (d BurstDetector) Detect(msg Message) (string, error) {
+	if msg.Edit {
+		return "", nil
+	}
+
+	count, err := d.Counter.CountRecentMessages(msg.SenderID, msg.SentAt.Add(-d.Window))
+	if err != nil {
+		return "", err
+	}
+	// The message being screened is not counted yet
+	if count >= d.Limit {
+		return fmt.Sprintf("%d messages within %s", count+1, d.Window), nil
+	}
+	return "", nil
+}
+
+// onlyDigits removes everything but digits from text
+func onlyDigits(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhnValid checks a card number with the Luhn algorithm
+func luhnValid(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// normalizeText lowercases text, replaces ё with е and collapses whitespace
+func normalizeText(text string) string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,184 @@
+package screening
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// detectorCase is a message text and whether a detector should flag it
+type detectorCase struct {
+	content string
+	flagged bool
+}
+
+func runDetectorCases(t *testing.T, detector Detector, cases []detectorCase) {
+	t.Helper()
+	for _, tc := range cases {
+		detail, err := detector.Detect(Message{Content: tc.content})
+		if err != nil {
+			t.Fatalf("Detect(%q): %v", tc.content, err)
+		}
+		if got := detail != ""; got != tc.flagged {
+			t.Errorf("Detect(%q) = %q, flagged %v, want %v", tc.content, detail, got, tc.flagged)
+		}
+	}
+}
+
+func TestLinkDetector(t *testing.T) {
+	runDetectorCases(t, LinkDetector{}, []detectorCase{
+		{"see https://example.com/sofa", true},
+		{"www.example.org", true},
+		{"write me at t.me/seller", true},
+		{"wa.me/79123456789", true},
+		{"my shop is furniture-shop.ru", true},
+		{"mail me at seller@mail.ru", true},
+		{"ask @sofa_seller", true},
+		{"@sofa_seller at the start", true},
+		{"The sofa is in good condition", false},
+		{"Шкаф в хорошем состоянии, самовывоз", false},
+		{"width 2.5 m, depth 0.8 m", false},
+		{"ask @bob", false},
+		{"", false},
+	})
+}
+
+func TestPhoneDetector(t *testing.T) {
+	runDetectorCases(t, PhoneDetector{}, []detectorCase{
+		{"call +7 (912) 345-67-89", true},
+		{"89123456789", true},
+		{"8 912 345 67 89", true},
+		{"9123456789", true},
+		{"+44 20 7946 0958", true},
+		{"+7-912-345-67-89 after six", true},
+		{"price 15000 rub", false},
+		{"2 chairs for 3500 each", false},
+		{"order 12345678901", false},
+		{"1234567890", false},
+		{"", false},
+	})
+}
+
+func TestCardDetector(t *testing.T) {
+	runDetectorCases(t, CardDetector{}, []detectorCase{
+		{"pay to 4111 1111 1111 1111", true},
+		{"5555-5555-5555-4444", true},
+		{"card 5555555555554444 please", true},
+		{"amex 378282246310005", true},
+		{"4111 1111 1111 1112", false},
+		{"order 1234567890123", false},
+		{"call 89123456789", false},
+		{"", false},
+	})
+}
+
+func TestPhraseDetector(t *testing.T) {
+	detector := PhraseDetector{Phrases: []string{"предоплата", "переведите на карту", "всё включено", "  "}}
+	runDetectorCases(t, detector, []detectorCase{
+		{"Нужна ПРЕДОПЛАТА 50%", true},
+		{"Переведите   на\nкарту сегодня", true},
+		{"Доставка и сборка - все включено", true},
+		{"Оплата при получении", false},
+		{"переведите деньги при встрече", false},
+		{"", false},
+	})
+
+	if detail, _ := (PhraseDetector{}).Detect(Message{Content: "предоплата"}); detail != "" {
+		t.Errorf("detector without phrases flagged %q", detail)
+	}
+}
+
+// fakeCounter counts messages for BurstDetector
+type fakeCounter struct {
+	count int
+	err   error
+	since time.Time
+	calls int
+}
+
+func (c *fakeCounter) CountRecentMessages(senderID int, since time.Time) (int, error) {
+	c.calls++
+	c.since = since
+	return c.count, c.err
+}
+
+func TestBurstDetector(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		count   int
+		edit    bool
+		flagged bool
+	}{
+		{"below the limit", 3, false, false},
+		{"one below the limit", 4, false, false},
+		{"at the limit", 5, false, true},
+		{"over the limit", 20, false, true},
+		{"edit over the limit", 20, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := &fakeCounter{count: tt.count}
+			detector := BurstDetector{Counter: counter, Limit: 5, Window: time.Minute}
+
+			detail, err := detector.Detect(Message{SenderID: 1, SentAt: now, Edit: tt.edit})
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if got := detail != ""; got != tt.flagged {
+				t.Errorf("Detect = %q, flagged %v, want %v", detail, got, tt.flagged)
+			}
+			if tt.edit {
+				if counter.calls != 0 {
+					t.Errorf("edit was counted")
+				}
+				return
+			}
+			if !counter.since.Equal(now.Add(-time.Minute)) {
+				t.Errorf("counted since %v, want %v", counter.since, now.Add(-time.Minute))
+			}
+		})
+	}
+
+	counter := &fakeCounter{err: errors.New("database is down")}
+	if _, err := (BurstDetector{Counter: counter, Limit: 5, Window: time.Minute}).Detect(Message{SentAt: now}); err == nil {
+		t.Errorf("Detect with a failing counter: want error")
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		number string
+		valid  bool
+	}{
+		{"4111111111111111", true},
+		{"5555555555554444", true},
+		{"378282246310005", true},
+		{"4012888888881881", true},
+		{"6011000990139424", true},
+		{"4111111111111112", false},
+		{"1234567812345678", false},
+		// Valid checksum, but too short or too long for a card
+		{"79927398713", false},
+		{"00000000000000000000", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := luhnValid(tt.number); got != tt.valid {
+			t.Errorf("luhnValid(%q) = %v, want %v", tt.number, got, tt.valid)
+		}
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := map[string]string{
+		"  Ёлка   Зелёная\n": "елка зеленая",
+		"ONE\ttwo":           "one two",
+		"":                   "",
+	}
+	for text, want := range tests {
+		if got := normalizeText(text); got != want {
+			t.Errorf("normalizeText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
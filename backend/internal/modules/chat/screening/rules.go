@@ -0,0 +1,71 @@
+package screening
+
+import (
+	"FurniSwap/pkg/config"
+	"log"
+)
+
+// Rule names
+const (
+	RuleLinks   = "links"
+	RulePhones  = "phones"
+	RuleCards   = "cards"
+	RulePhrases = "phrases"
+	RuleBurst   = "burst"
+)
+
+// DefaultBannedPhrases are typical phrases of advance-payment scams
+var DefaultBannedPhrases = []string{
+	"предоплата",
+	"предоплату",
+	"переведите на карту",
+	"перевод на карту",
+	"оплата на карту",
+	"номер карты",
+	"безопасная сделка по ссылке",
+	"пишите в телеграм",
+	"пишите в ватсап",
+	"напишите в whatsapp",
+}
+
+// defaultActions are the rule actions used unless configured otherwise
+var defaultActions = map[string]string{
+	RuleLinks:   ActionWarn,
+	RulePhones:  ActionWarn,
+	RuleCards:   ActionHold,
+	RulePhrases: ActionHold,
+	RuleBurst:   ActionReject,
+}
+
+// DefaultRules builds the standard rules with the actions, phrases and rate limit from the configuration
+func DefaultRules(counter MessageCounter) []Rule {
+	phrases := config.Config.ChatBannedPhrases
+	if len(phrases) == 0 {
+		phrases = DefaultBannedPhrases
+	}
+
+	rules := []Rule{
+		{Name: RuleLinks, Detector: LinkDetector{}},
+		{Name: RulePhones, Detector: PhoneDetector{}},
+		{Name: RuleCards, Detector: CardDetector{}},
+		{Name: RulePhrases, Detector: PhraseDetector{Phrases: phrases}},
+		{Name: RuleBurst, Detector: BurstDetector{
+			Counter: counter,
+			Limit:   config.Config.ChatBurstLimit,
+			Window:  config.Config.ChatBurstWindow,
+		}},
+	}
+
+	for i := range rules {
+		rules[i].Action = defaultActions[rules[i].Name]
+		if action, ok := config.Config.ChatScreeningActions[rules[i].Name]; ok {
+			if ValidAction(action) {
+				rules[i].Action = action
+			} else {
+				log.Printf("WARNING: invalid action %q for chat screening rule %s, using %s", action, rules[i].Name, rules[i].Action)
+			}
+		}
+	}
+
+	return rules
+}
@@ -0,0 +1,98 @@
+package screening
+
+import (
+	"FurniSwap/pkg/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// withConfig sets the chat screening configuration for the duration of a test
+func withConfig(t *testing.T, actions map[string]string, phrases []string) {
+	t.Helper()
+	saved := config.Config
+	t.Cleanup(func() { config.Config = saved })
+
+	config.Config.ChatScreeningActions = actions
+	config.Config.ChatBannedPhrases = phrases
+	config.Config.ChatBurstLimit = 15
+	config.Config.ChatBurstWindow = time.Minute
+}
+
+// ruleActions maps the rule names to their actions
+func ruleActions(rules []Rule) map[string]string {
+	actions := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		actions[rule.Name] = rule.Action
+	}
+	return actions
+}
+
+func TestDefaultRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions map[string]string
+		want    map[string]string
+	}{
+		{
+			name:    "defaults",
+			actions: map[string]string{},
+			want:    defaultActions,
+		},
+		{
+			name:    "configured actions",
+			actions: map[string]string{RuleLinks: ActionHold, RuleBurst: ActionOff, RulePhones: ActionAllow},
+			want: map[string]string{
+				RuleLinks: ActionHold, RulePhones: ActionAllow, RuleCards: ActionHold,
+				RulePhrases: ActionHold, RuleBurst: ActionOff,
+			},
+		},
+		{
+			name:    "invalid action keeps the default",
+			actions: map[string]string{RuleCards: "ban", RulePhrases: ActionReject},
+			want: map[string]string{
+				RuleLinks: ActionWarn, RulePhones: ActionWarn, RuleCards: ActionHold,
+				RulePhrases: ActionReject, RuleBurst: ActionReject,
+			},
+		},
+		{
+			name:    "unknown rule is ignored",
+			actions: map[string]string{"emails": ActionReject},
+			want:    defaultActions,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.actions, nil)
+			if got := ruleActions(DefaultRules(&fakeCounter{})); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rule actions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRulesPhrasesAndBurst(t *testing.T) {
+	withConfig(t, nil, []string{"custom phrase"})
+	config.Config.ChatBurstLimit = 3
+	config.Config.ChatBurstWindow = 30 * time.Second
+
+	for _, rule := range DefaultRules(&fakeCounter{}) {
+		switch detector := rule.Detector.(type) {
+		case PhraseDetector:
+			if !reflect.DeepEqual(detector.Phrases, []string{"custom phrase"}) {
+				t.Errorf("phrases = %v, want the configured ones", detector.Phrases)
+			}
+		case BurstDetector:
+			if detector.Limit != 3 || detector.Window != 30*time.Second {
+				t.Errorf("burst limit = %d per %s, want 3 per 30s", detector.Limit, detector.Window)
+			}
+		}
+	}
+
+	withConfig(t, nil, nil)
+	for _, rule := range DefaultRules(&fakeCounter{}) {
+		if detector, ok := rule.Detector.(PhraseDetector); ok && !reflect.DeepEqual(detector.Phrases, DefaultBannedPhrases) {
+			t.Errorf("phrases = %v, want the default ones", detector.Phrases)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package screening
+
+import (
+	"log"
+	"time"
+)
+
+// Actions taken on a message, from the mildest to the strictest
+const (
+	// ActionAllow delivers the message as is
+	ActionAllow = "allow"
+	// ActionWarn delivers the message with a warning for the recipient
+	ActionWarn = "warn"
+	// ActionHold keeps the message from the recipient until a moderator approves it
+	ActionHold = "hold"
+	// ActionReject refuses to send the message
+	ActionReject = "reject"
+	// ActionOff disables a rule in the configuration
+	ActionOff = "off"
+)
+
+// actionSeverity orders the actions so that the strictest triggered one wins
+var actionSeverity = map[string]int{
+	ActionAllow:  0,
+	ActionWarn:   1,
+	ActionHold:   2,
+	ActionReject: 3,
+}
+
+// ValidAction reports whether an action can be assigned to a rule
+func ValidAction(action string) bool {
+	_, ok := actionSeverity[action]
+	return ok || action == ActionOff
+}
+
+// Stricter reports whether action a is stricter than action b
+func Stricter(a, b string) bool {
+	return actionSeverity[a] > actionSeverity[b]
+}
+
+// Message is a chat message to screen
+type Message struct {
+	ChatID   int
+	SenderID int
+	Content  string
+	SentAt   time.Time
+	// Edit is set when an already sent message is edited
+	Edit bool
+}
+
+// Detector finds suspicious content in a message.
+// It returns a short description of what it found, or an empty string if the message is clean.
+type Detector interface {
+	Detect(msg Message) (string, error)
+}
+
+// Rule applies an action to the messages matched by a detector
+type Rule struct {
+	Name     string
+	Detector Detector
+	Action   string
+}
+
+// Match is a rule triggered by a message
+type Match struct {
+	Rule   string
+	Action string
+	Detail string
+}
+
+// Result is the outcome of screening a message: the strictest action of the triggered rules
+type Result struct {
+	Action  string
+	Matches []Match
+}
+
+// Pipeline screens messages with a list of rules
+type Pipeline struct {
+	rules []Rule
+}
+
+// NewPipeline creates a new pipeline; rules with the "off" action are left out
+func NewPipeline(rules ...Rule) *Pipeline {
+	p := &Pipeline{}
+	for _, rule := range rules {
+		if rule.Action == ActionOff {
+			continue
+		}
+		p.rules = append(p.rules, rule)
+	}
+	return p
+}
+
+// Screen runs all rules on a message. A failing detector is skipped so that
+// an outage of e.g. the rate counter doesn't block messaging.
+func (p *Pipeline) Screen(msg Message) Result {
+	result := Result{Action: ActionAllow}
+	if msg.SentAt.IsZero() {
+		msg.SentAt = time.Now()
+	}
+
+	for _, rule := range p.rules {
+		detail, err := rule.Detector.Detect(msg)
+		if err != nil {
+			log.Printf("Error running screening rule %s: %v", rule.Name, err)
+			continue
+		}
+		if detail == "" {
+			continue
+		}
+
+		result.Matches = append(result.Matches, Match{
+			Rule:   rule.Name,
+			Action: rule.Action,
+			Detail: detail,
+		})
+		if Stricter(rule.Action, result.Action) {
+			result.Action = rule.Action
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,175 @@
+package screening
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubDetector returns a fixed result
+type stubDetector struct {
+	detail string
+	err    error
+}
+
+func (d stubDetector) Detect(msg Message) (string, error) {
+	return d.detail, d.err
+}
+
+// timeDetector records the time of the message it screened
+type timeDetector struct {
+	sentAt *time.Time
+}
+
+func (d timeDetector) Detect(msg Message) (string, error) {
+	*d.sentAt = msg.SentAt
+	return "", nil
+}
+
+func TestPipelineScreen(t *testing.T) {
+	clean := stubDetector{}
+	found := stubDetector{detail: "found"}
+	failing := stubDetector{err: errors.New("database is down")}
+
+	tests := []struct {
+		name        string
+		rules       []Rule
+		wantAction  string
+		wantMatches []string
+	}{
+		{
+			name:       "no rules",
+			wantAction: ActionAllow,
+		},
+		{
+			name: "clean message",
+			rules: []Rule{
+				{Name: "a", Detector: clean, Action: ActionReject},
+				{Name: "b", Detector: clean, Action: ActionHold},
+			},
+			wantAction: ActionAllow,
+		},
+		{
+			name: "flagged message",
+			rules: []Rule{
+				{Name: "a", Detector: found, Action: ActionWarn},
+				{Name: "b", Detector: clean, Action: ActionReject},
+			},
+			wantAction:  ActionWarn,
+			wantMatches: []string{"a"},
+		},
+		{
+			name: "strictest action wins",
+			rules: []Rule{
+				{Name: "a", Detector: found, Action: ActionWarn},
+				{Name: "b", Detector: found, Action: ActionReject},
+				{Name: "c", Detector: found, Action: ActionHold},
+			},
+			wantAction:  ActionReject,
+			wantMatches: []string{"a", "b", "c"},
+		},
+		{
+			name: "rule turned off",
+			rules: []Rule{
+				{Name: "a", Detector: found, Action: ActionOff},
+				{Name: "b", Detector: found, Action: ActionHold},
+			},
+			wantAction:  ActionHold,
+			wantMatches: []string{"b"},
+		},
+		{
+			name: "allow rule matches without blocking",
+			rules: []Rule{
+				{Name: "a", Detector: found, Action: ActionAllow},
+			},
+			wantAction:  ActionAllow,
+			wantMatches: []string{"a"},
+		},
+		{
+			name: "failing detector is skipped",
+			rules: []Rule{
+				{Name: "a", Detector: failing, Action: ActionReject},
+				{Name: "b", Detector: found, Action: ActionWarn},
+			},
+			wantAction:  ActionWarn,
+			wantMatches: []string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewPipeline(tt.rules...).Screen(Message{Content: "hello"})
+			if result.Action != tt.wantAction {
+				t.Errorf("action = %s, want %s", result.Action, tt.wantAction)
+			}
+			var matches []string
+			for _, match := range result.Matches {
+				matches = append(matches, match.Rule)
+				if match.Detail != "found" {
+					t.Errorf("match %s has detail %q", match.Rule, match.Detail)
+				}
+			}
+			if !reflect.DeepEqual(matches, tt.wantMatches) {
+				t.Errorf("matches = %v, want %v", matches, tt.wantMatches)
+			}
+		})
+	}
+}
+
+func TestPipelineScreenSetsTime(t *testing.T) {
+	var sentAt time.Time
+	pipeline := NewPipeline(Rule{Name: "time", Detector: timeDetector{sentAt: &sentAt}, Action: ActionWarn})
+
+	pipeline.Screen(Message{})
+	if sentAt.IsZero() || time.Since(sentAt) > time.Minute {
+		t.Errorf("message without a time was screened at %v, want now", sentAt)
+	}
+
+	fixed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pipeline.Screen(Message{SentAt: fixed})
+	if !sentAt.Equal(fixed) {
+		t.Errorf("message was screened at %v, want %v", sentAt, fixed)
+	}
+}
+
+func TestPipelineScreenEdit(t *testing.T) {
+	counter := &fakeCounter{count: 100}
+	pipeline := NewPipeline(
+		Rule{Name: RuleLinks, Detector: LinkDetector{}, Action: ActionWarn},
+		Rule{Name: RuleBurst, Detector: BurstDetector{Counter: counter, Limit: 5, Window: time.Minute}, Action: ActionReject},
+	)
+
+	if result := pipeline.Screen(Message{Content: "hello"}); result.Action != ActionReject {
+		t.Fatalf("new message over the rate limit: action %s, want %s", result.Action, ActionReject)
+	}
+
+	// An edit is still screened for contacts, but not rate limited
+	result := pipeline.Screen(Message{Content: "see t.me/seller", Edit: true})
+	if result.Action != ActionWarn || len(result.Matches) != 1 || result.Matches[0].Rule != RuleLinks {
+		t.Errorf("edit: got %+v, want only the links rule", result)
+	}
+}
+
+func TestValidAction(t *testing.T) {
+	for _, action := range []string{ActionAllow, ActionWarn, ActionHold, ActionReject, ActionOff} {
+		if !ValidAction(action) {
+			t.Errorf("ValidAction(%q) = false", action)
+		}
+	}
+	for _, action := range []string{"", "ban", "WARN"} {
+		if ValidAction(action) {
+			t.Errorf("ValidAction(%q) = true", action)
+		}
+	}
+}
+
+func TestStricter(t *testing.T) {
+	order := []string{ActionAllow, ActionWarn, ActionHold, ActionReject}
+	for i, a := range order {
+		for j, b := range order {
+			if got := Stricter(a, b); got != (i > j) {
+				t.Errorf("Stricter(%s, %s) = %v, want %v", a, b, got, i > j)
+			}
+		}
+	}
+}
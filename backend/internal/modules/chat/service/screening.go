@@ -0,0 +1,150 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"FurniSwap/internal/modules/chat/screening"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// screenMessage runs the screening pipeline on a new message. Rejected messages are logged and
+// returned as an error; otherwise the result is returned with how to deliver the message.
+func (s *Service) screenMessage(chatID, userID int, content string) (screening.Result, model.MessageScreening, error) {
+	result := s.screener.Screen(screening.Message{ChatID: chatID, SenderID: userID, Content: content})
+
+	if result.Action == screening.ActionReject {
+		s.logScreening(chatID, userID, nil, content, result)
+		return result, model.MessageScreening{}, errors.New("message rejected by screening")
+	}
+
+	return result, model.MessageScreening{
+		Held:    result.Action == screening.ActionHold,
+		Warning: screeningWarning(result),
+	}, nil
+}
+
+// logScreening adds the rules triggered by a message to the audit log
+func (s *Service) logScreening(chatID, userID int, messageID *int, content string, result screening.Result) {
+	if len(result.Matches) == 0 {
+		return
+	}
+
+	// A rejected first message has no chat, it is never created
+	var chat *int
+	if chatID != 0 {
+		chat = &chatID
+	}
+
+	events := make([]model.ScreeningEvent, 0, len(result.Matches))
+	for _, match := range result.Matches {
+		events = append(events, model.ScreeningEvent{
+			ChatID:    chat,
+			SenderID:  userID,
+			MessageID: messageID,
+			Rule:      match.Rule,
+			Action:    match.Action,
+			Detail:    match.Detail,
+			Content:   content,
+		})
+	}
+
+	if err := s.repo.LogScreeningEvents(events); err != nil {
+		log.Printf("Error logging screening of message from user %d in chat %d: %v", userID, chatID, err)
+	}
+}
+
+// screeningWarning builds the warning shown to the recipient from the rules with the warn action
+func screeningWarning(result screening.Result) string {
+	var details []string
+	for _, match := range result.Matches {
+		if match.Action == screening.ActionWarn {
+			details = append(details, match.Detail)
+		}
+	}
+	if len(details) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Be careful: this message contains %s. Never pay in advance and keep the conversation on FurniSwap.",
+		strings.Join(details, ", "))
+}
+
+// GetScreeningEvents gets the screening audit log for moderators
+func (s *Service) GetScreeningEvents(rule, action string, page, limit int) (*model.ScreeningEventResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return s.repo.GetScreeningEvents(rule, action, page, limit)
+}
+
+// GetHeldMessages gets the messages held for review
+func (s *Service) GetHeldMessages(page, limit int) (*model.MessageResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return s.repo.GetHeldMessages(page, limit)
+}
+
+// ApproveHeldMessage delivers a held message to the recipient
+func (s *Service) ApproveHeldMessage(messageID int) error {
+	message, err := s.getHeldMessage(messageID)
+	if err != nil {
+		return err
+	}
+
+	released, err := s.repo.ReleaseMessage(messageID)
+	if err != nil {
+		return fmt.Errorf("error releasing message: %w", err)
+	}
+	if !released {
+		return errors.New("message is not held")
+	}
+
+	s.deliverMessage(message.ChatID, messageID)
+	return nil
+}
+
+// RejectHeldMessage deletes a held message; the recipient never sees it
+func (s *Service) RejectHeldMessage(messageID int) error {
+	message, err := s.getHeldMessage(messageID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteMessage(messageID)
+	if err != nil {
+		return fmt.Errorf("error deleting message: %w", err)
+	}
+	if !deleted {
+		return errors.New("message is not held")
+	}
+
+	s.publishMessage(model.EventMessageDeleted, message.ChatID, messageID)
+	return nil
+}
+
+// getHeldMessage gets a message awaiting review
+func (s *Service) getHeldMessage(messageID int) (*model.Message, error) {
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("message not found")
+		}
+		return nil, err
+	}
+
+	if !message.IsHeld || message.IsDeleted {
+		return nil, errors.New("message is not held")
+	}
+
+	return message, nil
+}
@@ -1,21 +1,37 @@
 package service
 
 import (
+	blockRepo "FurniSwap/internal/modules/block/repository"
+	"FurniSwap/internal/modules/chat/hub"
 	"FurniSwap/internal/modules/chat/model"
 	"FurniSwap/internal/modules/chat/repository"
+	"FurniSwap/internal/modules/chat/screening"
+	"FurniSwap/pkg/utils"
+	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Service provides chat operations
 type Service struct {
-	repo *repository.Repository
+	repo      *repository.Repository
+	hub       *hub.Hub
+	blockRepo *blockRepo.Repository
+	screener  *screening.Pipeline
 }
 
-// NewService creates a new chat service
-func NewService(repo *repository.Repository) *Service {
+// NewService creates a new chat service. New and edited messages are checked by the screener.
+func NewService(repo *repository.Repository, hub *hub.Hub, blockRepo *blockRepo.Repository, screener *screening.Pipeline) *Service {
 	return &Service{
-		repo: repo,
+		repo:      repo,
+		hub:       hub,
+		blockRepo: blockRepo,
+		screener:  screener,
 	}
 }
 
@@ -26,8 +42,18 @@ func (s *Service) InitiateChat(userID int, req model.InitiateChatRequest) (int,
 		return 0, errors.New("cannot initiate chat with yourself")
 	}
 
-	// Validate recipient user exists (to be implemented)
-	// This would check for the existence of the recipient in the user database
+	// Validate recipient user exists
+	exists, err := s.repo.UserExists(req.RecipientID)
+	if err != nil {
+		return 0, fmt.Errorf("error checking recipient: %w", err)
+	}
+	if !exists {
+		return 0, errors.New("recipient not found")
+	}
+
+	if err := s.checkNotBlocked(userID, req.RecipientID); err != nil {
+		return 0, err
+	}
 
 	// Check if chat already exists
 	existingChat, err := s.repo.GetChatByUsers(userID, req.RecipientID, req.ListingID)
@@ -38,7 +64,15 @@ func (s *Service) InitiateChat(userID int, req model.InitiateChatRequest) (int,
 	var chatID int
 	if existingChat != nil {
 		chatID = existingChat.ID
-	} else {
+	}
+
+	// Screen the message before creating the chat so that rejected messages don't leave empty chats
+	result, delivery, err := s.screenMessage(chatID, userID, req.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	if chatID == 0 {
 		// Create new chat
 		chatID, err = s.repo.CreateChat(userID, req.RecipientID, req.ListingID)
 		if err != nil {
@@ -47,16 +81,24 @@ func (s *Service) InitiateChat(userID int, req model.InitiateChatRequest) (int,
 	}
 
 	// Add initial message
-	_, err = s.repo.AddMessage(chatID, userID, req.Message)
+	messageID, err := s.repo.AddMessage(chatID, userID, req.Message, nil, nil, delivery)
 	if err != nil {
 		return 0, fmt.Errorf("error adding message: %w", err)
 	}
 
+	s.logScreening(chatID, userID, &messageID, req.Message, result)
+	if delivery.Held {
+		s.publishMessage(model.EventMessage, chatID, messageID)
+	} else {
+		s.deliverMessage(chatID, messageID)
+	}
+
 	return chatID, nil
 }
 
-// SendMessage sends a message in a chat
-func (s *Service) SendMessage(chatID, userID int, req model.SendMessageRequest) (int, error) {
+// SendMessage sends a message in a chat. Files are uploaded as new attachments,
+// in addition to the attachments uploaded beforehand and referenced in the request.
+func (s *Service) SendMessage(chatID, userID int, req model.SendMessageRequest, files []*multipart.FileHeader) (int, error) {
 	// Check if user has access to the chat
 	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
 	if err != nil {
@@ -67,24 +109,76 @@ func (s *Service) SendMessage(chatID, userID int, req model.SendMessageRequest)
 		return 0, errors.New("you don't have access to this chat")
 	}
 
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return 0, fmt.Errorf("error getting chat: %w", err)
+	}
+	if err := s.checkNotBlocked(userID, otherParticipant(chat, userID)); err != nil {
+		return 0, err
+	}
+
+	attachmentCount := len(req.AttachmentIDs) + len(files)
+	if strings.TrimSpace(req.Content) == "" && attachmentCount == 0 {
+		return 0, errors.New("message is empty")
+	}
+	if attachmentCount > model.MaxAttachmentsPerMessage {
+		return 0, errors.New("too many attachments")
+	}
+
+	// The replied message must be in the same chat
+	if req.ReplyToID != nil {
+		replyTo, err := s.repo.GetMessageByID(*req.ReplyToID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("error getting replied message: %w", err)
+		}
+		if err != nil || replyTo.ChatID != chatID {
+			return 0, errors.New("replied message not found")
+		}
+	}
+
+	result, delivery, err := s.screenMessage(chatID, userID, req.Content)
+	if err != nil {
+		return 0, err
+	}
+
+	// Upload attached files
+	attachmentIDs := append([]int{}, req.AttachmentIDs...)
+	for _, file := range files {
+		attachmentID, err := s.saveAttachment(chatID, userID, file)
+		if err != nil {
+			return 0, err
+		}
+		attachmentIDs = append(attachmentIDs, attachmentID)
+	}
+
 	// Add message
-	messageID, err := s.repo.AddMessage(chatID, userID, req.Content)
+	messageID, err := s.repo.AddMessage(chatID, userID, req.Content, req.ReplyToID, attachmentIDs, delivery)
 	if err != nil {
+		if err.Error() == "attachment not found" {
+			return 0, err
+		}
 		return 0, fmt.Errorf("error adding message: %w", err)
 	}
 
+	s.logScreening(chatID, userID, &messageID, req.Content, result)
+	if delivery.Held {
+		s.publishMessage(model.EventMessage, chatID, messageID)
+	} else {
+		s.deliverMessage(chatID, messageID)
+	}
+
 	return messageID, nil
 }
 
-// GetUserChats gets all chats for a user
-func (s *Service) GetUserChats(userID, page, limit int) (*model.ChatResponse, error) {
+// GetUserChats gets the chats of a user; archived chats are listed separately
+func (s *Service) GetUserChats(userID int, archived bool, page, limit int) (*model.ChatResponse, error) {
 	if page < 1 {
 		page = 1
 	}
 	if limit < 1 || limit > 50 {
 		limit = 10
 	}
-	return s.repo.GetUserChats(userID, page, limit)
+	return s.repo.GetUserChats(userID, archived, page, limit)
 }
 
 // GetChatMessages gets all messages in a chat
@@ -105,7 +199,57 @@ func (s *Service) GetChatMessages(chatID, userID, page, limit int) (*model.Messa
 	if limit < 1 || limit > 50 {
 		limit = 50
 	}
-	return s.repo.GetChatMessages(chatID, page, limit)
+
+	// Messages from before the user deleted the chat stay hidden
+	hiddenUpToID, err := s.repo.GetHiddenUpToID(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetChatMessages(chatID, userID, hiddenUpToID, page, limit)
+}
+
+// GetChatMessagesWindow gets a window of chat messages around a cursor, newest first
+func (s *Service) GetChatMessagesWindow(chatID, userID int, cursor model.MessageCursor) (*model.MessageWindow, error) {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return nil, errors.New("you don't have access to this chat")
+	}
+
+	if cursor.Before > 0 && cursor.After > 0 {
+		return nil, errors.New("only one of before and after can be set")
+	}
+	if cursor.Limit < 1 || cursor.Limit > 100 {
+		cursor.Limit = 50
+	}
+
+	// Messages from before the user deleted the chat stay hidden
+	cursor.ViewerID = userID
+	cursor.HiddenUpToID, err = s.repo.GetHiddenUpToID(chatID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetChatMessagesWindow(chatID, cursor)
+}
+
+// SearchMessages searches the messages of all of the user's chats
+func (s *Service) SearchMessages(userID int, query string, page, limit int) (*model.MessageSearchResponse, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("search query is empty")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 20
+	}
+	return s.repo.SearchMessages(userID, query, page, limit)
 }
 
 // GetChatByID gets a chat by ID
@@ -122,3 +266,411 @@ func (s *Service) GetChatByID(chatID, userID int) (*model.Chat, error) {
 
 	return s.repo.GetChatByID(chatID)
 }
+
+// MarkRead marks the messages the user received in a chat as read up to a message
+// and notifies the sender
+func (s *Service) MarkRead(chatID, userID int, req model.MarkReadRequest) error {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return errors.New("you don't have access to this chat")
+	}
+
+	lastID, err := s.repo.MarkMessagesRead(chatID, userID, req.MessageID)
+	if err != nil {
+		return fmt.Errorf("error marking messages as read: %w", err)
+	}
+
+	// Nothing new was read
+	if lastID == 0 {
+		return nil
+	}
+
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		log.Printf("Error getting chat %d to publish read receipt: %v", chatID, err)
+		return nil
+	}
+
+	s.hub.Publish([]int{chat.User1ID, chat.User2ID}, model.Event{
+		Type:       model.EventRead,
+		ChatID:     chatID,
+		UserID:     userID,
+		ReadUpToID: lastID,
+	})
+	return nil
+}
+
+// GetUnreadCount gets the total number of unread messages of the user
+func (s *Service) GetUnreadCount(userID int) (int, error) {
+	return s.repo.GetUnreadCount(userID)
+}
+
+// EditMessage edits the content of the user's own message within MessageEditWindow
+func (s *Service) EditMessage(chatID, messageID, userID int, req model.EditMessageRequest) error {
+	message, err := s.getOwnMessage(chatID, messageID, userID)
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		return errors.New("message is empty")
+	}
+
+	if time.Since(message.CreatedAt) > model.MessageEditWindow {
+		return errors.New("edit window has expired")
+	}
+
+	// Edits are screened like new messages, except for the rate limit, but can't be held once
+	// the message is delivered
+	result := s.screener.Screen(screening.Message{ChatID: chatID, SenderID: userID, Content: req.Content, Edit: true})
+	s.logScreening(chatID, userID, &messageID, req.Content, result)
+	if result.Action == screening.ActionHold || result.Action == screening.ActionReject {
+		return errors.New("message rejected by screening")
+	}
+
+	updated, err := s.repo.UpdateMessageContent(messageID, req.Content, screeningWarning(result))
+	if err != nil {
+		return fmt.Errorf("error updating message: %w", err)
+	}
+	if !updated {
+		return errors.New("message not found")
+	}
+
+	s.publishMessage(model.EventMessageEdited, chatID, messageID)
+	return nil
+}
+
+// DeleteMessage soft-deletes the user's own message
+func (s *Service) DeleteMessage(chatID, messageID, userID int) error {
+	if _, err := s.getOwnMessage(chatID, messageID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteMessage(messageID)
+	if err != nil {
+		return fmt.Errorf("error deleting message: %w", err)
+	}
+	if !deleted {
+		return errors.New("message not found")
+	}
+
+	s.publishMessage(model.EventMessageDeleted, chatID, messageID)
+	return nil
+}
+
+// getOwnMessage gets a message of a chat, checking that it was sent by the user and is not deleted
+func (s *Service) getOwnMessage(chatID, messageID, userID int) (*model.Message, error) {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return nil, errors.New("you don't have access to this chat")
+	}
+
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("message not found")
+		}
+		return nil, err
+	}
+
+	if message.ChatID != chatID || message.IsDeleted {
+		return nil, errors.New("message not found")
+	}
+
+	if message.Kind != model.MessageKindText {
+		return nil, errors.New("system messages cannot be changed")
+	}
+
+	if message.SenderID != userID {
+		return nil, errors.New("you can only change your own messages")
+	}
+
+	return message, nil
+}
+
+// UploadAttachment uploads an attachment to be sent with a later message
+func (s *Service) UploadAttachment(chatID, userID int, file *multipart.FileHeader) (*model.Attachment, error) {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return nil, errors.New("you don't have access to this chat")
+	}
+
+	attachmentID, err := s.saveAttachment(chatID, userID, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetAttachment(attachmentID)
+}
+
+// GetAttachment gets an attachment of a chat the user participates in
+func (s *Service) GetAttachment(chatID, attachmentID, userID int) (*model.Attachment, error) {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return nil, errors.New("you don't have access to this chat")
+	}
+
+	attachment, err := s.repo.GetAttachment(attachmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("attachment not found")
+		}
+		return nil, err
+	}
+
+	// Pending attachments are visible only to the uploader
+	if attachment.ChatID != chatID || (attachment.MessageID == nil && attachment.UploaderID != userID) {
+		return nil, errors.New("attachment not found")
+	}
+
+	return attachment, nil
+}
+
+// saveAttachment validates and stores an uploaded file as a pending attachment
+func (s *Service) saveAttachment(chatID, userID int, file *multipart.FileHeader) (int, error) {
+	if file.Size > model.MaxAttachmentSize {
+		return 0, errors.New("attachment is too large")
+	}
+
+	// Detect the type from the file contents rather than trusting the client
+	contentType, err := utils.DetectContentType(file)
+	if err != nil {
+		return 0, err
+	}
+	if !model.AllowedAttachmentTypes[contentType] {
+		return 0, errors.New("unsupported attachment type")
+	}
+
+	// Upload the file
+	filePath, err := utils.UploadFile(file, fmt.Sprintf("chats/%d", chatID))
+	if err != nil {
+		return 0, fmt.Errorf("error uploading attachment: %w", err)
+	}
+
+	attachmentID, err := s.repo.CreateAttachment(chatID, userID, filePath, filepath.Base(file.Filename), contentType, file.Size)
+	if err != nil {
+		// If there's an error adding to the database, delete the uploaded file
+		_ = utils.DeleteFile(filePath)
+		return 0, fmt.Errorf("error adding attachment to database: %w", err)
+	}
+
+	return attachmentID, nil
+}
+
+// ReportChat reports abuse by the other participant of a chat to the moderators
+func (s *Service) ReportChat(chatID, userID int, req model.ReportRequest) (int, error) {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return 0, errors.New("you don't have access to this chat")
+	}
+
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return 0, fmt.Errorf("error getting chat: %w", err)
+	}
+	reportedUserID := otherParticipant(chat, userID)
+
+	// The reported message must be sent by the other participant in this chat
+	if req.MessageID != nil {
+		message, err := s.repo.GetMessageByID(*req.MessageID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("error getting reported message: %w", err)
+		}
+		if err != nil || message.ChatID != chatID || message.SenderID != reportedUserID {
+			return 0, errors.New("reported message not found")
+		}
+	}
+
+	return s.repo.CreateReport(chatID, userID, reportedUserID, req)
+}
+
+// GetReports gets chat reports for the moderation queue
+func (s *Service) GetReports(status string, page, limit int) (*model.ReportResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return s.repo.GetReports(status, page, limit)
+}
+
+// reportContextSize is how many messages of the chat a moderator sees with a report
+const reportContextSize = 100
+
+// GetReport gets a chat report for a moderator with messages of the chat, newest first:
+// the messages around the reported one, or the latest messages if no message was reported
+func (s *Service) GetReport(reportID int) (*model.ChatReport, error) {
+	report, err := s.repo.GetReportByID(reportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("report not found")
+		}
+		return nil, err
+	}
+
+	if report.MessageID == nil {
+		latest, err := s.repo.GetChatMessagesWindow(report.ChatID, model.MessageCursor{Limit: reportContextSize})
+		if err != nil {
+			return nil, err
+		}
+		report.Messages = latest.Messages
+		return report, nil
+	}
+
+	// The reported message and the older half, then the newer half on top
+	older, err := s.repo.GetChatMessagesWindow(report.ChatID, model.MessageCursor{
+		Before: *report.MessageID + 1,
+		Limit:  reportContextSize / 2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.repo.GetChatMessagesWindow(report.ChatID, model.MessageCursor{
+		After: *report.MessageID,
+		Limit: reportContextSize / 2,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Messages = append(newer.Messages, older.Messages...)
+
+	return report, nil
+}
+
+// ResolveReport closes a chat report as resolved or dismissed
+func (s *Service) ResolveReport(reportID, moderatorID int, req model.ResolveReportRequest) error {
+	if _, err := s.GetReport(reportID); err != nil {
+		return err
+	}
+
+	resolved, err := s.repo.ResolveReport(reportID, moderatorID, req.Status, req.Resolution)
+	if err != nil {
+		return err
+	}
+	if !resolved {
+		return errors.New("report is already closed")
+	}
+	return nil
+}
+
+// checkNotBlocked checks that neither of two users has blocked the other
+func (s *Service) checkNotBlocked(userID, otherID int) error {
+	blocked, err := s.blockRepo.IsBlocked(userID, otherID)
+	if err != nil {
+		return fmt.Errorf("error checking user block: %w", err)
+	}
+	if blocked {
+		return errors.New("messaging is blocked between these users")
+	}
+	return nil
+}
+
+// otherParticipant returns the participant of a chat who is not the given user
+func otherParticipant(chat *model.Chat, userID int) int {
+	if chat.User1ID == userID {
+		return chat.User2ID
+	}
+	return chat.User1ID
+}
+
+// Connect registers a WebSocket client of a user to receive chat events
+func (s *Service) Connect(userID, sessionID int) *hub.Client {
+	client := hub.NewClient(userID, sessionID)
+	s.hub.Register(client)
+	return client
+}
+
+// SessionActive checks if the session a WebSocket client was opened with is still active
+func (s *Service) SessionActive(client *hub.Client) (bool, error) {
+	return s.repo.SessionActive(client.UserID, client.SessionID)
+}
+
+// Disconnect unregisters a WebSocket client
+func (s *Service) Disconnect(client *hub.Client) {
+	s.hub.Unregister(client)
+}
+
+// SendTyping notifies the other participant of a chat that the user started or stopped typing
+func (s *Service) SendTyping(chatID, userID int, isTyping bool) error {
+	// Check if user has access to the chat
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return errors.New("you don't have access to this chat")
+	}
+
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		return fmt.Errorf("error getting chat: %w", err)
+	}
+
+	recipientID := otherParticipant(chat, userID)
+	if err := s.checkNotBlocked(userID, recipientID); err != nil {
+		return err
+	}
+
+	s.hub.Publish([]int{recipientID}, model.Event{
+		Type:     model.EventTyping,
+		ChatID:   chatID,
+		UserID:   userID,
+		IsTyping: isTyping,
+	})
+	return nil
+}
+
+// publishMessage delivers a new or changed message to both participants of the chat.
+// Errors are only logged: the message is already saved and clients can reload the chat.
+func (s *Service) publishMessage(eventType string, chatID, messageID int) {
+	chat, err := s.repo.GetChatByID(chatID)
+	if err != nil {
+		log.Printf("Error getting chat %d to publish message: %v", chatID, err)
+		return
+	}
+
+	message, err := s.repo.GetMessageByID(messageID)
+	if err != nil {
+		log.Printf("Error getting message %d to publish: %v", messageID, err)
+		return
+	}
+
+	// Held messages are visible only to the sender until a moderator approves them
+	recipients := []int{chat.User1ID, chat.User2ID}
+	if message.IsHeld {
+		recipients = []int{message.SenderID}
+	}
+
+	s.hub.Publish(recipients, model.Event{
+		Type:    eventType,
+		ChatID:  chatID,
+		Message: message,
+	})
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ArchiveChat moves a chat to or out of the user's archive
+func (s *Service) ArchiveChat(chatID, userID int, archived bool) error {
+	if err := s.checkAccess(chatID, userID); err != nil {
+		return err
+	}
+	return s.repo.SetChatArchived(chatID, userID, archived)
+}
+
+// PinChat pins a chat to the top of the user's chat list or unpins it
+func (s *Service) PinChat(chatID, userID int, pinned bool) error {
+	if err := s.checkAccess(chatID, userID); err != nil {
+		return err
+	}
+
+	if pinned {
+		count, err := s.repo.CountPinnedChats(userID, chatID)
+		if err != nil {
+			return err
+		}
+		if count >= model.MaxPinnedChats {
+			return errors.New("too many pinned chats")
+		}
+	}
+
+	return s.repo.SetChatPinned(chatID, userID, pinned)
+}
+
+// MuteChat mutes a chat for the user until the given time or indefinitely.
+// Muted chats don't count towards the total of unread messages and stay archived on new messages.
+func (s *Service) MuteChat(chatID, userID int, req model.MuteChatRequest) error {
+	if err := s.checkAccess(chatID, userID); err != nil {
+		return err
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return errors.New("mute end must be in the future")
+	}
+
+	return s.repo.SetChatMuted(chatID, userID, req.Until)
+}
+
+// UnmuteChat unmutes a chat for the user
+func (s *Service) UnmuteChat(chatID, userID int) error {
+	if err := s.checkAccess(chatID, userID); err != nil {
+		return err
+	}
+	return s.repo.UnmuteChat(chatID, userID)
+}
+
+// DeleteChat deletes a chat for the user only. The other participant keeps the conversation;
+// if they write again, the chat comes back with the new messages only.
+func (s *Service) DeleteChat(chatID, userID int) error {
+	if err := s.checkAccess(chatID, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteChatForUser(chatID, userID)
+}
+
+// deliverMessage brings a chat with a new message back from the participants' archives
+// and delivers the message in real time
+func (s *Service) deliverMessage(chatID, messageID int) {
+	if err := s.repo.UnarchiveChat(chatID); err != nil {
+		log.Printf("Error unarchiving chat %d after new message: %v", chatID, err)
+	}
+
+	s.publishMessage(model.EventMessage, chatID, messageID)
+}
+
+// checkAccess checks that the user is a participant of the chat
+func (s *Service) checkAccess(chatID, userID int) error {
+	hasAccess, err := s.repo.CheckChatAccess(chatID, userID)
+	if err != nil {
+		return fmt.Errorf("error checking chat access: %w", err)
+	}
+
+	if !hasAccess {
+		return errors.New("you don't have access to this chat")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/chat/model"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+// ListingPriceChanged posts a system message about a new price to all chats about a listing
+func (s *Service) ListingPriceChanged(listingID, sellerID int, title string, oldPrice, newPrice float64) error {
+	payload := model.ListingEventPayload{
+		ListingID:    listingID,
+		ListingTitle: title,
+		OldPrice:     &oldPrice,
+		NewPrice:     &newPrice,
+	}
+	content := fmt.Sprintf("The price has changed from %s to %s", formatPrice(oldPrice), formatPrice(newPrice))
+
+	return s.postListingEvent(listingID, sellerID, payload, func(chat model.Chat) (string, string) {
+		return model.MessageKindPriceChanged, content
+	})
+}
+
+// ListingReserved posts a system message about the listing being reserved to all chats about it
+func (s *Service) ListingReserved(listingID, sellerID int, title string) error {
+	payload := model.ListingEventPayload{
+		ListingID:    listingID,
+		ListingTitle: title,
+	}
+
+	return s.postListingEvent(listingID, sellerID, payload, func(chat model.Chat) (string, string) {
+		return model.MessageKindListingReserved, "The listing has been reserved"
+	})
+}
+
+// ListingSold posts system messages about a sale: the buyer's chat gets "sold to you",
+// the chats with other interested users get "sold to someone else"
+func (s *Service) ListingSold(listingID, sellerID int, title string, buyerID int) error {
+	payload := model.ListingEventPayload{
+		ListingID:    listingID,
+		ListingTitle: title,
+		BuyerID:      buyerID,
+	}
+
+	return s.postListingEvent(listingID, sellerID, payload, func(chat model.Chat) (string, string) {
+		if chat.User1ID == buyerID || chat.User2ID == buyerID {
+			return model.MessageKindSoldToYou, "The listing has been sold to you"
+		}
+		return model.MessageKindSoldToOther, "The listing has been sold to someone else"
+	})
+}
+
+// GetListingChats gets the chats about a listing, so they can be told about its removal
+// after the listing is gone
+func (s *Service) GetListingChats(listingID int) ([]model.Chat, error) {
+	return s.repo.GetListingChats(listingID)
+}
+
+// ListingRemoved posts a system message about the listing being removed to the chats that
+// were about it. The chats are got before the listing is deleted; they are kept without it.
+func (s *Service) ListingRemoved(chats []model.Chat, listingID, sellerID int, title string) error {
+	payload := model.ListingEventPayload{
+		ListingID:    listingID,
+		ListingTitle: title,
+	}
+
+	return s.postToChats(chats, sellerID, payload, func(chat model.Chat) (string, string) {
+		return model.MessageKindListingRemoved, "The listing has been removed by the seller"
+	})
+}
+
+// postListingEvent adds a system message to every chat about a listing and delivers it in real time.
+// The message kind and fallback text for clients that don't know the kind are chosen per chat.
+func (s *Service) postListingEvent(listingID, sellerID int, payload model.ListingEventPayload, message func(chat model.Chat) (kind, content string)) error {
+	chats, err := s.repo.GetListingChats(listingID)
+	if err != nil {
+		return err
+	}
+
+	return s.postToChats(chats, sellerID, payload, message)
+}
+
+// postToChats adds a system message about a listing to each of the chats and delivers it in real time
+func (s *Service) postToChats(chats []model.Chat, sellerID int, payload model.ListingEventPayload, message func(chat model.Chat) (kind, content string)) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding system message payload: %w", err)
+	}
+
+	for _, chat := range chats {
+		kind, content := message(chat)
+
+		// System messages are attributed to the seller, who owns the listing
+		messageID, err := s.repo.AddSystemMessage(chat.ID, sellerID, kind, content, data)
+		if err != nil {
+			log.Printf("Error posting %s message to chat %d: %v", kind, chat.ID, err)
+			continue
+		}
+
+		s.deliverMessage(chat.ID, messageID)
+	}
+
+	return nil
+}
+
+// formatPrice formats a price in rubles
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64) + " RUB"
+}
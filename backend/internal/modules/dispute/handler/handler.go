@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/dispute/model"
+	"FurniSwap/internal/modules/dispute/service"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler provides dispute handlers
+type Handler struct {
+	service *service.Service
+}
+
+// NewHandler creates a new dispute handler
+func NewHandler(service *service.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterRoutes registers dispute routes for purchase participants (auth required)
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/purchases/:id/dispute", h.OpenDispute)
+	router.GET("/disputes/:id", h.GetDispute)
+	router.POST("/disputes/:id/messages", h.AddMessage)
+	router.POST("/disputes/:id/evidence", h.AddEvidence)
+	router.GET("/disputes/:id/evidence/:evidenceId", h.GetEvidence)
+}
+
+// RegisterModeratorRoutes registers dispute moderation routes (disputes.moderate permission required)
+func (h *Handler) RegisterModeratorRoutes(router *gin.RouterGroup) {
+	router.GET("/disputes", h.GetDisputes)
+	router.GET("/disputes/:id", h.GetDisputeForModerator)
+	router.POST("/disputes/:id/messages", h.AddModeratorMessage)
+	router.POST("/disputes/:id/resolve", h.ResolveDispute)
+}
+
+// OpenDispute handles opening a dispute for a purchase
+func (h *Handler) OpenDispute(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.OpenDisputeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Open dispute
+	disputeID, err := h.service.OpenDispute(purchaseID, userID.(int), req)
+	if err != nil {
+		switch err.Error() {
+		case "purchase not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+		case "you are not a participant of this purchase":
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this purchase"})
+		case "purchase cannot be disputed":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase cannot be disputed"})
+		case "dispute window has expired":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dispute window has expired"})
+		case "purchase already has an open dispute":
+			c.JSON(http.StatusConflict, gin.H{"error": "Purchase already has an open dispute"})
+		default:
+			log.Printf("Error opening dispute: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error opening dispute"})
+		}
+		return
+	}
+
+	// Get the created dispute
+	dispute, err := h.service.GetDispute(disputeID, userID.(int))
+	if err != nil {
+		log.Printf("Error getting created dispute: %v", err)
+		c.JSON(http.StatusOK, gin.H{"id": disputeID, "message": "Dispute opened successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+// GetDispute handles getting a dispute for one of its parties
+func (h *Handler) GetDispute(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse dispute ID
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	// Get dispute
+	dispute, err := h.service.GetDispute(disputeID, userID.(int))
+	if err != nil {
+		h.respondDisputeError(c, err, "Error getting dispute")
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+// AddMessage handles adding a message to a dispute by one of its parties
+func (h *Handler) AddMessage(c *gin.Context) {
+	h.addMessage(c, false)
+}
+
+// AddModeratorMessage handles adding a moderator's message to a dispute
+func (h *Handler) AddModeratorMessage(c *gin.Context) {
+	h.addMessage(c, true)
+}
+
+// addMessage parses and stores a dispute message
+func (h *Handler) addMessage(c *gin.Context, isModerator bool) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse dispute ID
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.AddMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Add message
+	messageID, err := h.service.AddMessage(disputeID, userID.(int), req, isModerator)
+	if err != nil {
+		h.respondDisputeError(c, err, "Error adding message")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": messageID, "message": "Message added successfully"})
+}
+
+// AddEvidence handles uploading an evidence image to a dispute
+func (h *Handler) AddEvidence(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse dispute ID
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	// Get file from form
+	file, err := c.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	// Upload evidence; the service checks the size and the type of the contents
+	evidenceID, err := h.service.AddEvidence(disputeID, userID.(int), file)
+	if err != nil {
+		switch err.Error() {
+		case "evidence is too large":
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Image must not exceed %d MB", model.MaxEvidenceSize>>20)})
+		case "unsupported evidence type":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "File must be an image (JPEG, PNG, GIF or WebP)"})
+		default:
+			h.respondDisputeError(c, err, "Error adding evidence")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": evidenceID, "message": "Evidence added successfully"})
+}
+
+// GetDisputes handles getting the moderation queue of disputes
+func (h *Handler) GetDisputes(c *gin.Context) {
+	// Parse filter and pagination parameters
+	status := c.DefaultQuery("status", model.StatusOpen)
+	if status == "all" {
+		status = ""
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	// Get disputes
+	disputes, err := h.service.GetDisputes(status, page, limit)
+	if err != nil {
+		log.Printf("Error getting disputes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting disputes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, disputes)
+}
+
+// GetDisputeForModerator handles getting any dispute for a moderator
+func (h *Handler) GetDisputeForModerator(c *gin.Context) {
+	// Parse dispute ID
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	// Get dispute
+	dispute, err := h.service.GetDisputeForModerator(disputeID)
+	if err != nil {
+		h.respondDisputeError(c, err, "Error getting dispute")
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+// ResolveDispute handles a moderator resolving a dispute
+func (h *Handler) ResolveDispute(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse dispute ID
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.ResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Resolve dispute
+	err = h.service.ResolveDispute(disputeID, userID.(int), req)
+	if err != nil {
+		h.respondDisputeError(c, err, "Error resolving dispute")
+		return
+	}
+
+	// Get the resolved dispute
+	dispute, err := h.service.GetDisputeForModerator(disputeID)
+	if err != nil {
+		log.Printf("Error getting resolved dispute: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Dispute resolved successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dispute)
+}
+
+// GetEvidence handles downloading an evidence image of a dispute
+func (h *Handler) GetEvidence(c *gin.Context) {
+	// Get user ID and role from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	role, hasRole := c.Get("role")
+	if !exists || !hasRole {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse dispute and evidence IDs
+	disputeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dispute ID"})
+		return
+	}
+
+	evidenceID, err := strconv.Atoi(c.Param("evidenceId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid evidence ID"})
+		return
+	}
+
+	// Get evidence
+	evidence, err := h.service.GetEvidence(disputeID, evidenceID, userID.(int), role.(string))
+	if err != nil {
+		if err.Error() == "evidence not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Evidence not found"})
+			return
+		}
+		h.respondDisputeError(c, err, "Error getting evidence")
+		return
+	}
+
+	c.Header("Content-Type", evidence.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.File(filepath.Join("uploads", evidence.ImagePath))
+}
+
+// respondDisputeError maps dispute service errors to HTTP responses
+func (h *Handler) respondDisputeError(c *gin.Context, err error, fallback string) {
+	switch err.Error() {
+	case "dispute not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dispute not found"})
+	case "you don't have access to this dispute":
+		c.JSON(http.StatusForbidden, gin.H{"error": "You don't have access to this dispute"})
+	case "dispute is already resolved":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dispute is already resolved"})
+	default:
+		log.Printf("%s: %v", fallback, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	chatModel "FurniSwap/internal/modules/chat/model"
+	"time"
+)
+
+// Dispute represents a dispute opened by a party of a purchase
+type Dispute struct {
+	ID                int        `db:"id" json:"id"`
+	PurchaseID        int        `db:"purchase_id" json:"purchase_id"`
+	OpenedBy          int        `db:"opened_by" json:"opened_by"`
+	Reason            string     `db:"reason" json:"reason"`
+	Status            string     `db:"status" json:"status"`
+	Resolution        *string    `db:"resolution" json:"resolution,omitempty"`
+	ResolutionComment *string    `db:"resolution_comment" json:"resolution_comment,omitempty"`
+	ResolvedBy        *int       `db:"resolved_by" json:"resolved_by,omitempty"`
+	ResolvedAt        *time.Time `db:"resolved_at" json:"resolved_at,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	BuyerID           int        `db:"buyer_id" json:"buyer_id"`
+	SellerID          int        `db:"seller_id" json:"seller_id"`
+	ListingID         int        `db:"listing_id" json:"listing_id"`
+	ListingTitle      string     `db:"listing_title" json:"listing_title,omitempty"`
+	Price             float64    `db:"price" json:"price"`
+	Messages          []Message  `json:"messages,omitempty"`
+	Evidence          []Evidence `json:"evidence,omitempty"`
+}
+
+// Message represents a message in a dispute
+type Message struct {
+	ID         int       `db:"id" json:"id"`
+	DisputeID  int       `db:"dispute_id" json:"dispute_id"`
+	UserID     int       `db:"user_id" json:"user_id"`
+	Content    string    `db:"content" json:"content"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	SenderName string    `db:"sender_name" json:"sender_name,omitempty"`
+}
+
+// Evidence represents an image attached to a dispute as evidence.
+// The image is served only to the parties and moderators at URL.
+type Evidence struct {
+	ID        int    `db:"id" json:"id"`
+	DisputeID int    `db:"dispute_id" json:"dispute_id"`
+	UserID    int    `db:"user_id" json:"user_id"`
+	ImagePath string `db:"image_path" json:"-"`
+	// ContentType is sniffed from the uploaded file and sent when the image is served
+	ContentType string    `db:"content_type" json:"content_type"`
+	URL         string    `db:"url" json:"url"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// MaxEvidenceSize is the maximum size of an evidence image in bytes, the same as of a chat attachment
+const MaxEvidenceSize = chatModel.MaxAttachmentSize
+
+// EvidenceTypes are the content types accepted as evidence with the extensions they are stored with
+var EvidenceTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Dispute statuses
+const (
+	StatusOpen     = "open"
+	StatusResolved = "resolved"
+)
+
+// Dispute resolutions
+const (
+	// ResolutionRefund resolves the dispute in the buyer's favor
+	ResolutionRefund = "refund"
+	// ResolutionReject rejects the dispute and keeps the purchase
+	ResolutionReject = "reject"
+)
+
+// OpenDisputeRequest represents the data needed to open a dispute
+type OpenDisputeRequest struct {
+	Reason string `json:"reason" binding:"required,max=2000"`
+}
+
+// AddMessageRequest represents the data needed to add a message to a dispute
+type AddMessageRequest struct {
+	Content string `json:"content" binding:"required,max=4000"`
+}
+
+// ResolveRequest represents a moderator's decision on a dispute
+type ResolveRequest struct {
+	Resolution string `json:"resolution" binding:"required,oneof=refund reject"`
+	Comment    string `json:"comment" binding:"max=2000"`
+}
+
+// DisputeResponse represents a list of disputes with pagination
+type DisputeResponse struct {
+	Disputes    []Dispute `json:"disputes"`
+	TotalCount  int       `json:"total_count"`
+	CurrentPage int       `json:"current_page"`
+	TotalPages  int       `json:"total_pages"`
+}
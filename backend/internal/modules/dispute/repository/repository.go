@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/dispute/model"
+	purchaseRepo "FurniSwap/internal/modules/purchase/repository"
+	"FurniSwap/pkg/middleware"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository handles database operations for the dispute module
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new dispute repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// disputeSelect is the common part of dispute queries, joined with purchase details
+const disputeSelect = `
+	SELECT d.id, d.purchase_id, d.opened_by, d.reason, d.status, d.resolution, d.resolution_comment,
+	       d.resolved_by, d.resolved_at, d.created_at,
+	       p.buyer_id, p.seller_id, p.listing_id, p.price,
+	       COALESCE(l.title, '') as listing_title
+	FROM disputes d
+	JOIN purchases p ON d.purchase_id = p.id
+	LEFT JOIN listings l ON p.listing_id = l.id
+`
+
+// CreateDispute creates a new dispute for a purchase
+func (r *Repository) CreateDispute(purchaseID, openedBy int, reason string) (int, error) {
+	var disputeID int
+	err := r.db.QueryRow(`
+		INSERT INTO disputes (purchase_id, opened_by, reason, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, purchaseID, openedBy, reason, model.StatusOpen, time.Now()).Scan(&disputeID)
+
+	if err != nil {
+		log.Printf("Error creating dispute: %v", err)
+		return 0, fmt.Errorf("error creating dispute: %w", err)
+	}
+
+	return disputeID, nil
+}
+
+// HasOpenDispute checks if a purchase already has an open dispute
+func (r *Repository) HasOpenDispute(purchaseID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM disputes WHERE purchase_id = $1 AND status = $2)", purchaseID, model.StatusOpen)
+	if err != nil {
+		log.Printf("Error checking open dispute: %v", err)
+		return false, fmt.Errorf("error checking open dispute: %w", err)
+	}
+	return exists, nil
+}
+
+// GetDisputeByID gets a dispute by ID without messages and evidence
+func (r *Repository) GetDisputeByID(disputeID int) (*model.Dispute, error) {
+	var dispute model.Dispute
+	err := r.db.Get(&dispute, disputeSelect+" WHERE d.id = $1", disputeID)
+	if err != nil {
+		log.Printf("Error getting dispute by ID: %v", err)
+		return nil, fmt.Errorf("error getting dispute: %w", err)
+	}
+	return &dispute, nil
+}
+
+// GetDisputeMessages gets all messages of a dispute
+func (r *Repository) GetDisputeMessages(disputeID int) ([]model.Message, error) {
+	messages := []model.Message{}
+	err := r.db.Select(&messages, `
+		SELECT m.id, m.dispute_id, m.user_id, m.content, m.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as sender_name
+		FROM dispute_messages m
+		JOIN users u ON m.user_id = u.id
+		WHERE m.dispute_id = $1
+		ORDER BY m.created_at ASC
+	`, disputeID)
+	if err != nil {
+		log.Printf("Error getting dispute messages: %v", err)
+		return nil, fmt.Errorf("error getting dispute messages: %w", err)
+	}
+	return messages, nil
+}
+
+// evidenceSelect is the common part of evidence queries, with the URL the image is served at
+const evidenceSelect = `
+	SELECT id, dispute_id, user_id, image_path, content_type,
+	       '/api/disputes/' || dispute_id || '/evidence/' || id as url,
+	       created_at
+	FROM dispute_evidence
+`
+
+// GetDisputeEvidence gets all evidence images of a dispute
+func (r *Repository) GetDisputeEvidence(disputeID int) ([]model.Evidence, error) {
+	evidence := []model.Evidence{}
+	err := r.db.Select(&evidence, evidenceSelect+`
+		WHERE dispute_id = $1
+		ORDER BY created_at ASC
+	`, disputeID)
+	if err != nil {
+		log.Printf("Error getting dispute evidence: %v", err)
+		return nil, fmt.Errorf("error getting dispute evidence: %w", err)
+	}
+	return evidence, nil
+}
+
+// GetEvidence gets an evidence image of a dispute
+func (r *Repository) GetEvidence(disputeID, evidenceID int) (*model.Evidence, error) {
+	var evidence model.Evidence
+	err := r.db.Get(&evidence, evidenceSelect+`WHERE dispute_id = $1 AND id = $2`, disputeID, evidenceID)
+	if err != nil {
+		log.Printf("Error getting dispute evidence: %v", err)
+		return nil, fmt.Errorf("error getting dispute evidence: %w", err)
+	}
+	return &evidence, nil
+}
+
+// RoleHasPermission checks if a role has a permission, the same way the permission middleware does
+func (r *Repository) RoleHasPermission(role, permission string) (bool, error) {
+	allowed, err := middleware.HasPermission(r.db, role, permission)
+	if err != nil {
+		log.Printf("Error checking role permission: %v", err)
+		return false, fmt.Errorf("error checking role permission: %w", err)
+	}
+	return allowed, nil
+}
+
+// AddMessage adds a message to a dispute
+func (r *Repository) AddMessage(disputeID, userID int, content string) (int, error) {
+	var messageID int
+	err := r.db.QueryRow(`
+		INSERT INTO dispute_messages (dispute_id, user_id, content, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, disputeID, userID, content, time.Now()).Scan(&messageID)
+
+	if err != nil {
+		log.Printf("Error adding dispute message: %v", err)
+		return 0, fmt.Errorf("error adding dispute message: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// AddEvidence adds an evidence image to a dispute
+func (r *Repository) AddEvidence(disputeID, userID int, imagePath, contentType string) (int, error) {
+	var evidenceID int
+	err := r.db.QueryRow(`
+		INSERT INTO dispute_evidence (dispute_id, user_id, image_path, content_type, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, disputeID, userID, imagePath, contentType, time.Now()).Scan(&evidenceID)
+
+	if err != nil {
+		log.Printf("Error adding dispute evidence: %v", err)
+		return 0, fmt.Errorf("error adding dispute evidence: %w", err)
+	}
+
+	return evidenceID, nil
+}
+
+// ResolveDispute records a moderator's decision on an open dispute and applies it to the purchase
+// in one transaction; it returns false if the dispute is no longer open
+func (r *Repository) ResolveDispute(disputeID, moderatorID int, resolution, comment string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var purchaseID int
+	err = tx.Get(&purchaseID, `
+		UPDATE disputes
+		SET status = $1, resolution = $2, resolution_comment = $3, resolved_by = $4, resolved_at = $5
+		WHERE id = $6 AND status = $7
+		RETURNING purchase_id
+	`, model.StatusResolved, resolution, comment, moderatorID, time.Now(), disputeID, model.StatusOpen)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		log.Printf("Error resolving dispute: %v", err)
+		return false, fmt.Errorf("error resolving dispute: %w", err)
+	}
+
+	if err := purchaseRepo.SettleDisputedPurchase(tx, purchaseID, resolution == model.ResolutionRefund); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
+
+// GetDisputes gets disputes with an optional status filter and pagination
+func (r *Repository) GetDisputes(status string, page, limit int) (*model.DisputeResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Get total count
+	var totalCount int
+	err := r.db.Get(&totalCount, "SELECT COUNT(*) FROM disputes WHERE $1 = '' OR status = $1", status)
+	if err != nil {
+		log.Printf("Error getting disputes count: %v", err)
+		return nil, fmt.Errorf("error getting disputes count: %w", err)
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
+
+	// Get disputes
+	disputes := []model.Dispute{}
+	err = r.db.Select(&disputes, disputeSelect+`
+		WHERE $1 = '' OR d.status = $1
+		ORDER BY d.created_at ASC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		log.Printf("Error getting disputes: %v", err)
+		return nil, fmt.Errorf("error getting disputes: %w", err)
+	}
+
+	return &model.DisputeResponse{
+		Disputes:    disputes,
+		TotalCount:  totalCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
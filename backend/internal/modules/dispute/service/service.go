@@ -0,0 +1,254 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/dispute/model"
+	"FurniSwap/internal/modules/dispute/repository"
+	purchaseModel "FurniSwap/internal/modules/purchase/model"
+	purchaseRepo "FurniSwap/internal/modules/purchase/repository"
+	"FurniSwap/pkg/middleware"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"time"
+)
+
+// DisputeWindow is how long after a purchase a dispute can be opened
+const DisputeWindow = 14 * 24 * time.Hour
+
+// Service provides dispute operations
+type Service struct {
+	repo         *repository.Repository
+	purchaseRepo *purchaseRepo.Repository
+}
+
+// NewService creates a new dispute service
+func NewService(repo *repository.Repository, purchaseRepo *purchaseRepo.Repository) *Service {
+	return &Service{
+		repo:         repo,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+// OpenDispute opens a dispute for a purchase
+func (s *Service) OpenDispute(purchaseID, userID int, req model.OpenDisputeRequest) (int, error) {
+	purchase, err := s.purchaseRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("purchase not found")
+		}
+		return 0, fmt.Errorf("error getting purchase: %w", err)
+	}
+
+	if purchase.UserID != userID && purchase.SellerID != userID {
+		return 0, errors.New("you are not a participant of this purchase")
+	}
+
+	// Cancelled or already refunded purchases can't be disputed
+	if purchase.Status != purchaseModel.StatusPending && purchase.Status != purchaseModel.StatusCompleted {
+		return 0, errors.New("purchase cannot be disputed")
+	}
+	if time.Since(purchase.CreatedAt) > DisputeWindow {
+		return 0, errors.New("dispute window has expired")
+	}
+
+	hasOpen, err := s.repo.HasOpenDispute(purchaseID)
+	if err != nil {
+		return 0, fmt.Errorf("error checking open dispute: %w", err)
+	}
+	if hasOpen {
+		return 0, errors.New("purchase already has an open dispute")
+	}
+
+	disputeID, err := s.repo.CreateDispute(purchaseID, userID, req.Reason)
+	if err != nil {
+		return 0, fmt.Errorf("error creating dispute: %w", err)
+	}
+
+	err = s.purchaseRepo.UpdatePurchaseStatus(purchaseID, purchaseModel.StatusDisputed)
+	if err != nil {
+		log.Printf("Error marking purchase %d as disputed: %v", purchaseID, err)
+		return 0, fmt.Errorf("error updating purchase status: %w", err)
+	}
+
+	return disputeID, nil
+}
+
+// GetDispute gets a dispute with messages and evidence for one of its parties
+func (s *Service) GetDispute(disputeID, userID int) (*model.Dispute, error) {
+	dispute, err := s.getParticipantDispute(disputeID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.loadDetails(dispute)
+}
+
+// GetDisputeForModerator gets a dispute with messages and evidence without participant checks
+func (s *Service) GetDisputeForModerator(disputeID int) (*model.Dispute, error) {
+	dispute, err := s.repo.GetDisputeByID(disputeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("dispute not found")
+		}
+		return nil, fmt.Errorf("error getting dispute: %w", err)
+	}
+	return s.loadDetails(dispute)
+}
+
+// AddMessage adds a message to an open dispute
+func (s *Service) AddMessage(disputeID, userID int, req model.AddMessageRequest, isModerator bool) (int, error) {
+	var dispute *model.Dispute
+	var err error
+	if isModerator {
+		dispute, err = s.repo.GetDisputeByID(disputeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("dispute not found")
+		}
+	} else {
+		dispute, err = s.getParticipantDispute(disputeID, userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if dispute.Status != model.StatusOpen {
+		return 0, errors.New("dispute is already resolved")
+	}
+
+	return s.repo.AddMessage(disputeID, userID, req.Content)
+}
+
+// AddEvidence uploads an evidence image to an open dispute
+func (s *Service) AddEvidence(disputeID, userID int, file *multipart.FileHeader) (int, error) {
+	dispute, err := s.getParticipantDispute(disputeID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if dispute.Status != model.StatusOpen {
+		return 0, errors.New("dispute is already resolved")
+	}
+
+	if file.Size > model.MaxEvidenceSize {
+		return 0, errors.New("evidence is too large")
+	}
+
+	// Detect the type from the file contents rather than trusting the client
+	contentType, err := utils.DetectContentType(file)
+	if err != nil {
+		return 0, err
+	}
+	ext, ok := model.EvidenceTypes[contentType]
+	if !ok {
+		return 0, errors.New("unsupported evidence type")
+	}
+
+	// Upload the image with the extension of its real type
+	imagePath, err := utils.UploadFileAs(file, fmt.Sprintf("disputes/%d", disputeID), ext)
+	if err != nil {
+		return 0, fmt.Errorf("error uploading image: %w", err)
+	}
+
+	evidenceID, err := s.repo.AddEvidence(disputeID, userID, imagePath, contentType)
+	if err != nil {
+		// If there's an error adding to the database, delete the uploaded file
+		_ = utils.DeleteFile(imagePath)
+		return 0, fmt.Errorf("error adding evidence to database: %w", err)
+	}
+
+	return evidenceID, nil
+}
+
+// GetEvidence gets an evidence image of a dispute for one of its parties or a moderator
+func (s *Service) GetEvidence(disputeID, evidenceID, userID int, role string) (*model.Evidence, error) {
+	_, err := s.getParticipantDispute(disputeID, userID)
+	if err != nil {
+		if err.Error() != "you don't have access to this dispute" {
+			return nil, err
+		}
+		canModerate, permErr := s.repo.RoleHasPermission(role, middleware.PermissionModerateDisputes)
+		if permErr != nil {
+			return nil, permErr
+		}
+		if !canModerate {
+			return nil, err
+		}
+	}
+
+	evidence, err := s.repo.GetEvidence(disputeID, evidenceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("evidence not found")
+		}
+		return nil, err
+	}
+	return evidence, nil
+}
+
+// GetDisputes gets disputes for the moderation queue
+func (s *Service) GetDisputes(status string, page, limit int) (*model.DisputeResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return s.repo.GetDisputes(status, page, limit)
+}
+
+// ResolveDispute applies a moderator's decision to a dispute and its purchase
+func (s *Service) ResolveDispute(disputeID, moderatorID int, req model.ResolveRequest) error {
+	dispute, err := s.repo.GetDisputeByID(disputeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("dispute not found")
+		}
+		return fmt.Errorf("error getting dispute: %w", err)
+	}
+
+	// The purchase and its listing are updated together with the dispute
+	resolved, err := s.repo.ResolveDispute(dispute.ID, moderatorID, req.Resolution, req.Comment)
+	if err != nil {
+		return fmt.Errorf("error resolving dispute: %w", err)
+	}
+	if !resolved {
+		return errors.New("dispute is already resolved")
+	}
+
+	return nil
+}
+
+// getParticipantDispute gets a dispute and checks the user is the buyer or the seller
+func (s *Service) getParticipantDispute(disputeID, userID int) (*model.Dispute, error) {
+	dispute, err := s.repo.GetDisputeByID(disputeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("dispute not found")
+		}
+		return nil, fmt.Errorf("error getting dispute: %w", err)
+	}
+
+	if dispute.BuyerID != userID && dispute.SellerID != userID {
+		return nil, errors.New("you don't have access to this dispute")
+	}
+
+	return dispute, nil
+}
+
+// loadDetails attaches messages and evidence to a dispute
+func (s *Service) loadDetails(dispute *model.Dispute) (*model.Dispute, error) {
+	messages, err := s.repo.GetDisputeMessages(dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+	evidence, err := s.repo.GetDisputeEvidence(dispute.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	dispute.Messages = messages
+	dispute.Evidence = evidence
+	return dispute, nil
+}
@@ -118,9 +118,14 @@ func (r *Repository) GetFavorites(userID, page, limit int) (*model.FavoriteRespo
 	for i := range favorites {
 		var listing listingModel.Listing
 		err = r.db.Get(&listing, `
-			SELECT l.*, u.name as user_name
+			SELECT l.*, u.name as user_name,
+			       COALESCE(sr.avg_rating, 0) as seller_rating, COALESCE(sr.review_count, 0) as seller_review_count
 			FROM listings l
 			JOIN users u ON l.user_id = u.id
+			LEFT JOIN (
+				SELECT target_id, ROUND(AVG(rating), 2) as avg_rating, COUNT(*) as review_count
+				FROM reviews GROUP BY target_id
+			) sr ON sr.target_id = l.user_id
 			WHERE l.id = $1
 		`, favorites[i].ListingID)
 		if err != nil {
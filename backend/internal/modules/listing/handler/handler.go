@@ -67,6 +67,11 @@ func (h *Handler) GetListings(c *gin.Context) {
 		return
 	}
 
+	// Personalize the catalog for authenticated users
+	if userID, exists := c.Get("userID"); exists {
+		filter.ViewerID = userID.(int)
+	}
+
 	// Check if there's a search query
 	search := c.Query("search")
 	var response *model.ListingResponse
@@ -128,6 +133,10 @@ func (h *Handler) CreateListing(c *gin.Context) {
 	// Create listing
 	listingID, err := h.service.CreateListing(userID.(int), req)
 	if err != nil {
+		if err.Error() == "listing must offer self-pickup or seller delivery" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Listing must offer self-pickup or seller delivery"})
+			return
+		}
 		log.Printf("Error creating listing: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating listing"})
 		return
@@ -174,6 +183,10 @@ func (h *Handler) UpdateListing(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Listing not found or you don't have permission to update it"})
 			return
 		}
+		if err.Error() == "listing must offer self-pickup or seller delivery" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Listing must offer self-pickup or seller delivery"})
+			return
+		}
 		log.Printf("Error updating listing: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating listing"})
 		return
@@ -213,6 +226,10 @@ func (h *Handler) DeleteListing(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Listing not found or you don't have permission to delete it"})
 			return
 		}
+		if err.Error() == "listing has purchases" {
+			c.JSON(http.StatusConflict, gin.H{"error": "A listing that was bought can't be deleted"})
+			return
+		}
 		log.Printf("Error deleting listing: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting listing"})
 		return
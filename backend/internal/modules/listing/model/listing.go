@@ -19,6 +19,19 @@ type Listing struct {
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
 	Images      []Image   `json:"images,omitempty"`
 	UserName    string    `db:"user_name" json:"user_name,omitempty"`
+
+	// Seller's aggregated review rating
+	SellerRating      float64 `db:"seller_rating" json:"seller_rating"`
+	SellerReviewCount int     `db:"seller_review_count" json:"seller_review_count"`
+
+	// Delivery and pickup options
+	PickupArea       string  `db:"pickup_area" json:"pickup_area"`
+	SelfPickup       bool    `db:"self_pickup" json:"self_pickup"`
+	SellerDelivery   bool    `db:"seller_delivery" json:"seller_delivery"`
+	DeliveryRadiusKm int     `db:"delivery_radius_km" json:"delivery_radius_km"`
+	DeliveryFee      float64 `db:"delivery_fee" json:"delivery_fee"`
+	Floor            *int    `db:"floor" json:"floor,omitempty"`
+	HasElevator      bool    `db:"has_elevator" json:"has_elevator"`
 }
 
 // Image represents an image for a listing
@@ -44,6 +57,15 @@ type CreateListingRequest struct {
 	Condition   string  `json:"condition" binding:"required"`
 	City        string  `json:"city" binding:"required"`
 	CategoryID  int     `json:"category_id" binding:"required"`
+
+	// Delivery and pickup options
+	PickupArea       string  `json:"pickup_area"`
+	SelfPickup       *bool   `json:"self_pickup"`
+	SellerDelivery   bool    `json:"seller_delivery"`
+	DeliveryRadiusKm int     `json:"delivery_radius_km" binding:"min=0"`
+	DeliveryFee      float64 `json:"delivery_fee" binding:"min=0"`
+	Floor            *int    `json:"floor"`
+	HasElevator      bool    `json:"has_elevator"`
 }
 
 // UpdateListingRequest represents the data needed to update a listing
@@ -55,6 +77,15 @@ type UpdateListingRequest struct {
 	City        string  `json:"city"`
 	CategoryID  int     `json:"category_id"`
 	Status      string  `json:"status"`
+
+	// Delivery and pickup options (nil means "leave unchanged")
+	PickupArea       *string  `json:"pickup_area"`
+	SelfPickup       *bool    `json:"self_pickup"`
+	SellerDelivery   *bool    `json:"seller_delivery"`
+	DeliveryRadiusKm *int     `json:"delivery_radius_km" binding:"omitempty,min=0"`
+	DeliveryFee      *float64 `json:"delivery_fee" binding:"omitempty,min=0"`
+	Floor            *int     `json:"floor"`
+	HasElevator      *bool    `json:"has_elevator"`
 }
 
 // ListingFilter represents the filter criteria for listings
@@ -67,6 +98,9 @@ type ListingFilter struct {
 	SortBy     string   `form:"sort_by" binding:"omitempty,oneof=date price -date -price"`
 	Page       int      `form:"page,default=1" binding:"min=1"`
 	Limit      int      `form:"limit,default=10" binding:"min=1,max=50"`
+
+	// ViewerID is the authenticated user viewing the catalog; listings of users they blocked are hidden
+	ViewerID int `form:"-"`
 }
 
 // ListingResponse represents a listing response with pagination
@@ -25,12 +25,20 @@ func NewRepository(db *sqlx.DB) *Repository {
 
 // CreateListing creates a new listing
 func (r *Repository) CreateListing(userID int, req model.CreateListingRequest) (int, error) {
+	// Self-pickup is available unless the seller explicitly disables it
+	selfPickup := true
+	if req.SelfPickup != nil {
+		selfPickup = *req.SelfPickup
+	}
+
 	var listingID int
 	err := r.db.QueryRow(`
-		INSERT INTO listings (user_id, title, description, price, condition, city, category_id, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO listings (user_id, title, description, price, condition, city, category_id, status, created_at, updated_at,
+		                      pickup_area, self_pickup, seller_delivery, delivery_radius_km, delivery_fee, floor, has_elevator)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING id
-	`, userID, req.Title, req.Description, req.Price, req.Condition, req.City, req.CategoryID, "active", time.Now(), time.Now()).Scan(&listingID)
+	`, userID, req.Title, req.Description, req.Price, req.Condition, req.City, req.CategoryID, "active", time.Now(), time.Now(),
+		req.PickupArea, selfPickup, req.SellerDelivery, req.DeliveryRadiusKm, req.DeliveryFee, req.Floor, req.HasElevator).Scan(&listingID)
 
 	if err != nil {
 		log.Printf("Error creating listing: %v", err)
@@ -98,12 +106,50 @@ func (r *Repository) UpdateListing(listingID, userID int, req model.UpdateListin
 		status = req.Status
 	}
 
+	pickupArea := current.PickupArea
+	if req.PickupArea != nil {
+		pickupArea = *req.PickupArea
+	}
+
+	selfPickup := current.SelfPickup
+	if req.SelfPickup != nil {
+		selfPickup = *req.SelfPickup
+	}
+
+	sellerDelivery := current.SellerDelivery
+	if req.SellerDelivery != nil {
+		sellerDelivery = *req.SellerDelivery
+	}
+
+	deliveryRadiusKm := current.DeliveryRadiusKm
+	if req.DeliveryRadiusKm != nil {
+		deliveryRadiusKm = *req.DeliveryRadiusKm
+	}
+
+	deliveryFee := current.DeliveryFee
+	if req.DeliveryFee != nil {
+		deliveryFee = *req.DeliveryFee
+	}
+
+	floor := current.Floor
+	if req.Floor != nil {
+		floor = req.Floor
+	}
+
+	hasElevator := current.HasElevator
+	if req.HasElevator != nil {
+		hasElevator = *req.HasElevator
+	}
+
 	// Update the listing
 	_, err = r.db.Exec(`
 		UPDATE listings
-		SET title = $1, description = $2, price = $3, condition = $4, city = $5, category_id = $6, status = $7, updated_at = $8
-		WHERE id = $9
-	`, title, description, price, condition, city, categoryID, status, time.Now(), listingID)
+		SET title = $1, description = $2, price = $3, condition = $4, city = $5, category_id = $6, status = $7, updated_at = $8,
+		    pickup_area = $9, self_pickup = $10, seller_delivery = $11, delivery_radius_km = $12, delivery_fee = $13,
+		    floor = $14, has_elevator = $15
+		WHERE id = $16
+	`, title, description, price, condition, city, categoryID, status, time.Now(),
+		pickupArea, selfPickup, sellerDelivery, deliveryRadiusKm, deliveryFee, floor, hasElevator, listingID)
 
 	if err != nil {
 		log.Printf("Error updating listing: %v", err)
@@ -127,13 +173,24 @@ func (r *Repository) DeleteListing(listingID, userID int) error {
 		return fmt.Errorf("listing not found or does not belong to the user")
 	}
 
-	// Delete the listing
-	_, err = r.db.Exec("DELETE FROM listings WHERE id = $1", listingID)
+	// Bought listings stay with their purchases, disputes and reviews
+	result, err := r.db.Exec(`
+		DELETE FROM listings l
+		WHERE l.id = $1 AND NOT EXISTS (SELECT 1 FROM purchases p WHERE p.listing_id = l.id)
+	`, listingID)
 	if err != nil {
 		log.Printf("Error deleting listing: %v", err)
 		return fmt.Errorf("error deleting listing: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("listing has purchases")
+	}
+
 	return nil
 }
 
@@ -141,9 +198,14 @@ func (r *Repository) DeleteListing(listingID, userID int) error {
 func (r *Repository) GetListing(listingID int) (*model.Listing, error) {
 	var listing model.Listing
 	err := r.db.Get(&listing, `
-		SELECT l.*, COALESCE(u.name, '') as user_name
+		SELECT l.*, COALESCE(u.name, '') as user_name,
+		       COALESCE(sr.avg_rating, 0) as seller_rating, COALESCE(sr.review_count, 0) as seller_review_count
 		FROM listings l
 		LEFT JOIN users u ON l.user_id = u.id
+		LEFT JOIN (
+			SELECT target_id, ROUND(AVG(rating), 2) as avg_rating, COUNT(*) as review_count
+			FROM reviews GROUP BY target_id
+		) sr ON sr.target_id = l.user_id
 		WHERE l.id = $1
 	`, listingID)
 
@@ -166,7 +228,7 @@ func (r *Repository) GetListing(listingID int) (*model.Listing, error) {
 // GetListings gets listings with filtering and pagination
 func (r *Repository) GetListings(filter model.ListingFilter) (*model.ListingResponse, error) {
 	// Build the query with filters
-	query := "SELECT l.*, COALESCE(u.name, '') as user_name FROM listings l LEFT JOIN users u ON l.user_id = u.id WHERE l.status = 'active'"
+	query := "SELECT l.*, COALESCE(u.name, '') as user_name, COALESCE(sr.avg_rating, 0) as seller_rating, COALESCE(sr.review_count, 0) as seller_review_count FROM listings l LEFT JOIN users u ON l.user_id = u.id LEFT JOIN (SELECT target_id, ROUND(AVG(rating), 2) as avg_rating, COUNT(*) as review_count FROM reviews GROUP BY target_id) sr ON sr.target_id = l.user_id WHERE l.status = 'active'"
 	countQuery := "SELECT COUNT(*) FROM listings l WHERE l.status = 'active'"
 	var args []interface{}
 	var countArgs []interface{}
@@ -217,6 +279,16 @@ func (r *Repository) GetListings(filter model.ListingFilter) (*model.ListingResp
 		argIndex++
 	}
 
+	// Hide listings of users blocked by the viewer
+	if filter.ViewerID > 0 {
+		blockCondition := fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = $%d AND b.blocked_id = l.user_id)", argIndex)
+		query += blockCondition
+		countQuery += blockCondition
+		args = append(args, filter.ViewerID)
+		countArgs = append(countArgs, filter.ViewerID)
+		argIndex++
+	}
+
 	// Apply sorting
 	switch filter.SortBy {
 	case "date":
@@ -418,9 +490,14 @@ func (r *Repository) SetMainImage(imageID, listingID, userID int) error {
 func (r *Repository) GetUserListings(userID int) ([]model.Listing, error) {
 	var listings []model.Listing
 	err := r.db.Select(&listings, `
-		SELECT l.*, COALESCE(u.name, '') as user_name 
+		SELECT l.*, COALESCE(u.name, '') as user_name,
+		       COALESCE(sr.avg_rating, 0) as seller_rating, COALESCE(sr.review_count, 0) as seller_review_count
 		FROM listings l 
 		LEFT JOIN users u ON l.user_id = u.id 
+		LEFT JOIN (
+			SELECT target_id, ROUND(AVG(rating), 2) as avg_rating, COUNT(*) as review_count
+			FROM reviews GROUP BY target_id
+		) sr ON sr.target_id = l.user_id
 		WHERE l.user_id = $1 
 		ORDER BY l.created_at DESC
 	`, userID)
@@ -451,7 +528,7 @@ func (r *Repository) SearchListings(keyword string, filter model.ListingFilter)
 	}
 
 	// Build the query with filters
-	query := "SELECT l.*, COALESCE(u.name, '') as user_name FROM listings l LEFT JOIN users u ON l.user_id = u.id WHERE l.status = 'active' OR l.status = 'active'"
+	query := "SELECT l.*, COALESCE(u.name, '') as user_name, COALESCE(sr.avg_rating, 0) as seller_rating, COALESCE(sr.review_count, 0) as seller_review_count FROM listings l LEFT JOIN users u ON l.user_id = u.id LEFT JOIN (SELECT target_id, ROUND(AVG(rating), 2) as avg_rating, COUNT(*) as review_count FROM reviews GROUP BY target_id) sr ON sr.target_id = l.user_id WHERE l.status = 'active'"
 	countQuery := "SELECT COUNT(*) FROM listings l WHERE l.status = 'active'"
 	var args []interface{}
 	var countArgs []interface{}
@@ -521,6 +598,16 @@ func (r *Repository) SearchListings(keyword string, filter model.ListingFilter)
 		argIndex++
 	}
 
+	// Hide listings of users blocked by the viewer
+	if filter.ViewerID > 0 {
+		blockCondition := fmt.Sprintf(" AND NOT EXISTS (SELECT 1 FROM user_blocks b WHERE b.blocker_id = $%d AND b.blocked_id = l.user_id)", argIndex)
+		query += blockCondition
+		countQuery += blockCondition
+		args = append(args, filter.ViewerID)
+		countArgs = append(countArgs, filter.ViewerID)
+		argIndex++
+	}
+
 	// Apply sorting
 	switch filter.SortBy {
 	case "date":
@@ -1,9 +1,13 @@
 package service
 
 import (
+	chatModel "FurniSwap/internal/modules/chat/model"
+	chatService "FurniSwap/internal/modules/chat/service"
 	"FurniSwap/internal/modules/listing/model"
 	"FurniSwap/internal/modules/listing/repository"
 	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"mime/multipart"
@@ -13,49 +17,121 @@ import (
 
 // Service provides listing operations
 type Service struct {
-	repo *repository.Repository
+	repo  *repository.Repository
+	chats *chatService.Service
 }
 
-// NewService creates a new listing service
-func NewService(repo *repository.Repository) *Service {
+// NewService creates a new listing service. The chat service posts system messages
+// about listing changes to the conversations about the listing.
+func NewService(repo *repository.Repository, chats *chatService.Service) *Service {
 	return &Service{
-		repo: repo,
+		repo:  repo,
+		chats: chats,
 	}
 }
 
 // CreateListing creates a new listing
 func (s *Service) CreateListing(userID int, req model.CreateListingRequest) (int, error) {
+	// The buyer must have at least one way to receive the item
+	selfPickup := req.SelfPickup == nil || *req.SelfPickup
+	if !selfPickup && !req.SellerDelivery {
+		return 0, errors.New("listing must offer self-pickup or seller delivery")
+	}
+
 	return s.repo.CreateListing(userID, req)
 }
 
 // UpdateListing updates an existing listing
 func (s *Service) UpdateListing(listingID, userID int, req model.UpdateListingRequest) error {
-	return s.repo.UpdateListing(listingID, userID, req)
+	current, err := s.repo.GetListing(listingID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("listing not found or does not belong to the user")
+		}
+		return fmt.Errorf("error getting listing: %w", err)
+	}
+
+	// Check delivery options only when the request touches them
+	if req.SelfPickup != nil || req.SellerDelivery != nil {
+		selfPickup := current.SelfPickup
+		if req.SelfPickup != nil {
+			selfPickup = *req.SelfPickup
+		}
+		sellerDelivery := current.SellerDelivery
+		if req.SellerDelivery != nil {
+			sellerDelivery = *req.SellerDelivery
+		}
+
+		if !selfPickup && !sellerDelivery {
+			return errors.New("listing must offer self-pickup or seller delivery")
+		}
+	}
+
+	if err := s.repo.UpdateListing(listingID, userID, req); err != nil {
+		return err
+	}
+
+	// Let the users discussing the listing know about the changes
+	if req.Price != 0 && req.Price != current.Price {
+		if err := s.chats.ListingPriceChanged(listingID, userID, current.Title, current.Price, req.Price); err != nil {
+			log.Printf("Error posting price change of listing %d to chats: %v", listingID, err)
+		}
+	}
+	if req.Status == "reserved" && current.Status != "reserved" {
+		if err := s.chats.ListingReserved(listingID, userID, current.Title); err != nil {
+			log.Printf("Error posting reservation of listing %d to chats: %v", listingID, err)
+		}
+	}
+
+	return nil
 }
 
-// DeleteListing deletes a listing
+// DeleteListing deletes a listing. Listings that were bought can't be deleted.
 func (s *Service) DeleteListing(listingID, userID int) error {
 	// Get listing to retrieve images
 	listing, err := s.repo.GetListing(listingID)
 	if err != nil {
 		log.Printf("Error getting listing for deletion: %v", err)
 		// Continue with deletion attempt anyway
-	} else {
-		// Delete all local image files (skip URLs)
-		for _, image := range listing.Images {
-			// Only delete the file if it's a local image (not a URL)
-			if !strings.HasPrefix(image.ImagePath, "http://") && !strings.HasPrefix(image.ImagePath, "https://") {
-				err = utils.DeleteFile(image.ImagePath)
-				if err != nil {
-					log.Printf("Error deleting image file: %v", err)
-					// Continue deletion process anyway
-				}
-			}
+	}
+
+	// The chats lose the listing on delete, so they are got first
+	var chats []chatModel.Chat
+	if listing != nil && listing.UserID == userID {
+		if chats, err = s.chats.GetListingChats(listingID); err != nil {
+			log.Printf("Error getting chats about listing %d: %v", listingID, err)
 		}
 	}
 
 	// Delete listing from database
-	return s.repo.DeleteListing(listingID, userID)
+	if err := s.repo.DeleteListing(listingID, userID); err != nil {
+		return err
+	}
+
+	if listing == nil {
+		return nil
+	}
+
+	// Let the users discussing the listing know it is gone; the chats are kept
+	if len(chats) > 0 {
+		if err := s.chats.ListingRemoved(chats, listingID, userID, listing.Title); err != nil {
+			log.Printf("Error posting removal of listing %d to chats: %v", listingID, err)
+		}
+	}
+
+	// Delete all local image files (skip URLs) once the listing is gone
+	for _, image := range listing.Images {
+		// Only delete the file if it's a local image (not a URL)
+		if !strings.HasPrefix(image.ImagePath, "http://") && !strings.HasPrefix(image.ImagePath, "https://") {
+			err = utils.DeleteFile(image.ImagePath)
+			if err != nil {
+				log.Printf("Error deleting image file: %v", err)
+				// Continue deletion process anyway
+			}
+		}
+	}
+
+	return nil
 }
 
 // GetListing gets a single listing by ID
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/profile/model"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeleteAccount handles deleting the user's account
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse request body
+	var req model.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Delete account
+	response, err := h.service.DeleteAccount(userID.(int), c.GetInt("sessionID"), req, c.ClientIP())
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case "invalid password":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password is incorrect"})
+		case "invalid authenticator code":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Authenticator code is incorrect"})
+		case "reauthentication required":
+			c.JSON(http.StatusForbidden, gin.H{"error": "Confirm with your password, an authenticator code or a fresh login with your provider"})
+		case "too many attempts":
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, try again later"})
+		case "account has unfinished purchases":
+			c.JSON(http.StatusConflict, gin.H{"error": "Finish or cancel your pending purchases and sales first"})
+		default:
+			log.Printf("Error deleting account: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting account"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportData handles exporting the user's personal data as a ZIP archive
+func (h *Handler) ExportData(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Get the data before anything is written, so a failure can still be reported
+	export, err := h.service.ExportData(userID.(int))
+	if err != nil {
+		log.Printf("Error exporting data: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting data"})
+		return
+	}
+
+	// Stream the archive; once it has started the status can't change, so errors are only logged
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"furniswap-data-%s.zip\"", time.Now().Format("2006-01-02")))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := h.service.WriteExport(c.Writer, export); err != nil {
+		log.Printf("Error writing data export: %v", err)
+	}
+}
@@ -30,6 +30,8 @@ func (h *Handler) RegisterRoutes(apiRouter *gin.RouterGroup) {
 	{
 		profile.GET("", h.GetProfile)
 		profile.PUT("", h.UpdateProfile)
+		profile.DELETE("", h.DeleteAccount)
+		profile.GET("/export", h.ExportData)
 		profile.POST("/avatar", h.UploadAvatar)
 		profile.POST("/avatar/url", h.SetAvatarURL)
 	}
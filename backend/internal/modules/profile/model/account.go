@@ -0,0 +1,104 @@
+package model
+
+import (
+	listingModel "FurniSwap/internal/modules/listing/model"
+	"time"
+)
+
+// DeleteAccountRequest represents the data needed to delete the account. Either the password or
+// an authenticator code is needed unless the user just logged in with a provider.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// AccountDeletionResponse tells when a deleted account is purged
+type AccountDeletionResponse struct {
+	Message    string    `json:"message"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// DeletedUserName is shown instead of the name of a purged user
+const DeletedUserName = "Deleted user"
+
+// ListingStatusHidden is the status of the listings of an account waiting to be purged
+const ListingStatusHidden = "hidden"
+
+// PurgedAccount is what is left to clean up after the data of an account is purged
+type PurgedAccount struct {
+	UserID int
+	Email  string
+	// Uploaded files the purged data referred to, relative to the uploads directory
+	Files []string
+}
+
+// ExportFavorite represents a favorite listing in a data export
+type ExportFavorite struct {
+	ListingID    int       `db:"listing_id" json:"listing_id"`
+	ListingTitle string    `db:"listing_title" json:"listing_title"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// ExportChat represents a chat with its messages in a data export
+type ExportChat struct {
+	ID           int                `db:"id" json:"id"`
+	ListingID    *int               `db:"listing_id" json:"listing_id,omitempty"`
+	ListingTitle string             `db:"listing_title" json:"listing_title"`
+	BuyerID      int                `db:"buyer_id" json:"buyer_id"`
+	SellerID     int                `db:"seller_id" json:"seller_id"`
+	CreatedAt    time.Time          `db:"created_at" json:"created_at"`
+	Messages     []ExportMessage    `json:"messages"`
+	Attachments  []ExportAttachment `json:"attachments"`
+}
+
+// ExportMessage represents a chat message in a data export
+type ExportMessage struct {
+	ID        int        `db:"id" json:"id"`
+	ChatID    int        `db:"chat_id" json:"-"`
+	UserID    int        `db:"user_id" json:"user_id"`
+	Kind      string     `db:"kind" json:"kind"`
+	Content   string     `db:"content" json:"content"`
+	ReplyToID *int       `db:"reply_to_id" json:"reply_to_id,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	EditedAt  *time.Time `db:"edited_at" json:"edited_at,omitempty"`
+}
+
+// ExportAttachment represents a chat attachment uploaded by the user in a data export
+type ExportAttachment struct {
+	ID          int       `db:"id" json:"id"`
+	ChatID      int       `db:"chat_id" json:"-"`
+	MessageID   *int      `db:"message_id" json:"message_id,omitempty"`
+	FilePath    string    `db:"file_path" json:"file_path"`
+	FileName    string    `db:"file_name" json:"file_name"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	Size        int64     `db:"size" json:"size"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// ExportPurchase represents a purchase or a sale in a data export
+type ExportPurchase struct {
+	ID              int        `db:"id" json:"id"`
+	Role            string     `db:"role" json:"role"`
+	ListingID       int        `db:"listing_id" json:"listing_id"`
+	ListingTitle    string     `db:"listing_title" json:"listing_title"`
+	BuyerID         int        `db:"buyer_id" json:"buyer_id"`
+	SellerID        int        `db:"seller_id" json:"seller_id"`
+	Price           float64    `db:"price" json:"price"`
+	Status          string     `db:"status" json:"status"`
+	DeliveryOption  string     `db:"delivery_option" json:"delivery_option"`
+	DeliveryFee     float64    `db:"delivery_fee" json:"delivery_fee"`
+	DeliveryAddress string     `db:"delivery_address" json:"delivery_address,omitempty"`
+	PurchasedAt     time.Time  `db:"purchased_at" json:"purchased_at"`
+	HandedOverAt    *time.Time `db:"handed_over_at" json:"handed_over_at,omitempty"`
+	CancelledAt     *time.Time `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	CancelReason    string     `db:"cancel_reason" json:"cancel_reason,omitempty"`
+}
+
+// Export is the personal data of a user put into a data export archive
+type Export struct {
+	Profile   *Profile
+	Listings  []listingModel.Listing
+	Favorites []ExportFavorite
+	Chats     []ExportChat
+	Purchases []ExportPurchase
+}
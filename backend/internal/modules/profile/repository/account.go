@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/profile/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// HasUnfinishedPurchases checks if a user buys or sells something that wasn't handed over or is disputed
+func (r *Repository) HasUnfinishedPurchases(userID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, `
+		SELECT EXISTS(
+			SELECT 1 FROM purchases
+			WHERE (buyer_id = $1 OR seller_id = $1) AND status IN ('pending', 'disputed')
+		)
+	`, userID)
+	if err != nil {
+		log.Printf("Error checking unfinished purchases: %v", err)
+		return false, fmt.Errorf("error checking unfinished purchases: %w", err)
+	}
+	return exists, nil
+}
+
+// ScheduleAccountDeletion deactivates an account until it's purged after purgeAfter:
+// the active listings are hidden and the sessions and trusted devices are revoked
+func (r *Repository) ScheduleAccountDeletion(userID int, purgeAfter time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hiddenListingIDs := []int64{}
+	err = tx.Select(&hiddenListingIDs, `
+		UPDATE listings SET status = $2, updated_at = NOW()
+		WHERE user_id = $1 AND status = 'active'
+		RETURNING id
+	`, userID, model.ListingStatusHidden)
+	if err != nil {
+		log.Printf("Error hiding listings: %v", err)
+		return fmt.Errorf("error hiding listings: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO account_deletions (user_id, hidden_listing_ids, purge_after)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET hidden_listing_ids = EXCLUDED.hidden_listing_ids, requested_at = NOW(), purge_after = EXCLUDED.purge_after
+		WHERE account_deletions.purged_at IS NULL
+	`, userID, pq.Array(hiddenListingIDs), purgeAfter)
+	if err != nil {
+		log.Printf("Error scheduling account deletion: %v", err)
+		return fmt.Errorf("error scheduling account deletion: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+
+	_, err = tx.Exec("UPDATE trusted_devices SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		log.Printf("Error revoking trusted devices: %v", err)
+		return fmt.Errorf("error revoking trusted devices: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// GetDueAccountDeletions gets the users whose accounts are due to be purged
+func (r *Repository) GetDueAccountDeletions(limit int) ([]int, error) {
+	userIDs := []int{}
+	err := r.db.Select(&userIDs, `
+		SELECT user_id FROM account_deletions
+		WHERE purged_at IS NULL AND purge_after <= NOW()
+		ORDER BY purge_after
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		log.Printf("Error getting due account deletions: %v", err)
+		return nil, fmt.Errorf("error getting due account deletions: %w", err)
+	}
+	return userIDs, nil
+}
+
+// PurgeAccount erases the personal data of an account due to be purged. Listings that were
+// never bought are deleted; the user row, chats, purchases, reviews and disputes are kept for
+// the other party, with the user anonymized. It returns sql.ErrNoRows if the account isn't due
+// or another instance is purging it.
+func (r *Repository) PurgeAccount(userID int) (*model.PurgedAccount, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var due int
+	err = tx.Get(&due, `
+		SELECT user_id FROM account_deletions
+		WHERE user_id = $1 AND purged_at IS NULL AND purge_after <= NOW()
+		FOR UPDATE SKIP LOCKED
+	`, userID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error locking account deletion: %v", err)
+		}
+		return nil, fmt.Errorf("error locking account deletion: %w", err)
+	}
+
+	purged := model.PurgedAccount{UserID: userID}
+	var avatar string
+	err = tx.QueryRow("SELECT email, COALESCE(avatar, '') FROM users WHERE id = $1", userID).Scan(&purged.Email, &avatar)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+	if avatar != "" {
+		purged.Files = append(purged.Files, avatar)
+	}
+
+	// Bought listings stay with their purchases; chats about deleted ones lose the listing
+	var listingImages []string
+	err = tx.Select(&listingImages, `
+		DELETE FROM listing_images li
+		USING listings l
+		WHERE li.listing_id = l.id AND l.user_id = $1
+		  AND NOT EXISTS (SELECT 1 FROM purchases p WHERE p.listing_id = l.id)
+		RETURNING li.image_path
+	`, userID)
+	if err != nil {
+		log.Printf("Error deleting listing images: %v", err)
+		return nil, fmt.Errorf("error deleting listing images: %w", err)
+	}
+	purged.Files = append(purged.Files, listingImages...)
+
+	_, err = tx.Exec(`
+		DELETE FROM listings l
+		WHERE l.user_id = $1 AND NOT EXISTS (SELECT 1 FROM purchases p WHERE p.listing_id = l.id)
+	`, userID)
+	if err != nil {
+		log.Printf("Error deleting listings: %v", err)
+		return nil, fmt.Errorf("error deleting listings: %w", err)
+	}
+
+	// Attachments uploaded but never sent are seen by nobody else
+	var attachments []string
+	err = tx.Select(&attachments, `
+		DELETE FROM message_attachments WHERE uploader_id = $1 AND message_id IS NULL RETURNING file_path
+	`, userID)
+	if err != nil {
+		log.Printf("Error deleting unsent attachments: %v", err)
+		return nil, fmt.Errorf("error deleting unsent attachments: %w", err)
+	}
+	purged.Files = append(purged.Files, attachments...)
+
+	personalData := []string{
+		"DELETE FROM favorites WHERE user_id = $1",
+		"DELETE FROM user_blocks WHERE blocker_id = $1 OR blocked_id = $1",
+		"DELETE FROM chat_user_states WHERE user_id = $1",
+		"DELETE FROM sessions WHERE user_id = $1",
+		"DELETE FROM trusted_devices WHERE user_id = $1",
+		"DELETE FROM password_reset_tokens WHERE user_id = $1",
+		"DELETE FROM two_factor_codes WHERE user_id = $1",
+		"DELETE FROM totp_credentials WHERE user_id = $1",
+		"DELETE FROM recovery_codes WHERE user_id = $1",
+		"DELETE FROM user_identities WHERE user_id = $1",
+		"DELETE FROM email_changes WHERE user_id = $1",
+		"DELETE FROM message_screening_events WHERE sender_id = $1",
+	}
+	for _, query := range personalData {
+		if _, err := tx.Exec(query, userID); err != nil {
+			log.Printf("Error erasing personal data: %v", err)
+			return nil, fmt.Errorf("error erasing personal data: %w", err)
+		}
+	}
+
+	_, err = tx.Exec("DELETE FROM auth_failures WHERE email = $1", purged.Email)
+	if err != nil {
+		log.Printf("Error erasing personal data: %v", err)
+		return nil, fmt.Errorf("error erasing personal data: %w", err)
+	}
+
+	// An empty password hash matches no password, so nobody can log in as the purged user
+	_, err = tx.Exec(`
+		UPDATE users
+		SET email = 'deleted-' || id || '@deleted.invalid', password_hash = '',
+		    name = $2, last_name = '', city = '', avatar = '',
+		    is_verified = FALSE, failed_login_attempts = 0, locked_until = NULL,
+		    two_factor_method = 'email', role = 'user'
+		WHERE id = $1
+	`, userID, model.DeletedUserName)
+	if err != nil {
+		log.Printf("Error anonymizing user: %v", err)
+		return nil, fmt.Errorf("error anonymizing user: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE account_deletions SET purged_at = NOW(), hidden_listing_ids = '{}' WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		log.Printf("Error marking account as purged: %v", err)
+		return nil, fmt.Errorf("error marking account as purged: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return nil, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return &purged, nil
+}
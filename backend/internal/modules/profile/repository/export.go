@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/profile/model"
+	"fmt"
+	"log"
+)
+
+// GetExportFavorites gets the favorite listings of a user for a data export
+func (r *Repository) GetExportFavorites(userID int) ([]model.ExportFavorite, error) {
+	favorites := []model.ExportFavorite{}
+	err := r.db.Select(&favorites, `
+		SELECT f.listing_id, l.title as listing_title, f.created_at
+		FROM favorites f
+		JOIN listings l ON l.id = f.listing_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting favorites for export: %v", err)
+		return nil, fmt.Errorf("error getting favorites for export: %w", err)
+	}
+	return favorites, nil
+}
+
+// GetExportChats gets the chats of a user with their messages and the attachments the user
+// uploaded for a data export. Deleted messages and messages held from the user are left out.
+func (r *Repository) GetExportChats(userID int) ([]model.ExportChat, error) {
+	chats := []model.ExportChat{}
+	err := r.db.Select(&chats, `
+		SELECT c.id, c.listing_id, COALESCE(l.title, '') as listing_title, c.buyer_id, c.seller_id, c.created_at
+		FROM chats c
+		LEFT JOIN listings l ON l.id = c.listing_id
+		WHERE c.buyer_id = $1 OR c.seller_id = $1
+		ORDER BY c.created_at
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting chats for export: %v", err)
+		return nil, fmt.Errorf("error getting chats for export: %w", err)
+	}
+
+	var messages []model.ExportMessage
+	err = r.db.Select(&messages, `
+		SELECT m.id, m.chat_id, m.user_id, m.kind, m.content, m.reply_to_id, m.created_at, m.edited_at
+		FROM messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE (c.buyer_id = $1 OR c.seller_id = $1)
+		  AND m.deleted_at IS NULL AND (NOT m.held OR m.user_id = $1)
+		ORDER BY m.id
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting messages for export: %v", err)
+		return nil, fmt.Errorf("error getting messages for export: %w", err)
+	}
+
+	var attachments []model.ExportAttachment
+	err = r.db.Select(&attachments, `
+		SELECT id, chat_id, message_id, file_path, file_name, content_type, size, created_at
+		FROM message_attachments
+		WHERE uploader_id = $1
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting attachments for export: %v", err)
+		return nil, fmt.Errorf("error getting attachments for export: %w", err)
+	}
+
+	byID := make(map[int]*model.ExportChat, len(chats))
+	for i := range chats {
+		chats[i].Messages = []model.ExportMessage{}
+		chats[i].Attachments = []model.ExportAttachment{}
+		byID[chats[i].ID] = &chats[i]
+	}
+	for _, message := range messages {
+		if chat, ok := byID[message.ChatID]; ok {
+			chat.Messages = append(chat.Messages, message)
+		}
+	}
+	for _, attachment := range attachments {
+		if chat, ok := byID[attachment.ChatID]; ok {
+			chat.Attachments = append(chat.Attachments, attachment)
+		}
+	}
+
+	return chats, nil
+}
+
+// GetExportPurchases gets the purchases and sales of a user for a data export
+func (r *Repository) GetExportPurchases(userID int) ([]model.ExportPurchase, error) {
+	purchases := []model.ExportPurchase{}
+	err := r.db.Select(&purchases, `
+		SELECT p.id, CASE WHEN p.buyer_id = $1 THEN 'buyer' ELSE 'seller' END as role,
+		       p.listing_id, COALESCE(l.title, '') as listing_title, p.buyer_id, p.seller_id,
+		       p.price, p.status, p.delivery_option, p.delivery_fee, p.delivery_address,
+		       p.purchased_at, p.handed_over_at, p.cancelled_at, p.cancel_reason
+		FROM purchases p
+		LEFT JOIN listings l ON l.id = p.listing_id
+		WHERE p.buyer_id = $1 OR p.seller_id = $1
+		ORDER BY p.purchased_at
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting purchases for export: %v", err)
+		return nil, fmt.Errorf("error getting purchases for export: %w", err)
+	}
+	return purchases, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/profile/model"
+	"FurniSwap/pkg/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// Account deletion settings
+const (
+	// AccountDeletionGracePeriod is how long a deleted account can be restored by logging in
+	AccountDeletionGracePeriod = 30 * 24 * time.Hour
+	// accountPurgeBatch is how many accounts are purged in one run
+	accountPurgeBatch = 50
+)
+
+// DeleteAccount deletes the account of a user who confirms it with the password, an authenticator
+// code or a fresh provider login. The account is deactivated right away and purged after the grace
+// period unless the user logs in before.
+func (s *Service) DeleteAccount(userID, sessionID int, req model.DeleteAccountRequest, ip string) (*model.AccountDeletionResponse, error) {
+	// Accounts created with a provider only have a random password
+	if err := s.auth.ConfirmIdentity(userID, sessionID, req.Password, req.Code, ip); err != nil {
+		return nil, err
+	}
+
+	// The other party of an unfinished deal still needs to reach the user
+	unfinished, err := s.repo.HasUnfinishedPurchases(userID)
+	if err != nil {
+		return nil, err
+	}
+	if unfinished {
+		return nil, errors.New("account has unfinished purchases")
+	}
+
+	profile, err := s.repo.GetProfileByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	purgeAfter := time.Now().Add(AccountDeletionGracePeriod)
+	if err := s.repo.ScheduleAccountDeletion(userID, purgeAfter); err != nil {
+		return nil, err
+	}
+
+	body := "Your FurniSwap account was deleted on " + time.Now().Format("02.01.2006 15:04") + ".\n\n" +
+		"Your listings are hidden and you were logged out everywhere. Your data will be erased for good on " +
+		purgeAfter.Format("02.01.2006") + ".\n\n" +
+		"Changed your mind? Log in before then and your account will be restored."
+	if err := utils.SendEmail(profile.Email, "Account Deleted", body); err != nil {
+		log.Printf("Error sending account deletion notification: %v", err)
+	}
+
+	return &model.AccountDeletionResponse{
+		Message:    "Account deleted. Log in before purge_after to restore it.",
+		PurgeAfter: purgeAfter,
+	}, nil
+}
+
+// PurgeDueAccounts erases the data of the accounts whose grace period is over
+func (s *Service) PurgeDueAccounts() error {
+	userIDs, err := s.repo.GetDueAccountDeletions(accountPurgeBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		purged, err := s.repo.PurgeAccount(userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Restored in the meantime or purged by another instance
+				continue
+			}
+			log.Printf("Error purging account of user %d: %v", userID, err)
+			continue
+		}
+
+		// Files are removed once nothing refers to them anymore
+		for _, file := range purged.Files {
+			if s.IsAvatarURL(file) {
+				continue
+			}
+			if err := utils.DeleteFile(file); err != nil {
+				log.Printf("Error deleting file of purged user %d: %v", userID, err)
+			}
+		}
+
+		body := "The data of your FurniSwap account has been erased as you requested.\n\n" +
+			"Chats, purchases and reviews you took part in are kept for the other users, without your name."
+		if err := utils.SendEmail(purged.Email, "Account Erased", body); err != nil {
+			log.Printf("Error sending account purge notification: %v", err)
+		}
+		log.Printf("Purged account of user %d", userID)
+	}
+
+	return nil
+}
+
+// StartAccountPurge purges due accounts now and then every interval until the returned stop function is called
+func (s *Service) StartAccountPurge(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := s.PurgeDueAccounts(); err != nil {
+				log.Printf("Error purging deleted accounts: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/profile/model"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ExportData gets the personal data of a user: the profile, listings, favorites, chats and
+// purchases
+func (s *Service) ExportData(userID int) (*model.Export, error) {
+	var export model.Export
+	var err error
+
+	if export.Profile, err = s.repo.GetProfileByID(userID); err != nil {
+		return nil, err
+	}
+	if export.Listings, err = s.listingRepo.GetUserListings(userID); err != nil {
+		return nil, err
+	}
+	if export.Favorites, err = s.repo.GetExportFavorites(userID); err != nil {
+		return nil, err
+	}
+	if export.Chats, err = s.repo.GetExportChats(userID); err != nil {
+		return nil, err
+	}
+	if export.Purchases, err = s.repo.GetExportPurchases(userID); err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// WriteExport streams an export to w as a ZIP archive: the data as JSON files, and the
+// uploaded images and attachments under uploads/ with the paths the JSON files refer to
+func (s *Service) WriteExport(w io.Writer, export *model.Export) error {
+	archive := zip.NewWriter(w)
+
+	documents := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"listings.json", export.Listings},
+		{"favorites.json", export.Favorites},
+		{"chats.json", export.Chats},
+		{"purchases.json", export.Purchases},
+	}
+	for _, document := range documents {
+		if err := writeJSON(archive, document.name, document.data); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range exportFiles(export) {
+		if s.IsAvatarURL(file) {
+			continue
+		}
+		if err := addUpload(archive, file); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing export archive: %w", err)
+	}
+	return nil
+}
+
+// exportFiles lists the uploaded files an export refers to, each once
+func exportFiles(export *model.Export) []string {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(file string) {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	add(export.Profile.Avatar)
+	for _, listing := range export.Listings {
+		for _, image := range listing.Images {
+			add(image.ImagePath)
+		}
+	}
+	for _, chat := range export.Chats {
+		for _, attachment := range chat.Attachments {
+			add(attachment.FilePath)
+		}
+	}
+	return files
+}
+
+// writeJSON adds an indented JSON document to the archive
+func writeJSON(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("error adding %s to export archive: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("error encoding %s: %w", name, err)
+	}
+	return nil
+}
+
+// addUpload copies an uploaded file into the archive; missing files are skipped
+func addUpload(archive *zip.Writer, uploadPath string) error {
+	src, err := os.Open(filepath.Join("uploads", uploadPath))
+	if err != nil {
+		log.Printf("Error opening uploaded file %s for export: %v", uploadPath, err)
+		return nil
+	}
+	defer src.Close()
+
+	w, err := archive.Create(path.Join("uploads", filepath.ToSlash(uploadPath)))
+	if err != nil {
+		return fmt.Errorf("error adding %s to export archive: %w", uploadPath, err)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf("error copying %s to export archive: %w", uploadPath, err)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	authService "FurniSwap/internal/modules/auth/service"
+	listingRepo "FurniSwap/internal/modules/listing/repository"
 	"FurniSwap/internal/modules/profile/model"
 	"FurniSwap/internal/modules/profile/repository"
 	"FurniSwap/pkg/utils"
@@ -14,13 +16,17 @@ import (
 
 // Service provides profile operations
 type Service struct {
-	repo *repository.Repository
+	repo        *repository.Repository
+	listingRepo *listingRepo.Repository
+	auth        *authService.Service
 }
 
 // NewService creates a new profile service
-func NewService(repo *repository.Repository) *Service {
+func NewService(repo *repository.Repository, listingRepo *listingRepo.Repository, auth *authService.Service) *Service {
 	return &Service{
-		repo: repo,
+		repo:        repo,
+		listingRepo: listingRepo,
+		auth:        auth,
 	}
 }
 
@@ -3,9 +3,11 @@ package handler
 import (
 	"FurniSwap/internal/modules/purchase/model"
 	"FurniSwap/internal/modules/purchase/service"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,6 +29,13 @@ func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/listings/:id/buy", h.BuyListing)
 	router.GET("/purchases", h.GetUserPurchases)
 	router.GET("/sales", h.GetUserSales)
+	router.POST("/purchases/:id/cancel", h.CancelPurchase)
+	router.POST("/purchases/:id/confirm", h.ConfirmHandover)
+	router.GET("/purchases/:id/receipt", h.GetPurchaseReceipt)
+	router.GET("/sales/:id/receipt", h.GetSaleReceipt)
+	router.GET("/purchases/export", h.ExportPurchases)
+	router.GET("/sales/export", h.ExportSales)
+	router.GET("/sales/summary", h.GetSalesSummary)
 }
 
 // BuyListing handles buying a listing
@@ -45,10 +54,17 @@ func (h *Handler) BuyListing(c *gin.Context) {
 		return
 	}
 
-	// Create buy request
+	// Create buy request; the body with the delivery choice is optional
 	buyReq := model.BuyRequest{
 		ListingID: listingID,
 	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&buyReq); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+		buyReq.ListingID = listingID
+	}
 
 	// Buy listing
 	purchaseID, err := h.service.BuyListing(userID.(int), buyReq)
@@ -65,6 +81,18 @@ func (h *Handler) BuyListing(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot buy your own listing"})
 			return
 		}
+		if err.Error() == "self-pickup is not available for this listing" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Self-pickup is not available for this listing"})
+			return
+		}
+		if err.Error() == "delivery is not available for this listing" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Delivery is not available for this listing"})
+			return
+		}
+		if err.Error() == "delivery address is required" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Delivery address is required"})
+			return
+		}
 		log.Printf("Error buying listing: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error buying listing"})
 		return
@@ -142,3 +170,249 @@ func (h *Handler) GetUserSales(c *gin.Context) {
 
 	c.JSON(http.StatusOK, sales)
 }
+
+// CancelPurchase handles cancelling a purchase before handover
+func (h *Handler) CancelPurchase(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// The cancellation reason is optional
+	var req model.CancelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+	}
+
+	// Cancel purchase
+	err = h.service.CancelPurchase(purchaseID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		if err.Error() == "you are not a participant of this purchase" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this purchase"})
+			return
+		}
+		if err.Error() == "purchase can only be cancelled before handover" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase can only be cancelled before handover"})
+			return
+		}
+		if err.Error() == "cancellation window has expired" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cancellation window has expired"})
+			return
+		}
+		log.Printf("Error cancelling purchase: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cancelling purchase"})
+		return
+	}
+
+	// Get the updated purchase
+	purchase, err := h.service.GetPurchaseByID(purchaseID)
+	if err != nil {
+		log.Printf("Error getting cancelled purchase: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Purchase cancelled successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, purchase)
+}
+
+// ConfirmHandover handles the buyer confirming that the item was received
+func (h *Handler) ConfirmHandover(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// Confirm handover
+	err = h.service.ConfirmHandover(purchaseID, userID.(int))
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		if err.Error() == "only the buyer can confirm handover" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the buyer can confirm handover"})
+			return
+		}
+		if err.Error() == "purchase is not awaiting handover" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase is not awaiting handover"})
+			return
+		}
+		log.Printf("Error confirming handover: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error confirming handover"})
+		return
+	}
+
+	// Get the updated purchase
+	purchase, err := h.service.GetPurchaseByID(purchaseID)
+	if err != nil {
+		log.Printf("Error getting confirmed purchase: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Handover confirmed successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, purchase)
+}
+
+// GetPurchaseReceipt handles downloading the PDF receipt of a purchase
+func (h *Handler) GetPurchaseReceipt(c *gin.Context) {
+	h.getReceipt(c, false)
+}
+
+// GetSaleReceipt handles downloading the PDF receipt of a sale
+func (h *Handler) GetSaleReceipt(c *gin.Context) {
+	h.getReceipt(c, true)
+}
+
+// getReceipt renders a receipt for the buyer or the seller
+func (h *Handler) getReceipt(c *gin.Context, asSeller bool) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// Render receipt
+	pdf, number, err := h.service.GetReceipt(purchaseID, userID.(int), asSeller)
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		log.Printf("Error generating receipt: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating receipt"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"receipt-%s.pdf\"", number))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
+
+// ExportPurchases handles exporting the full purchase history
+func (h *Handler) ExportPurchases(c *gin.Context) {
+	h.exportHistory(c, false)
+}
+
+// ExportSales handles exporting the full sales history
+func (h *Handler) ExportSales(c *gin.Context) {
+	h.exportHistory(c, true)
+}
+
+// exportHistory exports the purchase or sales history in the requested format
+func (h *Handler) exportHistory(c *gin.Context, asSeller bool) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	format := c.DefaultQuery("format", service.ExportFormatCSV)
+
+	var contentType string
+	switch format {
+	case service.ExportFormatCSV:
+		contentType = "text/csv; charset=utf-8"
+	case service.ExportFormatXLSX:
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be csv or xlsx"})
+		return
+	}
+
+	// Export history
+	data, err := h.service.ExportHistory(userID.(int), asSeller, format)
+	if err != nil {
+		log.Printf("Error exporting history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting history"})
+		return
+	}
+
+	name := "purchases"
+	if asSeller {
+		name = "sales"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.%s\"", name, format))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// GetSalesSummary handles getting the user's sales analytics
+func (h *Handler) GetSalesSummary(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse date range (YYYY-MM-DD, both inclusive)
+	var from, to *time.Time
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date, expected YYYY-MM-DD"})
+			return
+		}
+		from = &date
+	}
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date, expected YYYY-MM-DD"})
+			return
+		}
+		to = &date
+	}
+
+	top, _ := strconv.Atoi(c.DefaultQuery("top", "5"))
+
+	// Get summary
+	summary, err := h.service.GetSalesSummary(userID.(int), from, to, c.Query("group_by"), top)
+	if err != nil {
+		if err.Error() == "invalid group_by" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must be day, week or month"})
+			return
+		}
+		if err.Error() == "invalid date range" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from date must not be after to date"})
+			return
+		}
+		log.Printf("Error getting sales summary: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting sales summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -18,8 +18,39 @@ type Purchase struct {
 	Listing    *model.Listing `json:"listing,omitempty"`
 	SellerName string         `db:"seller_name" json:"seller_name,omitempty"`
 	BuyerName  string         `db:"buyer_name" json:"buyer_name,omitempty"`
+
+	// Delivery option chosen by the buyer
+	DeliveryOption  string  `db:"delivery_option" json:"delivery_option"`
+	DeliveryFee     float64 `db:"delivery_fee" json:"delivery_fee"`
+	DeliveryAddress string  `db:"delivery_address" json:"delivery_address,omitempty"`
+
+	// Handover and cancellation details
+	HandedOverAt *time.Time `db:"handed_over_at" json:"handed_over_at,omitempty"`
+	CancelledAt  *time.Time `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	CancelledBy  *int       `db:"cancelled_by" json:"cancelled_by,omitempty"`
+	CancelReason string     `db:"cancel_reason" json:"cancel_reason,omitempty"`
 }
 
+// Purchase statuses
+const (
+	// StatusPending means the item has not been handed over yet
+	StatusPending = "pending"
+	// StatusCompleted means the buyer confirmed receiving the item
+	StatusCompleted = "completed"
+	// StatusCancelled means the purchase was cancelled before handover
+	StatusCancelled = "cancelled"
+	// StatusDisputed means one of the parties opened a dispute
+	StatusDisputed = "disputed"
+	// StatusRefunded means a moderator resolved a dispute in the buyer's favor
+	StatusRefunded = "refunded"
+)
+
+// Delivery options a buyer can choose when buying a listing
+const (
+	DeliveryOptionPickup   = "pickup"
+	DeliveryOptionDelivery = "delivery"
+)
+
 // PurchaseResponse represents a list of purchases with pagination
 type PurchaseResponse struct {
 	Purchases   []Purchase `json:"purchases"`
@@ -30,5 +61,103 @@ type PurchaseResponse struct {
 
 // BuyRequest represents the data needed to buy a listing
 type BuyRequest struct {
-	ListingID int `json:"listing_id" binding:"required"`
+	ListingID       int    `json:"listing_id" binding:"required"`
+	DeliveryOption  string `json:"delivery_option" binding:"omitempty,oneof=pickup delivery"`
+	DeliveryAddress string `json:"delivery_address"`
+}
+
+// CancelRequest represents the data needed to cancel a purchase
+type CancelRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// ReceiptData represents the purchase details printed on a receipt
+type ReceiptData struct {
+	PurchaseID      int       `db:"id"`
+	PurchasedAt     time.Time `db:"purchased_at"`
+	Price           float64   `db:"price"`
+	Status          string    `db:"status"`
+	DeliveryOption  string    `db:"delivery_option"`
+	DeliveryFee     float64   `db:"delivery_fee"`
+	DeliveryAddress string    `db:"delivery_address"`
+	BuyerID         int       `db:"buyer_id"`
+	BuyerName       string    `db:"buyer_name"`
+	BuyerEmail      string    `db:"buyer_email"`
+	SellerID        int       `db:"seller_id"`
+	SellerName      string    `db:"seller_name"`
+	SellerEmail     string    `db:"seller_email"`
+	ListingID       int       `db:"listing_id"`
+	ListingTitle    string    `db:"listing_title"`
+	Condition       string    `db:"condition"`
+	City            string    `db:"city"`
+}
+
+// HistoryRecord represents a single row of the exported purchase or sales history
+type HistoryRecord struct {
+	PurchaseID      int       `db:"id"`
+	PurchasedAt     time.Time `db:"purchased_at"`
+	ListingID       int       `db:"listing_id"`
+	ListingTitle    string    `db:"listing_title"`
+	CounterpartName string    `db:"counterpart_name"`
+	Price           float64   `db:"price"`
+	DeliveryOption  string    `db:"delivery_option"`
+	DeliveryFee     float64   `db:"delivery_fee"`
+	Status          string    `db:"status"`
+}
+
+// Sales summary grouping periods
+const (
+	GroupByDay   = "day"
+	GroupByWeek  = "week"
+	GroupByMonth = "month"
+)
+
+// SalesSummary represents the seller's sales analytics for a date range
+type SalesSummary struct {
+	From        time.Time         `json:"from"`
+	To          time.Time         `json:"to"`
+	GroupBy     string            `json:"group_by"`
+	Totals      SalesStats        `json:"totals"`
+	Periods     []PeriodStats     `json:"periods"`
+	Categories  []CategoryStats   `json:"categories"`
+	TimeToSell  TimeToSellStats   `json:"time_to_sell"`
+	TopListings []TopListingStats `json:"top_listings"`
+}
+
+// SalesStats represents aggregated revenue figures
+type SalesStats struct {
+	Revenue      float64 `db:"revenue" json:"revenue"`
+	SalesCount   int     `db:"sales_count" json:"sales_count"`
+	AveragePrice float64 `db:"average_price" json:"average_price"`
+}
+
+// PeriodStats represents revenue figures for a day, week or month
+type PeriodStats struct {
+	Period time.Time `db:"period" json:"period"`
+	SalesStats
+}
+
+// CategoryStats represents revenue figures for a category
+type CategoryStats struct {
+	CategoryID   *int   `db:"category_id" json:"category_id"`
+	CategoryName string `db:"category_name" json:"category_name"`
+	SalesStats
+}
+
+// TimeToSellStats represents how long listings took to sell, in days
+type TimeToSellStats struct {
+	AverageDays float64 `db:"average_days" json:"average_days"`
+	MedianDays  float64 `db:"median_days" json:"median_days"`
+	MinDays     float64 `db:"min_days" json:"min_days"`
+	MaxDays     float64 `db:"max_days" json:"max_days"`
+}
+
+// TopListingStats represents one of the seller's best-performing listings
+type TopListingStats struct {
+	PurchaseID  int       `db:"id" json:"purchase_id"`
+	ListingID   int       `db:"listing_id" json:"listing_id"`
+	Title       string    `db:"title" json:"title"`
+	Price       float64   `db:"price" json:"price"`
+	PurchasedAt time.Time `db:"purchased_at" json:"purchased_at"`
+	DaysToSell  float64   `db:"days_to_sell" json:"days_to_sell"`
 }
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"FurniSwap/internal/modules/purchase/model"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -23,13 +25,13 @@ func NewRepository(db *sqlx.DB) *Repository {
 }
 
 // CreatePurchase creates a new purchase
-func (r *Repository) CreatePurchase(userID, listingID, sellerID int, price float64) (int, error) {
+func (r *Repository) CreatePurchase(userID, listingID, sellerID int, price float64, deliveryOption string, deliveryFee float64, deliveryAddress string) (int, error) {
 	var purchaseID int
 	err := r.db.QueryRow(`
-		INSERT INTO purchases (buyer_id, listing_id, seller_id, price, purchased_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO purchases (buyer_id, listing_id, seller_id, price, purchased_at, delivery_option, delivery_fee, delivery_address, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
-	`, userID, listingID, sellerID, price, time.Now()).Scan(&purchaseID)
+	`, userID, listingID, sellerID, price, time.Now(), deliveryOption, deliveryFee, deliveryAddress, model.StatusPending).Scan(&purchaseID)
 
 	if err != nil {
 		log.Printf("Error creating purchase: %v", err)
@@ -44,7 +46,9 @@ func (r *Repository) GetPurchaseByID(purchaseID int) (*model.Purchase, error) {
 	var purchase model.Purchase
 	err := r.db.Get(&purchase, `
 		SELECT p.id, p.buyer_id as user_id, p.listing_id, p.seller_id, p.price, 
-		       p.purchased_at as created_at, p.purchased_at as updated_at, 
+		       p.purchased_at as created_at, p.purchased_at as updated_at,
+		       p.delivery_option, p.delivery_fee, p.delivery_address,
+		       p.status, p.handed_over_at, p.cancelled_at, p.cancelled_by, p.cancel_reason,
 			   u1.name || ' ' || COALESCE(u1.last_name, '') as buyer_name,
 			   u2.name || ' ' || COALESCE(u2.last_name, '') as seller_name
 		FROM purchases p
@@ -79,7 +83,9 @@ func (r *Repository) GetUserPurchases(userID, page, limit int) (*model.PurchaseR
 	var purchases []model.Purchase
 	err = r.db.Select(&purchases, `
 		SELECT p.id, p.buyer_id as user_id, p.listing_id, p.seller_id, p.price, 
-		       p.purchased_at as created_at, p.purchased_at as updated_at, 
+		       p.purchased_at as created_at, p.purchased_at as updated_at,
+		       p.delivery_option, p.delivery_fee, p.delivery_address,
+		       p.status, p.handed_over_at, p.cancelled_at, p.cancelled_by, p.cancel_reason,
 			   u.name || ' ' || COALESCE(u.last_name, '') as seller_name
 		FROM purchases p
 		JOIN users u ON p.seller_id = u.id
@@ -120,7 +126,9 @@ func (r *Repository) GetUserSales(userID, page, limit int) (*model.PurchaseRespo
 	var purchases []model.Purchase
 	err = r.db.Select(&purchases, `
 		SELECT p.id, p.buyer_id as user_id, p.listing_id, p.seller_id, p.price, 
-		       p.purchased_at as created_at, p.purchased_at as updated_at, 
+		       p.purchased_at as created_at, p.purchased_at as updated_at,
+		       p.delivery_option, p.delivery_fee, p.delivery_address,
+		       p.status, p.handed_over_at, p.cancelled_at, p.cancelled_by, p.cancel_reason,
 			   u.name || ' ' || COALESCE(u.last_name, '') as buyer_name
 		FROM purchases p
 		JOIN users u ON p.buyer_id = u.id
@@ -140,3 +148,268 @@ func (r *Repository) GetUserSales(userID, page, limit int) (*model.PurchaseRespo
 		TotalPages:  totalPages,
 	}, nil
 }
+
+// CancelPurchase cancels a pending purchase and puts its listing back on sale in one transaction;
+// it returns false if the purchase is no longer pending
+func (r *Repository) CancelPurchase(purchaseID, userID int, reason string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var listingID int
+	err = tx.Get(&listingID, `
+		UPDATE purchases
+		SET status = $1, cancelled_at = $2, cancelled_by = $3, cancel_reason = $4
+		WHERE id = $5 AND status = $6
+		RETURNING listing_id
+	`, model.StatusCancelled, time.Now(), userID, reason, purchaseID, model.StatusPending)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		log.Printf("Error cancelling purchase: %v", err)
+		return false, fmt.Errorf("error cancelling purchase: %w", err)
+	}
+
+	if err := reactivateListing(tx, listingID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return true, nil
+}
+
+// SettleDisputedPurchase applies the resolution of a dispute to its purchase within the caller's
+// transaction. A refunded purchase that was never handed over puts the listing back on sale; a
+// rejected dispute returns the purchase to the state it was in before the dispute.
+func SettleDisputedPurchase(tx *sqlx.Tx, purchaseID int, refund bool) error {
+	if !refund {
+		_, err := tx.Exec(`
+			UPDATE purchases
+			SET status = CASE WHEN handed_over_at IS NULL THEN $1 ELSE $2 END
+			WHERE id = $3
+		`, model.StatusPending, model.StatusCompleted, purchaseID)
+		if err != nil {
+			log.Printf("Error updating purchase status: %v", err)
+			return fmt.Errorf("error updating purchase status: %w", err)
+		}
+		return nil
+	}
+
+	var purchase struct {
+		ListingID  int  `db:"listing_id"`
+		HandedOver bool `db:"handed_over"`
+	}
+	err := tx.Get(&purchase, `
+		UPDATE purchases SET status = $1 WHERE id = $2
+		RETURNING listing_id, handed_over_at IS NOT NULL as handed_over
+	`, model.StatusRefunded, purchaseID)
+	if err != nil {
+		log.Printf("Error updating purchase status: %v", err)
+		return fmt.Errorf("error updating purchase status: %w", err)
+	}
+
+	// The item never left the seller, so it can be sold again
+	if !purchase.HandedOver {
+		return reactivateListing(tx, purchase.ListingID)
+	}
+	return nil
+}
+
+// reactivateListing puts the listing of a cancelled or refunded purchase back on sale
+func reactivateListing(tx *sqlx.Tx, listingID int) error {
+	_, err := tx.Exec("UPDATE listings SET status = 'active', updated_at = NOW() WHERE id = $1", listingID)
+	if err != nil {
+		log.Printf("Error reactivating listing: %v", err)
+		return fmt.Errorf("error reactivating listing: %w", err)
+	}
+	return nil
+}
+
+// ConfirmHandover marks a pending purchase as completed; it returns false if the purchase is no longer pending
+func (r *Repository) ConfirmHandover(purchaseID int) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE purchases
+		SET status = $1, handed_over_at = $2
+		WHERE id = $3 AND status = $4
+	`, model.StatusCompleted, time.Now(), purchaseID, model.StatusPending)
+	if err != nil {
+		log.Printf("Error confirming handover: %v", err)
+		return false, fmt.Errorf("error confirming handover: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// UpdatePurchaseStatus sets the status of a purchase
+func (r *Repository) UpdatePurchaseStatus(purchaseID int, status string) error {
+	_, err := r.db.Exec("UPDATE purchases SET status = $1 WHERE id = $2", status, purchaseID)
+	if err != nil {
+		log.Printf("Error updating purchase status: %v", err)
+		return fmt.Errorf("error updating purchase status: %w", err)
+	}
+	return nil
+}
+
+// GetReceiptData gets the purchase details needed to render a receipt
+func (r *Repository) GetReceiptData(purchaseID int) (*model.ReceiptData, error) {
+	var data model.ReceiptData
+	err := r.db.Get(&data, `
+		SELECT p.id, p.purchased_at, p.price, p.status, p.delivery_option, p.delivery_fee, p.delivery_address,
+		       p.buyer_id, u1.name || ' ' || COALESCE(u1.last_name, '') as buyer_name, u1.email as buyer_email,
+		       p.seller_id, u2.name || ' ' || COALESCE(u2.last_name, '') as seller_name, u2.email as seller_email,
+		       p.listing_id, COALESCE(l.title, '') as listing_title,
+		       COALESCE(l.condition, '') as condition, COALESCE(l.city, '') as city
+		FROM purchases p
+		JOIN users u1 ON p.buyer_id = u1.id
+		JOIN users u2 ON p.seller_id = u2.id
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE p.id = $1
+	`, purchaseID)
+	if err != nil {
+		log.Printf("Error getting receipt data: %v", err)
+		return nil, fmt.Errorf("error getting receipt data: %w", err)
+	}
+	return &data, nil
+}
+
+// GetPurchaseHistory gets the full purchase history of a buyer for export
+func (r *Repository) GetPurchaseHistory(userID int) ([]model.HistoryRecord, error) {
+	records := []model.HistoryRecord{}
+	err := r.db.Select(&records, `
+		SELECT p.id, p.purchased_at, p.listing_id, COALESCE(l.title, '') as listing_title,
+		       u.name || ' ' || COALESCE(u.last_name, '') as counterpart_name,
+		       p.price, p.delivery_option, p.delivery_fee, p.status
+		FROM purchases p
+		JOIN users u ON p.seller_id = u.id
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE p.buyer_id = $1
+		ORDER BY p.purchased_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting purchase history: %v", err)
+		return nil, fmt.Errorf("error getting purchase history: %w", err)
+	}
+	return records, nil
+}
+
+// GetSalesHistory gets the full sales history of a seller for export
+func (r *Repository) GetSalesHistory(userID int) ([]model.HistoryRecord, error) {
+	records := []model.HistoryRecord{}
+	err := r.db.Select(&records, `
+		SELECT p.id, p.purchased_at, p.listing_id, COALESCE(l.title, '') as listing_title,
+		       u.name || ' ' || COALESCE(u.last_name, '') as counterpart_name,
+		       p.price, p.delivery_option, p.delivery_fee, p.status
+		FROM purchases p
+		JOIN users u ON p.buyer_id = u.id
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE p.seller_id = $1
+		ORDER BY p.purchased_at DESC
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting sales history: %v", err)
+		return nil, fmt.Errorf("error getting sales history: %w", err)
+	}
+	return records, nil
+}
+
+// salesSummaryFilter selects the seller's sales in a date range, excluding cancelled and refunded purchases
+const salesSummaryFilter = `
+		p.seller_id = $1 AND p.purchased_at >= $2 AND p.purchased_at < $3
+		AND p.status NOT IN ('cancelled', 'refunded')`
+
+// GetSalesSummary aggregates the seller's sales in [from, to) grouped by day, week or month
+func (r *Repository) GetSalesSummary(sellerID int, from, to time.Time, groupBy string, topLimit int) (*model.SalesSummary, error) {
+	summary := &model.SalesSummary{
+		Periods:     []model.PeriodStats{},
+		Categories:  []model.CategoryStats{},
+		TopListings: []model.TopListingStats{},
+	}
+
+	// Totals
+	err := r.db.Get(&summary.Totals, `
+		SELECT COALESCE(SUM(p.price), 0) as revenue, COUNT(*) as sales_count,
+		       COALESCE(ROUND(AVG(p.price), 2), 0) as average_price
+		FROM purchases p
+		WHERE `+salesSummaryFilter, sellerID, from, to)
+	if err != nil {
+		log.Printf("Error getting sales totals: %v", err)
+		return nil, fmt.Errorf("error getting sales totals: %w", err)
+	}
+
+	// Revenue by period
+	err = r.db.Select(&summary.Periods, `
+		SELECT date_trunc($4, p.purchased_at) as period,
+		       SUM(p.price) as revenue, COUNT(*) as sales_count, ROUND(AVG(p.price), 2) as average_price
+		FROM purchases p
+		WHERE `+salesSummaryFilter+`
+		GROUP BY 1
+		ORDER BY 1
+	`, sellerID, from, to, groupBy)
+	if err != nil {
+		log.Printf("Error getting sales by period: %v", err)
+		return nil, fmt.Errorf("error getting sales by period: %w", err)
+	}
+
+	// Revenue by category
+	err = r.db.Select(&summary.Categories, `
+		SELECT c.id as category_id, COALESCE(c.name, '') as category_name,
+		       SUM(p.price) as revenue, COUNT(*) as sales_count, ROUND(AVG(p.price), 2) as average_price
+		FROM purchases p
+		LEFT JOIN listings l ON p.listing_id = l.id
+		LEFT JOIN categories c ON l.category_id = c.id
+		WHERE `+salesSummaryFilter+`
+		GROUP BY c.id, c.name
+		ORDER BY revenue DESC
+	`, sellerID, from, to)
+	if err != nil {
+		log.Printf("Error getting sales by category: %v", err)
+		return nil, fmt.Errorf("error getting sales by category: %w", err)
+	}
+
+	// Time to sell, from listing creation to purchase
+	err = r.db.Get(&summary.TimeToSell, `
+		SELECT COALESCE(ROUND(AVG(t.days)::numeric, 2), 0) as average_days,
+		       COALESCE(ROUND((percentile_cont(0.5) WITHIN GROUP (ORDER BY t.days))::numeric, 2), 0) as median_days,
+		       COALESCE(ROUND(MIN(t.days)::numeric, 2), 0) as min_days,
+		       COALESCE(ROUND(MAX(t.days)::numeric, 2), 0) as max_days
+		FROM (
+			SELECT EXTRACT(EPOCH FROM (p.purchased_at - l.created_at)) / 86400 as days
+			FROM purchases p
+			JOIN listings l ON p.listing_id = l.id
+			WHERE `+salesSummaryFilter+`
+		) t
+	`, sellerID, from, to)
+	if err != nil {
+		log.Printf("Error getting time to sell: %v", err)
+		return nil, fmt.Errorf("error getting time to sell: %w", err)
+	}
+
+	// Top listings by price, faster sales first on ties
+	err = r.db.Select(&summary.TopListings, `
+		SELECT p.id, p.listing_id, COALESCE(l.title, '') as title, p.price, p.purchased_at,
+		       COALESCE(ROUND((EXTRACT(EPOCH FROM (p.purchased_at - l.created_at)) / 86400)::numeric, 2), 0) as days_to_sell
+		FROM purchases p
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE `+salesSummaryFilter+`
+		ORDER BY p.price DESC, days_to_sell ASC
+		LIMIT $4
+	`, sellerID, from, to, topLimit)
+	if err != nil {
+		log.Printf("Error getting top listings: %v", err)
+		return nil, fmt.Errorf("error getting top listings: %w", err)
+	}
+
+	return summary, nil
+}
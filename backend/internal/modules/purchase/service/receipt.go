@@ -0,0 +1,183 @@
+package service
+
+import (
+	"FurniSwap/internal/modules/purchase/model"
+	"FurniSwap/pkg/utils"
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Export formats for the purchase and sales history
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// ReceiptNumber builds the unique receipt number of a purchase
+func ReceiptNumber(data *model.ReceiptData) string {
+	return fmt.Sprintf("FS-%s-%06d", data.PurchasedAt.Format("20060102"), data.PurchaseID)
+}
+
+// GetReceipt renders a PDF receipt for the buyer (asSeller=false) or the seller (asSeller=true) of a purchase
+func (s *Service) GetReceipt(purchaseID, userID int, asSeller bool) ([]byte, string, error) {
+	data, err := s.repo.GetReceiptData(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", errors.New("purchase not found")
+		}
+		return nil, "", fmt.Errorf("error getting receipt data: %w", err)
+	}
+
+	// Buyers get receipts for their purchases, sellers for their sales
+	if (!asSeller && data.BuyerID != userID) || (asSeller && data.SellerID != userID) {
+		return nil, "", errors.New("purchase not found")
+	}
+
+	number := ReceiptNumber(data)
+
+	doc := utils.NewPDFDocument()
+	doc.AddText("FurniSwap", 20, true)
+	if asSeller {
+		doc.AddText("Sales receipt", 14, false)
+	} else {
+		doc.AddText("Purchase receipt", 14, false)
+	}
+	doc.AddSpace(10)
+
+	doc.AddField("Receipt number:", number, 11)
+	doc.AddField("Date:", data.PurchasedAt.Format("02.01.2006 15:04"), 11)
+	doc.AddField("Status:", data.Status, 11)
+	doc.AddSpace(10)
+
+	doc.AddText("Seller", 12, true)
+	doc.AddField("Name:", data.SellerName, 11)
+	doc.AddField("Email:", data.SellerEmail, 11)
+	doc.AddSpace(6)
+
+	doc.AddText("Buyer", 12, true)
+	doc.AddField("Name:", data.BuyerName, 11)
+	doc.AddField("Email:", data.BuyerEmail, 11)
+	doc.AddSpace(10)
+
+	doc.AddText("Item", 12, true)
+	doc.AddField("Listing:", fmt.Sprintf("%s (#%d)", data.ListingTitle, data.ListingID), 11)
+	if data.Condition != "" {
+		doc.AddField("Condition:", data.Condition, 11)
+	}
+	if data.City != "" {
+		doc.AddField("City:", data.City, 11)
+	}
+	doc.AddField("Delivery:", data.DeliveryOption, 11)
+	if data.DeliveryAddress != "" {
+		doc.AddField("Delivery address:", data.DeliveryAddress, 11)
+	}
+	doc.AddSpace(10)
+
+	doc.AddField("Price:", formatAmount(data.Price), 11)
+	doc.AddField("Delivery fee:", formatAmount(data.DeliveryFee), 11)
+	doc.AddField("Total:", formatAmount(data.Price+data.DeliveryFee), 12)
+	doc.AddSpace(20)
+
+	doc.AddText("This receipt was generated automatically by FurniSwap.", 9, false)
+
+	return doc.Bytes(), number, nil
+}
+
+// ExportHistory exports the full purchase (asSeller=false) or sales (asSeller=true) history as CSV or XLSX
+func (s *Service) ExportHistory(userID int, asSeller bool, format string) ([]byte, error) {
+	var records []model.HistoryRecord
+	var err error
+	counterpart := "Seller"
+	if asSeller {
+		records, err = s.repo.GetSalesHistory(userID)
+		counterpart = "Buyer"
+	} else {
+		records, err = s.repo.GetPurchaseHistory(userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := []string{"Purchase ID", "Date", "Listing ID", "Listing", counterpart, "Price", "Delivery option", "Delivery fee", "Status"}
+
+	var buf bytes.Buffer
+	switch format {
+	case ExportFormatCSV:
+		w := csv.NewWriter(&buf)
+		if err := w.Write(header); err != nil {
+			return nil, fmt.Errorf("error writing CSV: %w", err)
+		}
+		for _, r := range records {
+			err := w.Write([]string{
+				strconv.Itoa(r.PurchaseID),
+				r.PurchasedAt.Format("2006-01-02 15:04:05"),
+				strconv.Itoa(r.ListingID),
+				csvText(r.ListingTitle),
+				csvText(r.CounterpartName),
+				strconv.FormatFloat(r.Price, 'f', 2, 64),
+				r.DeliveryOption,
+				strconv.FormatFloat(r.DeliveryFee, 'f', 2, 64),
+				r.Status,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error writing CSV: %w", err)
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, fmt.Errorf("error writing CSV: %w", err)
+		}
+	case ExportFormatXLSX:
+		rows := make([][]interface{}, 0, len(records)+1)
+		headerRow := make([]interface{}, len(header))
+		for i, h := range header {
+			headerRow[i] = h
+		}
+		rows = append(rows, headerRow)
+		for _, r := range records {
+			rows = append(rows, []interface{}{
+				r.PurchaseID,
+				r.PurchasedAt.Format("2006-01-02 15:04:05"),
+				r.ListingID,
+				r.ListingTitle,
+				r.CounterpartName,
+				r.Price,
+				r.DeliveryOption,
+				r.DeliveryFee,
+				r.Status,
+			})
+		}
+
+		sheetName := "Purchases"
+		if asSeller {
+			sheetName = "Sales"
+		}
+		if err := utils.WriteXLSX(&buf, sheetName, rows); err != nil {
+			return nil, fmt.Errorf("error writing XLSX: %w", err)
+		}
+	default:
+		return nil, errors.New("unsupported export format")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// csvText makes user-entered text safe to open in a spreadsheet: a cell starting with a formula
+// character is prefixed with an apostrophe, so a listing titled "=HYPERLINK(...)" stays text.
+// XLSX cells are stored as inline strings and are never evaluated, so they need no escaping.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatAmount formats a price in rubles
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64) + " RUB"
+}
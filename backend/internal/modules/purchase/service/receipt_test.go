@@ -0,0 +1,25 @@
+package service
+
+import "testing"
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Oak wardrobe", "Oak wardrobe"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+7 900 000-00-00", "'+7 900 000-00-00"},
+		{"-1+2", "'-1+2"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+2", "'\t=1+2"},
+		{"\r=1+2", "'\r=1+2"},
+		{"Sofa =1+2", "Sofa =1+2"},
+	}
+	for _, tt := range tests {
+		if got := csvText(tt.value); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	chatService "FurniSwap/internal/modules/chat/service"
 	listingModel "FurniSwap/internal/modules/listing/model"
 	"FurniSwap/internal/modules/listing/repository"
 	listingRepo "FurniSwap/internal/modules/listing/repository"
@@ -10,19 +11,30 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
+)
+
+// Cancellation windows, counted from the moment of purchase
+const (
+	// BuyerCancelWindow is how long the buyer can cancel a purchase
+	BuyerCancelWindow = 24 * time.Hour
+	// SellerCancelWindow is how long the seller can cancel a purchase
+	SellerCancelWindow = 72 * time.Hour
 )
 
 // Service provides purchase operations
 type Service struct {
 	repo        *purchaseRepo.Repository
 	listingRepo *listingRepo.Repository
+	chats       *chatService.Service
 }
 
 // NewService creates a new purchase service
-func NewService(repo *purchaseRepo.Repository, listingRepo *repository.Repository) *Service {
+func NewService(repo *purchaseRepo.Repository, listingRepo *repository.Repository, chats *chatService.Service) *Service {
 	return &Service{
 		repo:        repo,
 		listingRepo: listingRepo,
+		chats:       chats,
 	}
 }
 
@@ -50,8 +62,38 @@ func (s *Service) BuyListing(userID int, req model.BuyRequest) (int, error) {
 		return 0, errors.New("you cannot buy your own listing")
 	}
 
+	// Resolve the delivery option; default to whatever the listing offers
+	deliveryOption := req.DeliveryOption
+	if deliveryOption == "" {
+		if listing.SelfPickup {
+			deliveryOption = model.DeliveryOptionPickup
+		} else {
+			deliveryOption = model.DeliveryOptionDelivery
+		}
+	}
+
+	var deliveryFee float64
+	var deliveryAddress string
+	switch deliveryOption {
+	case model.DeliveryOptionPickup:
+		if !listing.SelfPickup {
+			return 0, errors.New("self-pickup is not available for this listing")
+		}
+	case model.DeliveryOptionDelivery:
+		if !listing.SellerDelivery {
+			return 0, errors.New("delivery is not available for this listing")
+		}
+		if req.DeliveryAddress == "" {
+			return 0, errors.New("delivery address is required")
+		}
+		deliveryFee = listing.DeliveryFee
+		deliveryAddress = req.DeliveryAddress
+	default:
+		return 0, errors.New("invalid delivery option")
+	}
+
 	// Create purchase record
-	purchaseID, err := s.repo.CreatePurchase(userID, listing.ID, listing.UserID, listing.Price)
+	purchaseID, err := s.repo.CreatePurchase(userID, listing.ID, listing.UserID, listing.Price, deliveryOption, deliveryFee, deliveryAddress)
 	if err != nil {
 		log.Printf("Error creating purchase for listing %d: %v", req.ListingID, err)
 		return 0, fmt.Errorf("error creating purchase: %w", err)
@@ -67,9 +109,80 @@ func (s *Service) BuyListing(userID int, req model.BuyRequest) (int, error) {
 		return 0, fmt.Errorf("error updating listing status: %w", err)
 	}
 
+	// Let the buyer and the other interested users know the listing is sold
+	if err := s.chats.ListingSold(listing.ID, listing.UserID, listing.Title, userID); err != nil {
+		log.Printf("Error posting sale of listing %d to chats: %v", listing.ID, err)
+	}
+
 	return purchaseID, nil
 }
 
+// CancelPurchase cancels a purchase before handover and puts the listing back on sale
+func (s *Service) CancelPurchase(purchaseID, userID int, req model.CancelRequest) error {
+	purchase, err := s.repo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("purchase not found")
+		}
+		return fmt.Errorf("error getting purchase: %w", err)
+	}
+
+	// Each party has its own cancellation window
+	var window time.Duration
+	switch userID {
+	case purchase.UserID:
+		window = BuyerCancelWindow
+	case purchase.SellerID:
+		window = SellerCancelWindow
+	default:
+		return errors.New("you are not a participant of this purchase")
+	}
+
+	if purchase.Status != model.StatusPending {
+		return errors.New("purchase can only be cancelled before handover")
+	}
+	if time.Since(purchase.CreatedAt) > window {
+		return errors.New("cancellation window has expired")
+	}
+
+	// The listing goes back on sale together with the cancellation
+	cancelled, err := s.repo.CancelPurchase(purchaseID, userID, req.Reason)
+	if err != nil {
+		return fmt.Errorf("error cancelling purchase: %w", err)
+	}
+	if !cancelled {
+		return errors.New("purchase can only be cancelled before handover")
+	}
+
+	return nil
+}
+
+// ConfirmHandover marks a purchase as received by the buyer
+func (s *Service) ConfirmHandover(purchaseID, userID int) error {
+	purchase, err := s.repo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("purchase not found")
+		}
+		return fmt.Errorf("error getting purchase: %w", err)
+	}
+
+	// Only the buyer can confirm receiving the item
+	if purchase.UserID != userID {
+		return errors.New("only the buyer can confirm handover")
+	}
+
+	confirmed, err := s.repo.ConfirmHandover(purchaseID)
+	if err != nil {
+		return fmt.Errorf("error confirming handover: %w", err)
+	}
+	if !confirmed {
+		return errors.New("purchase is not awaiting handover")
+	}
+
+	return nil
+}
+
 // GetPurchaseByID gets a purchase by ID
 func (s *Service) GetPurchaseByID(purchaseID int) (*model.Purchase, error) {
 	return s.repo.GetPurchaseByID(purchaseID)
@@ -96,3 +209,53 @@ func (s *Service) GetUserSales(userID, page, limit int) (*model.PurchaseResponse
 	}
 	return s.repo.GetUserSales(userID, page, limit)
 }
+
+// Sales summary defaults
+const (
+	// DefaultSummaryDays is the date range used when no dates are given
+	DefaultSummaryDays = 30
+	// DefaultTopListings is the number of top listings returned by default
+	DefaultTopListings = 5
+)
+
+// GetSalesSummary aggregates the user's sales between the from and to dates (both inclusive).
+// Missing dates default to the last DefaultSummaryDays days.
+func (s *Service) GetSalesSummary(userID int, from, to *time.Time, groupBy string, top int) (*model.SalesSummary, error) {
+	if groupBy == "" {
+		groupBy = model.GroupByDay
+	}
+	if groupBy != model.GroupByDay && groupBy != model.GroupByWeek && groupBy != model.GroupByMonth {
+		return nil, errors.New("invalid group_by")
+	}
+	if top < 1 || top > 50 {
+		top = DefaultTopListings
+	}
+
+	// Resolve the date range
+	var end time.Time
+	if to != nil {
+		end = *to
+	} else {
+		now := time.Now()
+		end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	}
+	var start time.Time
+	if from != nil {
+		start = *from
+	} else {
+		start = end.AddDate(0, 0, -(DefaultSummaryDays - 1))
+	}
+	if start.After(end) {
+		return nil, errors.New("invalid date range")
+	}
+
+	summary, err := s.repo.GetSalesSummary(userID, start, end.AddDate(0, 0, 1), groupBy, top)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.From = start
+	summary.To = end
+	summary.GroupBy = groupBy
+	return summary, nil
+}
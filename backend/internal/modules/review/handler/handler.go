@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"FurniSwap/internal/modules/review/model"
+	"FurniSwap/internal/modules/review/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler provides review handlers
+type Handler struct {
+	service *service.Service
+}
+
+// NewHandler creates a new review handler
+func NewHandler(service *service.Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// RegisterPublicRoutes registers public review routes (no auth required)
+func (h *Handler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/users/:id/reviews", h.GetUserReviews)
+}
+
+// RegisterRoutes registers protected review routes (auth required)
+func (h *Handler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/purchases/:id/reviews", h.CreateReview)
+	router.GET("/purchases/:id/reviews", h.GetPurchaseReviews)
+	router.POST("/reviews/:id/reply", h.ReplyToReview)
+}
+
+// CreateReview handles leaving a review for a purchase
+func (h *Handler) CreateReview(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Create review
+	reviewID, err := h.service.CreateReview(purchaseID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		if err.Error() == "you are not a participant of this purchase" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this purchase"})
+			return
+		}
+		if err.Error() == "purchase is not completed" {
+			c.JSON(http.StatusConflict, gin.H{"error": "A purchase can be reviewed only after the item is handed over"})
+			return
+		}
+		if err.Error() == "you have already reviewed this purchase" {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this purchase"})
+			return
+		}
+		log.Printf("Error creating review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating review"})
+		return
+	}
+
+	// Get the created review
+	review, err := h.service.GetReviewByID(reviewID)
+	if err != nil {
+		log.Printf("Error getting created review: %v", err)
+		c.JSON(http.StatusOK, gin.H{"id": reviewID, "message": "Review created successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// GetPurchaseReviews handles getting reviews of a purchase
+func (h *Handler) GetPurchaseReviews(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse purchase ID
+	purchaseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purchase ID"})
+		return
+	}
+
+	// Get reviews
+	reviews, err := h.service.GetPurchaseReviews(purchaseID, userID.(int))
+	if err != nil {
+		if err.Error() == "purchase not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Purchase not found"})
+			return
+		}
+		if err.Error() == "you are not a participant of this purchase" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this purchase"})
+			return
+		}
+		log.Printf("Error getting purchase reviews: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// ReplyToReview handles posting a reply to a review
+func (h *Handler) ReplyToReview(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Parse review ID
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.ReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+		return
+	}
+
+	// Save reply
+	err = h.service.ReplyToReview(reviewID, userID.(int), req)
+	if err != nil {
+		if err.Error() == "review not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+			return
+		}
+		if err.Error() == "only the reviewed user can reply" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the reviewed user can reply"})
+			return
+		}
+		if err.Error() == "review already has a reply" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Review already has a reply"})
+			return
+		}
+		log.Printf("Error replying to review: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error replying to review"})
+		return
+	}
+
+	// Get the updated review
+	review, err := h.service.GetReviewByID(reviewID)
+	if err != nil {
+		log.Printf("Error getting updated review: %v", err)
+		c.JSON(http.StatusOK, gin.H{"message": "Reply saved successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// GetUserReviews handles getting reviews received by a user
+func (h *Handler) GetUserReviews(c *gin.Context) {
+	// Parse user ID
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	// Get reviews
+	reviews, err := h.service.GetUserReviews(userID, page, limit)
+	if err != nil {
+		log.Printf("Error getting user reviews: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Review represents a rating and text review left for a purchase counterparty
+type Review struct {
+	ID           int        `db:"id" json:"id"`
+	PurchaseID   int        `db:"purchase_id" json:"purchase_id"`
+	AuthorID     int        `db:"author_id" json:"author_id"`
+	TargetID     int        `db:"target_id" json:"target_id"`
+	AuthorRole   string     `db:"author_role" json:"author_role"`
+	Rating       int        `db:"rating" json:"rating"`
+	Text         string     `db:"text" json:"text"`
+	Reply        *string    `db:"reply" json:"reply,omitempty"`
+	RepliedAt    *time.Time `db:"replied_at" json:"replied_at,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	AuthorName   string     `db:"author_name" json:"author_name,omitempty"`
+	ListingTitle string     `db:"listing_title" json:"listing_title,omitempty"`
+}
+
+// Roles of the review author in the purchase
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+)
+
+// RatingSummary represents the aggregated rating of a user
+type RatingSummary struct {
+	Rating      float64 `db:"rating" json:"rating"`
+	ReviewCount int     `db:"review_count" json:"review_count"`
+}
+
+// CreateReviewRequest represents the data needed to leave a review
+type CreateReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Text   string `json:"text" binding:"max=2000"`
+}
+
+// ReplyRequest represents the data needed to reply to a review
+type ReplyRequest struct {
+	Text string `json:"text" binding:"required,max=2000"`
+}
+
+// ReviewResponse represents a list of reviews with pagination
+type ReviewResponse struct {
+	Reviews     []Review      `json:"reviews"`
+	Summary     RatingSummary `json:"summary"`
+	TotalCount  int           `json:"total_count"`
+	CurrentPage int           `json:"current_page"`
+	TotalPages  int           `json:"total_pages"`
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"FurniSwap/internal/modules/review/model"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository handles database operations for the review module
+type Repository struct {
+	db *sqlx.DB
+}
+
+// NewRepository creates a new review repository
+func NewRepository(db *sqlx.DB) *Repository {
+	return &Repository{
+		db: db,
+	}
+}
+
+// CreateReview creates a new review for a purchase
+func (r *Repository) CreateReview(purchaseID, authorID, targetID int, authorRole string, rating int, text string) (int, error) {
+	var reviewID int
+	err := r.db.QueryRow(`
+		INSERT INTO reviews (purchase_id, author_id, target_id, author_role, rating, text, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, purchaseID, authorID, targetID, authorRole, rating, text, time.Now()).Scan(&reviewID)
+
+	if err != nil {
+		log.Printf("Error creating review: %v", err)
+		return 0, fmt.Errorf("error creating review: %w", err)
+	}
+
+	return reviewID, nil
+}
+
+// ReviewExists checks if the author has already reviewed the purchase
+func (r *Repository) ReviewExists(purchaseID, authorID int) (bool, error) {
+	var exists bool
+	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM reviews WHERE purchase_id = $1 AND author_id = $2)", purchaseID, authorID)
+	if err != nil {
+		log.Printf("Error checking if review exists: %v", err)
+		return false, fmt.Errorf("error checking if review exists: %w", err)
+	}
+	return exists, nil
+}
+
+// GetReviewByID gets a review by ID
+func (r *Repository) GetReviewByID(reviewID int) (*model.Review, error) {
+	var review model.Review
+	err := r.db.Get(&review, `
+		SELECT rv.id, rv.purchase_id, rv.author_id, rv.target_id, rv.author_role, rv.rating, rv.text,
+		       rv.reply, rv.replied_at, rv.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as author_name,
+		       COALESCE(l.title, '') as listing_title
+		FROM reviews rv
+		JOIN users u ON rv.author_id = u.id
+		JOIN purchases p ON rv.purchase_id = p.id
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE rv.id = $1
+	`, reviewID)
+	if err != nil {
+		log.Printf("Error getting review by ID: %v", err)
+		return nil, fmt.Errorf("error getting review: %w", err)
+	}
+	return &review, nil
+}
+
+// SetReply stores the reviewed user's reply; it returns false if a reply already exists
+func (r *Repository) SetReply(reviewID int, text string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE reviews
+		SET reply = $1, replied_at = $2
+		WHERE id = $3 AND reply IS NULL
+	`, text, time.Now(), reviewID)
+	if err != nil {
+		log.Printf("Error saving review reply: %v", err)
+		return false, fmt.Errorf("error saving review reply: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error checking affected rows: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetPurchaseReviews gets all reviews left for a purchase
+func (r *Repository) GetPurchaseReviews(purchaseID int) ([]model.Review, error) {
+	reviews := []model.Review{}
+	err := r.db.Select(&reviews, `
+		SELECT rv.id, rv.purchase_id, rv.author_id, rv.target_id, rv.author_role, rv.rating, rv.text,
+		       rv.reply, rv.replied_at, rv.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as author_name
+		FROM reviews rv
+		JOIN users u ON rv.author_id = u.id
+		WHERE rv.purchase_id = $1
+		ORDER BY rv.created_at ASC
+	`, purchaseID)
+	if err != nil {
+		log.Printf("Error getting purchase reviews: %v", err)
+		return nil, fmt.Errorf("error getting purchase reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// GetUserReviews gets reviews received by a user with pagination
+func (r *Repository) GetUserReviews(userID, page, limit int) (*model.ReviewResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * limit
+
+	// Get rating summary, which also gives the total count
+	summary, err := r.GetUserRating(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate total pages
+	totalPages := int(math.Ceil(float64(summary.ReviewCount) / float64(limit)))
+
+	// Get reviews
+	reviews := []model.Review{}
+	err = r.db.Select(&reviews, `
+		SELECT rv.id, rv.purchase_id, rv.author_id, rv.target_id, rv.author_role, rv.rating, rv.text,
+		       rv.reply, rv.replied_at, rv.created_at,
+		       u.name || ' ' || COALESCE(u.last_name, '') as author_name,
+		       COALESCE(l.title, '') as listing_title
+		FROM reviews rv
+		JOIN users u ON rv.author_id = u.id
+		JOIN purchases p ON rv.purchase_id = p.id
+		LEFT JOIN listings l ON p.listing_id = l.id
+		WHERE rv.target_id = $1
+		ORDER BY rv.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		log.Printf("Error getting user reviews: %v", err)
+		return nil, fmt.Errorf("error getting user reviews: %w", err)
+	}
+
+	return &model.ReviewResponse{
+		Reviews:     reviews,
+		Summary:     *summary,
+		TotalCount:  summary.ReviewCount,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+	}, nil
+}
+
+// GetUserRating gets the aggregated rating of a user
+func (r *Repository) GetUserRating(userID int) (*model.RatingSummary, error) {
+	var summary model.RatingSummary
+	err := r.db.Get(&summary, `
+		SELECT COALESCE(ROUND(AVG(rating), 2), 0) as rating, COUNT(*) as review_count
+		FROM reviews
+		WHERE target_id = $1
+	`, userID)
+	if err != nil {
+		log.Printf("Error getting user rating: %v", err)
+		return nil, fmt.Errorf("error getting user rating: %w", err)
+	}
+	return &summary, nil
+}
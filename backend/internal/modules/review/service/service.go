@@ -0,0 +1,145 @@
+package service
+
+import (
+	purchaseModel "FurniSwap/internal/modules/purchase/model"
+	purchaseRepo "FurniSwap/internal/modules/purchase/repository"
+	"FurniSwap/internal/modules/review/model"
+	"FurniSwap/internal/modules/review/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// Service provides review operations
+type Service struct {
+	repo         *repository.Repository
+	purchaseRepo *purchaseRepo.Repository
+}
+
+// NewService creates a new review service
+func NewService(repo *repository.Repository, purchaseRepo *purchaseRepo.Repository) *Service {
+	return &Service{
+		repo:         repo,
+		purchaseRepo: purchaseRepo,
+	}
+}
+
+// CreateReview leaves a review for the other party of a purchase
+func (s *Service) CreateReview(purchaseID, userID int, req model.CreateReviewRequest) (int, error) {
+	// Reviews can only be left for an existing purchase
+	purchase, err := s.purchaseRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errors.New("purchase not found")
+		}
+		return 0, fmt.Errorf("error getting purchase: %w", err)
+	}
+
+	// Determine who is reviewing whom
+	var targetID int
+	var authorRole string
+	switch userID {
+	case purchase.UserID:
+		targetID = purchase.SellerID
+		authorRole = model.RoleBuyer
+	case purchase.SellerID:
+		targetID = purchase.UserID
+		authorRole = model.RoleSeller
+	default:
+		return 0, errors.New("you are not a participant of this purchase")
+	}
+
+	// Only a deal that went through can be reviewed, not a cancelled, refunded or disputed one
+	if purchase.Status != purchaseModel.StatusCompleted {
+		return 0, errors.New("purchase is not completed")
+	}
+
+	// Each party can leave only one review per purchase
+	exists, err := s.repo.ReviewExists(purchaseID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("error checking existing review: %w", err)
+	}
+	if exists {
+		return 0, errors.New("you have already reviewed this purchase")
+	}
+
+	reviewID, err := s.repo.CreateReview(purchaseID, userID, targetID, authorRole, req.Rating, req.Text)
+	if err != nil {
+		// A concurrent request for the same purchase got in first
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return 0, errors.New("you have already reviewed this purchase")
+		}
+		log.Printf("Error creating review for purchase %d: %v", purchaseID, err)
+		return 0, fmt.Errorf("error creating review: %w", err)
+	}
+
+	return reviewID, nil
+}
+
+// ReplyToReview posts the reviewed user's public reply to a review
+func (s *Service) ReplyToReview(reviewID, userID int, req model.ReplyRequest) error {
+	review, err := s.repo.GetReviewByID(reviewID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("review not found")
+		}
+		return fmt.Errorf("error getting review: %w", err)
+	}
+
+	// Only the reviewed user can reply
+	if review.TargetID != userID {
+		return errors.New("only the reviewed user can reply")
+	}
+
+	saved, err := s.repo.SetReply(reviewID, req.Text)
+	if err != nil {
+		return fmt.Errorf("error saving reply: %w", err)
+	}
+	if !saved {
+		return errors.New("review already has a reply")
+	}
+
+	return nil
+}
+
+// GetReviewByID gets a review by ID
+func (s *Service) GetReviewByID(reviewID int) (*model.Review, error) {
+	return s.repo.GetReviewByID(reviewID)
+}
+
+// GetPurchaseReviews gets reviews of a purchase visible to one of its parties
+func (s *Service) GetPurchaseReviews(purchaseID, userID int) ([]model.Review, error) {
+	purchase, err := s.purchaseRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("purchase not found")
+		}
+		return nil, fmt.Errorf("error getting purchase: %w", err)
+	}
+
+	if purchase.UserID != userID && purchase.SellerID != userID {
+		return nil, errors.New("you are not a participant of this purchase")
+	}
+
+	return s.repo.GetPurchaseReviews(purchaseID)
+}
+
+// GetUserReviews gets reviews received by a user
+func (s *Service) GetUserReviews(userID, page, limit int) (*model.ReviewResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return s.repo.GetUserReviews(userID, page, limit)
+}
+
+// GetUserRating gets the aggregated rating of a user
+func (s *Service) GetUserRating(userID int) (*model.RatingSummary, error) {
+	return s.repo.GetUserRating(userID)
+}
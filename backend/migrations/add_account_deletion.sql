@@ -0,0 +1,61 @@
+-- Account deletions: the account is deactivated when the user deletes it and purged after
+-- purge_after unless the user logs in before. Purging erases the personal data and keeps the
+-- user row anonymized, so the other party's chats, purchases and reviews stay intact.
+-- The listings hidden on deletion are listed to show them again if the deletion is cancelled.
+CREATE TABLE IF NOT EXISTS account_deletions
+(
+    user_id            INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    hidden_listing_ids INT[]     NOT NULL DEFAULT '{}',
+    requested_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+    purge_after        TIMESTAMP NOT NULL,
+    purged_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS account_deletions_purge_after_idx ON account_deletions (purge_after) WHERE purged_at IS NULL;
+
+-- Records shared with other users must not disappear with a user row, so deleting a user
+-- who has them fails instead of cascading
+ALTER TABLE listings DROP CONSTRAINT IF EXISTS listings_user_id_fkey;
+ALTER TABLE listings ADD CONSTRAINT listings_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_buyer_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_buyer_id_fkey
+    FOREIGN KEY (buyer_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_seller_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_seller_id_fkey
+    FOREIGN KEY (seller_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_user_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE message_attachments DROP CONSTRAINT IF EXISTS message_attachments_uploader_id_fkey;
+ALTER TABLE message_attachments ADD CONSTRAINT message_attachments_uploader_id_fkey
+    FOREIGN KEY (uploader_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_buyer_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_buyer_id_fkey
+    FOREIGN KEY (buyer_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_seller_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_seller_id_fkey
+    FOREIGN KEY (seller_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_author_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_author_id_fkey
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_target_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_target_id_fkey
+    FOREIGN KEY (target_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE disputes DROP CONSTRAINT IF EXISTS disputes_opened_by_fkey;
+ALTER TABLE disputes ADD CONSTRAINT disputes_opened_by_fkey
+    FOREIGN KEY (opened_by) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE dispute_messages DROP CONSTRAINT IF EXISTS dispute_messages_user_id_fkey;
+ALTER TABLE dispute_messages ADD CONSTRAINT dispute_messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE dispute_evidence DROP CONSTRAINT IF EXISTS dispute_evidence_user_id_fkey;
+ALTER TABLE dispute_evidence ADD CONSTRAINT dispute_evidence_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
@@ -0,0 +1,35 @@
+-- Verification codes are stored hashed, one active code per user and purpose, with an attempt counter.
+-- Existing plain codes are dropped; they expire within minutes anyway. The rename runs only
+-- once, so the migration can be applied again.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1
+               FROM information_schema.columns
+               WHERE table_schema = current_schema()
+                 AND table_name = 'two_factor_codes'
+                 AND column_name = 'code') THEN
+        DELETE FROM two_factor_codes;
+        ALTER TABLE two_factor_codes RENAME COLUMN code TO code_hash;
+    END IF;
+END
+$$;
+ALTER TABLE two_factor_codes ADD COLUMN IF NOT EXISTS purpose TEXT NOT NULL DEFAULT 'verification';
+ALTER TABLE two_factor_codes ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX IF NOT EXISTS two_factor_codes_user_purpose_idx ON two_factor_codes (user_id, purpose);
+
+-- Temporary lockout after repeated wrong passwords
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Failed authentication attempts for per-email and per-IP throttling
+CREATE TABLE IF NOT EXISTS auth_failures
+(
+    id         SERIAL PRIMARY KEY,
+    action     TEXT      NOT NULL,
+    email      TEXT      NOT NULL,
+    ip         TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS auth_failures_email_idx ON auth_failures (action, email, created_at);
+CREATE INDEX IF NOT EXISTS auth_failures_ip_idx ON auth_failures (action, ip, created_at);
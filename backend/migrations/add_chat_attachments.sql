@@ -0,0 +1,16 @@
+-- Image and file attachments in chat messages.
+-- Attachments uploaded ahead of a message have no message_id until the message is sent.
+CREATE TABLE message_attachments
+(
+    id           SERIAL PRIMARY KEY,
+    chat_id      INT REFERENCES chats (id) ON DELETE CASCADE,
+    message_id   INT REFERENCES messages (id) ON DELETE CASCADE,
+    uploader_id  INT REFERENCES users (id) ON DELETE CASCADE,
+    file_path    TEXT   NOT NULL,
+    file_name    TEXT   NOT NULL,
+    content_type TEXT   NOT NULL,
+    size         BIGINT NOT NULL,
+    created_at   TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX message_attachments_message_id_idx ON message_attachments (message_id);
@@ -0,0 +1,20 @@
+-- Last message metadata maintained on chats for ordering and previews
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_message_id INT REFERENCES messages (id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_message_at TIMESTAMP;
+
+UPDATE chats c
+SET last_message_id = m.id, last_message_at = m.created_at
+FROM (
+    SELECT DISTINCT ON (chat_id) id, chat_id, created_at
+    FROM messages
+    ORDER BY chat_id, created_at DESC, id DESC
+) m
+WHERE m.chat_id = c.id;
+
+UPDATE chats SET last_message_at = COALESCE(created_at, NOW()) WHERE last_message_at IS NULL;
+
+ALTER TABLE chats ALTER COLUMN last_message_at SET DEFAULT NOW();
+ALTER TABLE chats ALTER COLUMN last_message_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chats_buyer_last_message_idx ON chats (buyer_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS chats_seller_last_message_idx ON chats (seller_id, last_message_at DESC);
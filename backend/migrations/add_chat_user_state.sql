@@ -0,0 +1,15 @@
+-- Per-participant chat state: archiving, muting, pinning and deleting a chat for oneself
+CREATE TABLE IF NOT EXISTS chat_user_states
+(
+    chat_id          INT REFERENCES chats (id) ON DELETE CASCADE,
+    user_id          INT REFERENCES users (id) ON DELETE CASCADE,
+    archived         BOOLEAN NOT NULL DEFAULT FALSE,
+    muted            BOOLEAN NOT NULL DEFAULT FALSE,
+    muted_until      TIMESTAMP,
+    pinned           BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Messages up to this ID are hidden from the user after deleting the chat for themselves
+    deleted_up_to_id INT,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS chat_user_states_user_id_idx ON chat_user_states (user_id);
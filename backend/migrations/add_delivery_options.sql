@@ -0,0 +1,15 @@
+-- Delivery and pickup options on listings
+ALTER TABLE listings ADD COLUMN pickup_area TEXT NOT NULL DEFAULT '';
+ALTER TABLE listings ADD COLUMN self_pickup BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE listings ADD COLUMN seller_delivery BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE listings ADD COLUMN delivery_radius_km INT NOT NULL DEFAULT 0;
+ALTER TABLE listings ADD COLUMN delivery_fee DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE listings ADD COLUMN floor INT;
+ALTER TABLE listings ADD COLUMN has_elevator BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Delivery option chosen by the buyer
+ALTER TABLE purchases ADD COLUMN delivery_option TEXT NOT NULL DEFAULT 'pickup';
+ALTER TABLE purchases ADD COLUMN delivery_fee DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE purchases ADD COLUMN delivery_address TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN purchases.delivery_option IS 'Possible values: pickup, delivery';
@@ -0,0 +1,62 @@
+-- Purchase lifecycle: handover, cancellation and disputes
+ALTER TABLE purchases ADD COLUMN status TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE purchases ADD COLUMN handed_over_at TIMESTAMP;
+ALTER TABLE purchases ADD COLUMN cancelled_at TIMESTAMP;
+ALTER TABLE purchases ADD COLUMN cancelled_by INT REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE purchases ADD COLUMN cancel_reason TEXT NOT NULL DEFAULT '';
+
+-- Purchases made before this migration are treated as handed over
+UPDATE purchases SET status = 'completed', handed_over_at = purchased_at;
+
+COMMENT ON COLUMN purchases.status IS 'Possible values: pending, completed, cancelled, disputed, refunded';
+
+-- A listing can be bought again after its purchase was cancelled or refunded
+ALTER TABLE purchases DROP CONSTRAINT purchases_listing_id_key;
+CREATE UNIQUE INDEX purchases_active_listing_idx ON purchases (listing_id)
+    WHERE status NOT IN ('cancelled', 'refunded');
+
+CREATE TABLE disputes
+(
+    id                 SERIAL PRIMARY KEY,
+    purchase_id        INT REFERENCES purchases (id) ON DELETE CASCADE,
+    opened_by          INT REFERENCES users (id) ON DELETE CASCADE,
+    reason             TEXT NOT NULL,
+    status             TEXT NOT NULL DEFAULT 'open',
+    resolution         TEXT,
+    resolution_comment TEXT,
+    resolved_by        INT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at        TIMESTAMP,
+    created_at         TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX disputes_purchase_id_idx ON disputes (purchase_id);
+CREATE INDEX disputes_status_idx ON disputes (status);
+
+COMMENT ON COLUMN disputes.status IS 'Possible values: open, resolved';
+COMMENT ON COLUMN disputes.resolution IS 'Possible values: refund, reject';
+
+CREATE TABLE dispute_messages
+(
+    id         SERIAL PRIMARY KEY,
+    dispute_id INT REFERENCES disputes (id) ON DELETE CASCADE,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    content    TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE dispute_evidence
+(
+    id         SERIAL PRIMARY KEY,
+    dispute_id INT REFERENCES disputes (id) ON DELETE CASCADE,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    image_path TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- A listing that was bought can't be deleted: its purchase, disputes and reviews would go with it
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_listing_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_listing_id_fkey
+    FOREIGN KEY (listing_id) REFERENCES listings (id) ON DELETE RESTRICT;
+
+-- The type sniffed from an evidence image, sent when it is served; older uploads are served as plain bytes
+ALTER TABLE dispute_evidence ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT 'application/octet-stream';
@@ -0,0 +1,24 @@
+-- Email changes: the new address is confirmed with a code before the switch, and the old
+-- address can revoke the change with a link until revocable_until. Until then the old
+-- address stays reserved for the user, so nobody else can sign up with it.
+CREATE TABLE IF NOT EXISTS email_changes
+(
+    id                SERIAL PRIMARY KEY,
+    user_id           INT REFERENCES users (id) ON DELETE CASCADE,
+    old_email         TEXT      NOT NULL,
+    new_email         TEXT      NOT NULL,
+    code_hash         TEXT      NOT NULL,
+    attempts          INT       NOT NULL DEFAULT 0,
+    revoke_token_hash TEXT UNIQUE NOT NULL,
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    code_expires_at   TIMESTAMP NOT NULL,
+    revocable_until   TIMESTAMP NOT NULL,
+    confirmed_at      TIMESTAMP,
+    revoked_at        TIMESTAMP
+);
+
+-- A user has at most one unconfirmed change
+CREATE UNIQUE INDEX IF NOT EXISTS email_changes_pending_idx ON email_changes (user_id)
+    WHERE confirmed_at IS NULL AND revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS email_changes_old_email_idx ON email_changes (old_email);
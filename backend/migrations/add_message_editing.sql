@@ -0,0 +1,4 @@
+-- Message editing, soft deletion and replies
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_id INT REFERENCES messages (id) ON DELETE SET NULL;
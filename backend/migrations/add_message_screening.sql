@@ -0,0 +1,22 @@
+-- Screening of chat messages for spam and scams
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS warning TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS messages_held_idx ON messages (created_at) WHERE held AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS messages_user_id_created_at_idx ON messages (user_id, created_at);
+
+-- Audit log of triggered screening rules; message_id is empty for rejected messages
+CREATE TABLE IF NOT EXISTS message_screening_events
+(
+    id         SERIAL PRIMARY KEY,
+    chat_id    INT REFERENCES chats (id) ON DELETE CASCADE,
+    sender_id  INT REFERENCES users (id) ON DELETE CASCADE,
+    message_id INT REFERENCES messages (id) ON DELETE SET NULL,
+    rule       VARCHAR(50) NOT NULL,
+    action     VARCHAR(20) NOT NULL,
+    detail     TEXT        NOT NULL DEFAULT '',
+    content    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMP            DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS message_screening_events_created_at_idx ON message_screening_events (created_at DESC);
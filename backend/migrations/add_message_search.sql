@@ -0,0 +1,3 @@
+-- Full-text search over chat messages
+CREATE INDEX IF NOT EXISTS messages_content_fts_idx ON messages USING GIN (to_tsvector('russian', content));
+CREATE INDEX IF NOT EXISTS messages_chat_id_id_idx ON messages (chat_id, id);
@@ -0,0 +1,26 @@
+-- Pending OpenID Connect logins: the state sent to the provider (hashed), with the nonce
+-- and PKCE verifier the callback needs; each state works once
+CREATE TABLE IF NOT EXISTS oauth_states
+(
+    state_hash    TEXT PRIMARY KEY,
+    provider      TEXT      NOT NULL,
+    nonce         TEXT      NOT NULL,
+    code_verifier TEXT      NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMP NOT NULL
+);
+
+-- External identities linked to users; the provider's subject identifies the account,
+-- the email is the one the provider reported when it was linked
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT      NOT NULL,
+    subject    TEXT      NOT NULL,
+    email      TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,7 @@
+-- Read receipts for chat messages
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+
+UPDATE messages SET is_read = FALSE WHERE is_read IS NULL;
+UPDATE messages SET read_at = created_at WHERE is_read = TRUE AND read_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON messages (chat_id, user_id) WHERE is_read = FALSE;
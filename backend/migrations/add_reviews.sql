@@ -0,0 +1,19 @@
+-- Ratings and reviews left by buyers and sellers after a purchase
+CREATE TABLE reviews
+(
+    id          SERIAL PRIMARY KEY,
+    purchase_id INT REFERENCES purchases (id) ON DELETE CASCADE,
+    author_id   INT REFERENCES users (id) ON DELETE CASCADE,
+    target_id   INT REFERENCES users (id) ON DELETE CASCADE,
+    author_role TEXT NOT NULL,
+    rating      INT  NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text        TEXT NOT NULL DEFAULT '',
+    reply       TEXT,
+    replied_at  TIMESTAMP,
+    created_at  TIMESTAMP DEFAULT NOW(),
+    UNIQUE (purchase_id, author_id)
+);
+
+CREATE INDEX reviews_target_id_idx ON reviews (target_id);
+
+COMMENT ON COLUMN reviews.author_role IS 'Possible values: buyer, seller';
@@ -0,0 +1,49 @@
+-- Roles and their permissions; route access is checked by permission, so what a role may do
+-- can be changed here without code changes
+CREATE TABLE IF NOT EXISTS roles
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role       TEXT REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description)
+VALUES ('user', 'Buys and sells furniture'),
+       ('moderator', 'Resolves disputes and chat reports'),
+       ('admin', 'Manages users and their roles')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description)
+VALUES ('disputes.moderate', 'Review and resolve disputes'),
+       ('chats.moderate', 'Review chat reports and screened messages'),
+       ('users.read', 'View users and their roles'),
+       ('users.manage', 'Change roles of users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('moderator', 'disputes.moderate'),
+       ('moderator', 'chats.moderate'),
+       ('moderator', 'users.read'),
+       ('admin', 'disputes.moderate'),
+       ('admin', 'chats.moderate'),
+       ('admin', 'users.read'),
+       ('admin', 'users.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- Every user has a role; moderators and admins are appointed by an admin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' REFERENCES roles (name);
+
+CREATE INDEX IF NOT EXISTS users_role_idx ON users (role) WHERE role <> 'user';
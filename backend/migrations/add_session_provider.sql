@@ -0,0 +1,3 @@
+-- The OpenID Connect provider a session was started with; empty for password logins.
+-- A fresh provider login stands in for the password before deleting the account.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT '';
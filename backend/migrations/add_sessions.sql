@@ -0,0 +1,19 @@
+-- Login sessions with rotating refresh tokens; only token hashes are stored
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id                  SERIAL PRIMARY KEY,
+    user_id             INT REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT UNIQUE NOT NULL,
+    -- The token replaced by the last rotation, to detect reuse of a stolen token
+    previous_token_hash TEXT,
+    device              TEXT      NOT NULL DEFAULT '',
+    ip                  TEXT      NOT NULL DEFAULT '',
+    user_agent          TEXT      NOT NULL DEFAULT '',
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMP NOT NULL,
+    revoked_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_previous_token_hash_idx ON sessions (previous_token_hash);
@@ -0,0 +1,8 @@
+-- Typed system messages generated by listing and purchase events
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind VARCHAR(32) NOT NULL DEFAULT 'text';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload JSONB;
+
+-- Keep conversations when their listing is deleted so the "listing removed" message stays visible
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_listing_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_listing_id_fkey
+    FOREIGN KEY (listing_id) REFERENCES listings (id) ON DELETE SET NULL;
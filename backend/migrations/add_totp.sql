@@ -0,0 +1,25 @@
+-- Second factor of a user: 'email' (code by email) or 'totp' (authenticator app)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_method TEXT NOT NULL DEFAULT 'email';
+
+-- TOTP authenticator of a user; the secret is encrypted with a key derived from JWT_SECRET_KEY
+CREATE TABLE IF NOT EXISTS totp_credentials
+(
+    user_id          INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret_encrypted TEXT      NOT NULL,
+    confirmed        BOOLEAN   NOT NULL DEFAULT FALSE,
+    -- The time step of the last accepted code, so a code can't be used twice
+    last_used_step   BIGINT    NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One-time recovery codes for signing in without the authenticator
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  TEXT      NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
@@ -0,0 +1,16 @@
+-- Devices where a user skips the second factor; the signed device token refers to the row,
+-- so a device stops being trusted as soon as it's revoked
+CREATE TABLE IF NOT EXISTS trusted_devices
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INT REFERENCES users (id) ON DELETE CASCADE,
+    device       TEXT      NOT NULL DEFAULT '',
+    ip           TEXT      NOT NULL DEFAULT '',
+    user_agent   TEXT      NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS trusted_devices_user_id_idx ON trusted_devices (user_id);
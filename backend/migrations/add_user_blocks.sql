@@ -0,0 +1,32 @@
+-- Users blocked by other users
+CREATE TABLE user_blocks
+(
+    blocker_id INT REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id INT REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+
+-- Chat abuse reports reviewed by moderators
+CREATE TABLE chat_reports
+(
+    id               SERIAL PRIMARY KEY,
+    chat_id          INT REFERENCES chats (id) ON DELETE CASCADE,
+    reporter_id      INT REFERENCES users (id) ON DELETE CASCADE,
+    reported_user_id INT REFERENCES users (id) ON DELETE CASCADE,
+    message_id       INT REFERENCES messages (id) ON DELETE SET NULL,
+    reason           TEXT NOT NULL,
+    comment          TEXT NOT NULL DEFAULT '',
+    status           TEXT NOT NULL DEFAULT 'open',
+    resolution       TEXT NOT NULL DEFAULT '',
+    resolved_by      INT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at      TIMESTAMP,
+    created_at       TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX chat_reports_status_idx ON chat_reports (status, created_at);
+
+COMMENT ON COLUMN chat_reports.reason IS 'Possible values: spam, harassment, scam, other';
+COMMENT ON COLUMN chat_reports.status IS 'Possible values: open, resolved, dismissed';
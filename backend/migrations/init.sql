@@ -103,4 +103,518 @@ CREATE TABLE purchases
 -- Add index for better performance
 CREATE INDEX purchases_buyer_id_idx ON purchases (buyer_id);
 CREATE INDEX purchases_seller_id_idx ON purchases (seller_id);
-CREATE INDEX listings_status_idx ON listings (status);
\ No newline at end of file
+CREATE INDEX listings_status_idx ON listings (status);
+
+-- Delivery and pickup options on listings
+ALTER TABLE listings ADD COLUMN pickup_area TEXT NOT NULL DEFAULT '';
+ALTER TABLE listings ADD COLUMN self_pickup BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE listings ADD COLUMN seller_delivery BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE listings ADD COLUMN delivery_radius_km INT NOT NULL DEFAULT 0;
+ALTER TABLE listings ADD COLUMN delivery_fee DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE listings ADD COLUMN floor INT;
+ALTER TABLE listings ADD COLUMN has_elevator BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Delivery option chosen by the buyer
+ALTER TABLE purchases ADD COLUMN delivery_option TEXT NOT NULL DEFAULT 'pickup';
+ALTER TABLE purchases ADD COLUMN delivery_fee DECIMAL NOT NULL DEFAULT 0;
+ALTER TABLE purchases ADD COLUMN delivery_address TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN purchases.delivery_option IS 'Possible values: pickup, delivery';
+
+-- Ratings and reviews left by buyers and sellers after a purchase
+CREATE TABLE reviews
+(
+    id          SERIAL PRIMARY KEY,
+    purchase_id INT REFERENCES purchases (id) ON DELETE CASCADE,
+    author_id   INT REFERENCES users (id) ON DELETE CASCADE,
+    target_id   INT REFERENCES users (id) ON DELETE CASCADE,
+    author_role TEXT NOT NULL,
+    rating      INT  NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text        TEXT NOT NULL DEFAULT '',
+    reply       TEXT,
+    replied_at  TIMESTAMP,
+    created_at  TIMESTAMP DEFAULT NOW(),
+    UNIQUE (purchase_id, author_id)
+);
+
+CREATE INDEX reviews_target_id_idx ON reviews (target_id);
+
+COMMENT ON COLUMN reviews.author_role IS 'Possible values: buyer, seller';
+
+-- Purchase lifecycle: handover, cancellation and disputes
+ALTER TABLE purchases ADD COLUMN status TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE purchases ADD COLUMN handed_over_at TIMESTAMP;
+ALTER TABLE purchases ADD COLUMN cancelled_at TIMESTAMP;
+ALTER TABLE purchases ADD COLUMN cancelled_by INT REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE purchases ADD COLUMN cancel_reason TEXT NOT NULL DEFAULT '';
+
+-- Purchases made before this migration are treated as handed over
+UPDATE purchases SET status = 'completed', handed_over_at = purchased_at;
+
+COMMENT ON COLUMN purchases.status IS 'Possible values: pending, completed, cancelled, disputed, refunded';
+
+-- A listing can be bought again after its purchase was cancelled or refunded
+ALTER TABLE purchases DROP CONSTRAINT purchases_listing_id_key;
+CREATE UNIQUE INDEX purchases_active_listing_idx ON purchases (listing_id)
+    WHERE status NOT IN ('cancelled', 'refunded');
+
+CREATE TABLE disputes
+(
+    id                 SERIAL PRIMARY KEY,
+    purchase_id        INT REFERENCES purchases (id) ON DELETE CASCADE,
+    opened_by          INT REFERENCES users (id) ON DELETE CASCADE,
+    reason             TEXT NOT NULL,
+    status             TEXT NOT NULL DEFAULT 'open',
+    resolution         TEXT,
+    resolution_comment TEXT,
+    resolved_by        INT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at        TIMESTAMP,
+    created_at         TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX disputes_purchase_id_idx ON disputes (purchase_id);
+CREATE INDEX disputes_status_idx ON disputes (status);
+
+COMMENT ON COLUMN disputes.status IS 'Possible values: open, resolved';
+COMMENT ON COLUMN disputes.resolution IS 'Possible values: refund, reject';
+
+CREATE TABLE dispute_messages
+(
+    id         SERIAL PRIMARY KEY,
+    dispute_id INT REFERENCES disputes (id) ON DELETE CASCADE,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    content    TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE dispute_evidence
+(
+    id         SERIAL PRIMARY KEY,
+    dispute_id INT REFERENCES disputes (id) ON DELETE CASCADE,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    image_path TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- A listing that was bought can't be deleted: its purchase, disputes and reviews would go with it
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_listing_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_listing_id_fkey
+    FOREIGN KEY (listing_id) REFERENCES listings (id) ON DELETE RESTRICT;
+
+-- The type sniffed from an evidence image, sent when it is served; older uploads are served as plain bytes
+ALTER TABLE dispute_evidence ADD COLUMN IF NOT EXISTS content_type TEXT NOT NULL DEFAULT 'application/octet-stream';
+
+-- Read receipts for chat messages
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+
+UPDATE messages SET is_read = FALSE WHERE is_read IS NULL;
+UPDATE messages SET read_at = created_at WHERE is_read = TRUE AND read_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON messages (chat_id, user_id) WHERE is_read = FALSE;
+
+-- Last message metadata maintained on chats for ordering and previews
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_message_id INT REFERENCES messages (id) ON DELETE SET NULL;
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS last_message_at TIMESTAMP;
+
+UPDATE chats c
+SET last_message_id = m.id, last_message_at = m.created_at
+FROM (
+    SELECT DISTINCT ON (chat_id) id, chat_id, created_at
+    FROM messages
+    ORDER BY chat_id, created_at DESC, id DESC
+) m
+WHERE m.chat_id = c.id;
+
+UPDATE chats SET last_message_at = COALESCE(created_at, NOW()) WHERE last_message_at IS NULL;
+
+ALTER TABLE chats ALTER COLUMN last_message_at SET DEFAULT NOW();
+ALTER TABLE chats ALTER COLUMN last_message_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS chats_buyer_last_message_idx ON chats (buyer_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS chats_seller_last_message_idx ON chats (seller_id, last_message_at DESC);
+
+-- Image and file attachments in chat messages.
+-- Attachments uploaded ahead of a message have no message_id until the message is sent.
+CREATE TABLE message_attachments
+(
+    id           SERIAL PRIMARY KEY,
+    chat_id      INT REFERENCES chats (id) ON DELETE CASCADE,
+    message_id   INT REFERENCES messages (id) ON DELETE CASCADE,
+    uploader_id  INT REFERENCES users (id) ON DELETE CASCADE,
+    file_path    TEXT   NOT NULL,
+    file_name    TEXT   NOT NULL,
+    content_type TEXT   NOT NULL,
+    size         BIGINT NOT NULL,
+    created_at   TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX message_attachments_message_id_idx ON message_attachments (message_id);
+
+-- Message editing, soft deletion and replies
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_id INT REFERENCES messages (id) ON DELETE SET NULL;
+
+-- Users blocked by other users
+CREATE TABLE user_blocks
+(
+    blocker_id INT REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id INT REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_id_idx ON user_blocks (blocked_id);
+
+-- Chat abuse reports reviewed by moderators
+CREATE TABLE chat_reports
+(
+    id               SERIAL PRIMARY KEY,
+    chat_id          INT REFERENCES chats (id) ON DELETE CASCADE,
+    reporter_id      INT REFERENCES users (id) ON DELETE CASCADE,
+    reported_user_id INT REFERENCES users (id) ON DELETE CASCADE,
+    message_id       INT REFERENCES messages (id) ON DELETE SET NULL,
+    reason           TEXT NOT NULL,
+    comment          TEXT NOT NULL DEFAULT '',
+    status           TEXT NOT NULL DEFAULT 'open',
+    resolution       TEXT NOT NULL DEFAULT '',
+    resolved_by      INT REFERENCES users (id) ON DELETE SET NULL,
+    resolved_at      TIMESTAMP,
+    created_at       TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX chat_reports_status_idx ON chat_reports (status, created_at);
+
+COMMENT ON COLUMN chat_reports.reason IS 'Possible values: spam, harassment, scam, other';
+COMMENT ON COLUMN chat_reports.status IS 'Possible values: open, resolved, dismissed';
+
+-- Full-text search over chat messages
+CREATE INDEX IF NOT EXISTS messages_content_fts_idx ON messages USING GIN (to_tsvector('russian', content));
+CREATE INDEX IF NOT EXISTS messages_chat_id_id_idx ON messages (chat_id, id);
+
+-- Typed system messages generated by listing and purchase events
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS kind VARCHAR(32) NOT NULL DEFAULT 'text';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS payload JSONB;
+
+-- Keep conversations when their listing is deleted so the "listing removed" message stays visible
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_listing_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_listing_id_fkey
+    FOREIGN KEY (listing_id) REFERENCES listings (id) ON DELETE SET NULL;
+
+-- Per-participant chat state: archiving, muting, pinning and deleting a chat for oneself
+CREATE TABLE IF NOT EXISTS chat_user_states
+(
+    chat_id          INT REFERENCES chats (id) ON DELETE CASCADE,
+    user_id          INT REFERENCES users (id) ON DELETE CASCADE,
+    archived         BOOLEAN NOT NULL DEFAULT FALSE,
+    muted            BOOLEAN NOT NULL DEFAULT FALSE,
+    muted_until      TIMESTAMP,
+    pinned           BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Messages up to this ID are hidden from the user after deleting the chat for themselves
+    deleted_up_to_id INT,
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS chat_user_states_user_id_idx ON chat_user_states (user_id);
+
+-- Screening of chat messages for spam and scams
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS warning TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS messages_held_idx ON messages (created_at) WHERE held AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS messages_user_id_created_at_idx ON messages (user_id, created_at);
+
+-- Audit log of triggered screening rules; message_id is empty for rejected messages
+CREATE TABLE IF NOT EXISTS message_screening_events
+(
+    id         SERIAL PRIMARY KEY,
+    chat_id    INT REFERENCES chats (id) ON DELETE CASCADE,
+    sender_id  INT REFERENCES users (id) ON DELETE CASCADE,
+    message_id INT REFERENCES messages (id) ON DELETE SET NULL,
+    rule       VARCHAR(50) NOT NULL,
+    action     VARCHAR(20) NOT NULL,
+    detail     TEXT        NOT NULL DEFAULT '',
+    content    TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMP            DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS message_screening_events_created_at_idx ON message_screening_events (created_at DESC);
+
+-- Login sessions with rotating refresh tokens; only token hashes are stored
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id                  SERIAL PRIMARY KEY,
+    user_id             INT REFERENCES users (id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT UNIQUE NOT NULL,
+    -- The token replaced by the last rotation, to detect reuse of a stolen token
+    previous_token_hash TEXT,
+    device              TEXT      NOT NULL DEFAULT '',
+    ip                  TEXT      NOT NULL DEFAULT '',
+    user_agent          TEXT      NOT NULL DEFAULT '',
+    created_at          TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMP NOT NULL,
+    revoked_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS sessions_previous_token_hash_idx ON sessions (previous_token_hash);
+
+-- Single-use password reset tokens; only token hashes are stored
+CREATE TABLE IF NOT EXISTS password_reset_tokens
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    token_hash TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- Verification codes are stored hashed, one active code per user and purpose, with an attempt counter.
+-- Existing plain codes are dropped; they expire within minutes anyway. The rename runs only
+-- once, so the migration can be applied again.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1
+               FROM information_schema.columns
+               WHERE table_schema = current_schema()
+                 AND table_name = 'two_factor_codes'
+                 AND column_name = 'code') THEN
+        DELETE FROM two_factor_codes;
+        ALTER TABLE two_factor_codes RENAME COLUMN code TO code_hash;
+    END IF;
+END
+$$;
+ALTER TABLE two_factor_codes ADD COLUMN IF NOT EXISTS purpose TEXT NOT NULL DEFAULT 'verification';
+ALTER TABLE two_factor_codes ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX IF NOT EXISTS two_factor_codes_user_purpose_idx ON two_factor_codes (user_id, purpose);
+
+-- Temporary lockout after repeated wrong passwords
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+
+-- Failed authentication attempts for per-email and per-IP throttling
+CREATE TABLE IF NOT EXISTS auth_failures
+(
+    id         SERIAL PRIMARY KEY,
+    action     TEXT      NOT NULL,
+    email      TEXT      NOT NULL,
+    ip         TEXT      NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS auth_failures_email_idx ON auth_failures (action, email, created_at);
+CREATE INDEX IF NOT EXISTS auth_failures_ip_idx ON auth_failures (action, ip, created_at);
+
+-- Second factor of a user: 'email' (code by email) or 'totp' (authenticator app)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_method TEXT NOT NULL DEFAULT 'email';
+
+-- TOTP authenticator of a user; the secret is encrypted with a key derived from JWT_SECRET_KEY
+CREATE TABLE IF NOT EXISTS totp_credentials
+(
+    user_id          INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret_encrypted TEXT      NOT NULL,
+    confirmed        BOOLEAN   NOT NULL DEFAULT FALSE,
+    -- The time step of the last accepted code, so a code can't be used twice
+    last_used_step   BIGINT    NOT NULL DEFAULT 0,
+    created_at       TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One-time recovery codes for signing in without the authenticator
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  TEXT      NOT NULL,
+    used_at    TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+-- Devices where a user skips the second factor; the signed device token refers to the row,
+-- so a device stops being trusted as soon as it's revoked
+CREATE TABLE IF NOT EXISTS trusted_devices
+(
+    id           SERIAL PRIMARY KEY,
+    user_id      INT REFERENCES users (id) ON DELETE CASCADE,
+    device       TEXT      NOT NULL DEFAULT '',
+    ip           TEXT      NOT NULL DEFAULT '',
+    user_agent   TEXT      NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMP NOT NULL,
+    revoked_at   TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS trusted_devices_user_id_idx ON trusted_devices (user_id);
+
+-- Pending OpenID Connect logins: the state sent to the provider (hashed), with the nonce
+-- and PKCE verifier the callback needs; each state works once
+CREATE TABLE IF NOT EXISTS oauth_states
+(
+    state_hash    TEXT PRIMARY KEY,
+    provider      TEXT      NOT NULL,
+    nonce         TEXT      NOT NULL,
+    code_verifier TEXT      NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at    TIMESTAMP NOT NULL
+);
+
+-- External identities linked to users; the provider's subject identifies the account,
+-- the email is the one the provider reported when it was linked
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id         SERIAL PRIMARY KEY,
+    user_id    INT REFERENCES users (id) ON DELETE CASCADE,
+    provider   TEXT      NOT NULL,
+    subject    TEXT      NOT NULL,
+    email      TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+-- Roles and their permissions; route access is checked by permission, so what a role may do
+-- can be changed here without code changes
+CREATE TABLE IF NOT EXISTS roles
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role       TEXT REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description)
+VALUES ('user', 'Buys and sells furniture'),
+       ('moderator', 'Resolves disputes and chat reports'),
+       ('admin', 'Manages users and their roles')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description)
+VALUES ('disputes.moderate', 'Review and resolve disputes'),
+       ('chats.moderate', 'Review chat reports and screened messages'),
+       ('users.read', 'View users and their roles'),
+       ('users.manage', 'Change roles of users')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+VALUES ('moderator', 'disputes.moderate'),
+       ('moderator', 'chats.moderate'),
+       ('moderator', 'users.read'),
+       ('admin', 'disputes.moderate'),
+       ('admin', 'chats.moderate'),
+       ('admin', 'users.read'),
+       ('admin', 'users.manage')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- Every user has a role; moderators and admins are appointed by an admin
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user' REFERENCES roles (name);
+
+CREATE INDEX IF NOT EXISTS users_role_idx ON users (role) WHERE role <> 'user';
+
+-- Email changes: the new address is confirmed with a code before the switch, and the old
+-- address can revoke the change with a link until revocable_until. Until then the old
+-- address stays reserved for the user, so nobody else can sign up with it.
+CREATE TABLE IF NOT EXISTS email_changes
+(
+    id                SERIAL PRIMARY KEY,
+    user_id           INT REFERENCES users (id) ON DELETE CASCADE,
+    old_email         TEXT      NOT NULL,
+    new_email         TEXT      NOT NULL,
+    code_hash         TEXT      NOT NULL,
+    attempts          INT       NOT NULL DEFAULT 0,
+    revoke_token_hash TEXT UNIQUE NOT NULL,
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW(),
+    code_expires_at   TIMESTAMP NOT NULL,
+    revocable_until   TIMESTAMP NOT NULL,
+    confirmed_at      TIMESTAMP,
+    revoked_at        TIMESTAMP
+);
+
+-- A user has at most one unconfirmed change
+CREATE UNIQUE INDEX IF NOT EXISTS email_changes_pending_idx ON email_changes (user_id)
+    WHERE confirmed_at IS NULL AND revoked_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS email_changes_old_email_idx ON email_changes (old_email);
+
+-- Account deletions: the account is deactivated when the user deletes it and purged after
+-- purge_after unless the user logs in before. Purging erases the personal data and keeps the
+-- user row anonymized, so the other party's chats, purchases and reviews stay intact.
+-- The listings hidden on deletion are listed to show them again if the deletion is cancelled.
+CREATE TABLE IF NOT EXISTS account_deletions
+(
+    user_id            INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    hidden_listing_ids INT[]     NOT NULL DEFAULT '{}',
+    requested_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+    purge_after        TIMESTAMP NOT NULL,
+    purged_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS account_deletions_purge_after_idx ON account_deletions (purge_after) WHERE purged_at IS NULL;
+
+-- Records shared with other users must not disappear with a user row, so deleting a user
+-- who has them fails instead of cascading
+ALTER TABLE listings DROP CONSTRAINT IF EXISTS listings_user_id_fkey;
+ALTER TABLE listings ADD CONSTRAINT listings_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_buyer_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_buyer_id_fkey
+    FOREIGN KEY (buyer_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_seller_id_fkey;
+ALTER TABLE chats ADD CONSTRAINT chats_seller_id_fkey
+    FOREIGN KEY (seller_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_user_id_fkey;
+ALTER TABLE messages ADD CONSTRAINT messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE message_attachments DROP CONSTRAINT IF EXISTS message_attachments_uploader_id_fkey;
+ALTER TABLE message_attachments ADD CONSTRAINT message_attachments_uploader_id_fkey
+    FOREIGN KEY (uploader_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_buyer_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_buyer_id_fkey
+    FOREIGN KEY (buyer_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE purchases DROP CONSTRAINT IF EXISTS purchases_seller_id_fkey;
+ALTER TABLE purchases ADD CONSTRAINT purchases_seller_id_fkey
+    FOREIGN KEY (seller_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_author_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_author_id_fkey
+    FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE RESTRICT;
+ALTER TABLE reviews DROP CONSTRAINT IF EXISTS reviews_target_id_fkey;
+ALTER TABLE reviews ADD CONSTRAINT reviews_target_id_fkey
+    FOREIGN KEY (target_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE disputes DROP CONSTRAINT IF EXISTS disputes_opened_by_fkey;
+ALTER TABLE disputes ADD CONSTRAINT disputes_opened_by_fkey
+    FOREIGN KEY (opened_by) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE dispute_messages DROP CONSTRAINT IF EXISTS dispute_messages_user_id_fkey;
+ALTER TABLE dispute_messages ADD CONSTRAINT dispute_messages_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+ALTER TABLE dispute_evidence DROP CONSTRAINT IF EXISTS dispute_evidence_user_id_fkey;
+ALTER TABLE dispute_evidence ADD CONSTRAINT dispute_evidence_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT;
+
+-- The OpenID Connect provider a session was started with; empty for password logins.
+-- A fresh provider login stands in for the password before deleting the account.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS provider TEXT NOT NULL DEFAULT '';
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,10 +14,15 @@ import (
 type AppConfig struct {
 	// Server settings
 	Port string
+	// TrustedProxies are the proxies (IPs or CIDRs) whose X-Forwarded-For header gives the client IP
+	TrustedProxies []string
 
 	// CORS settings
 	AllowedOrigins []string
 
+	// Frontend URL used in links sent by email
+	FrontendURL string
+
 	// JWT Settings
 	JWTSecret string
 
@@ -33,6 +41,27 @@ type AppConfig struct {
 
 	// File upload settings
 	UploadsDir string
+
+	// Chat screening settings
+	ChatScreeningActions map[string]string
+	ChatBannedPhrases    []string
+	ChatBurstLimit       int
+	ChatBurstWindow      time.Duration
+
+	// OpenID Connect login providers
+	OIDCProviders []OIDCProviderConfig
+}
+
+// OIDCProviderConfig configures an OpenID Connect provider for social login
+type OIDCProviderConfig struct {
+	// Name is used in URLs, e.g. /auth/oauth/google
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the frontend page the provider returns to with the code and state
+	RedirectURL string
+	Scopes      []string
 }
 
 // Config is the global application configuration
@@ -57,12 +86,21 @@ func Load() {
 		port = "8080"
 	}
 
+	// The client IP feeds the login limits, so it is taken only from proxies we run
+	trustedProxies := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+
 	// CORS settings
 	allowedOrigins := []string{"http://178.130.49.128"}
 	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
 		allowedOrigins = []string{origins}
 	}
 
+	// Frontend URL, by default the allowed origin
+	frontendURL := strings.TrimSuffix(os.Getenv("FRONTEND_URL"), "/")
+	if frontendURL == "" {
+		frontendURL = allowedOrigins[0]
+	}
+
 	// JWT Settings
 	jwtSecret := os.Getenv("JWT_SECRET_KEY")
 	if jwtSecret == "" {
@@ -116,6 +154,69 @@ func Load() {
 		uploadsDir = "uploads"
 	}
 
+	// MODERATOR_USER_IDS was replaced by roles stored in the database
+	if os.Getenv("MODERATOR_USER_IDS") != "" {
+		log.Println("WARNING: MODERATOR_USER_IDS is no longer used; moderators are users with the moderator or admin role")
+	}
+
+	// Chat screening settings: per-rule actions as "rule=action,...", e.g. "links=warn,burst=off",
+	// and banned phrases separated by semicolons
+	chatScreeningActions := parseChatScreeningActions(os.Getenv("CHAT_SCREENING_RULES"))
+	chatBannedPhrases := parseChatBannedPhrases(os.Getenv("CHAT_BANNED_PHRASES"))
+
+	chatBurstLimit := 15
+	if limit := os.Getenv("CHAT_BURST_LIMIT"); limit != "" {
+		if value, err := strconv.Atoi(limit); err == nil && value > 0 {
+			chatBurstLimit = value
+		} else {
+			log.Printf("WARNING: invalid CHAT_BURST_LIMIT %q, using %d", limit, chatBurstLimit)
+		}
+	}
+
+	chatBurstWindow := time.Minute
+	if window := os.Getenv("CHAT_BURST_WINDOW"); window != "" {
+		if value, err := time.ParseDuration(window); err == nil && value > 0 {
+			chatBurstWindow = value
+		} else {
+			log.Printf("WARNING: invalid CHAT_BURST_WINDOW %q, using %s", window, chatBurstWindow)
+		}
+	}
+
+	// OpenID Connect providers: OIDC_PROVIDERS lists names, each configured with
+	// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+	// OIDC_<NAME>_REDIRECT_URL and optionally OIDC_<NAME>_SCOPES (space-separated)
+	var oidcProviders []OIDCProviderConfig
+	if names := os.Getenv("OIDC_PROVIDERS"); names != "" {
+		for _, name := range strings.Split(names, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			prefix := "OIDC_" + strings.ToUpper(name) + "_"
+			provider := OIDCProviderConfig{
+				Name:         name,
+				IssuerURL:    strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+				ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+				ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+				RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+				Scopes:       []string{"openid", "email", "profile"},
+			}
+			if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+				provider.Scopes = strings.Fields(scopes)
+			}
+			if provider.RedirectURL == "" {
+				provider.RedirectURL = frontendURL + "/oauth/" + name + "/callback"
+			}
+
+			if provider.IssuerURL == "" || provider.ClientID == "" {
+				log.Printf("WARNING: OIDC provider %q needs %sISSUER and %sCLIENT_ID, skipping it", name, prefix, prefix)
+				continue
+			}
+			oidcProviders = append(oidcProviders, provider)
+		}
+	}
+
 	// Create the uploads directory if it doesn't exist
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
 		log.Fatal("Error creating uploads directory:", err)
@@ -124,7 +225,9 @@ func Load() {
 	// Set the global configuration
 	Config = AppConfig{
 		Port:           port,
+		TrustedProxies: trustedProxies,
 		AllowedOrigins: allowedOrigins,
+		FrontendURL:    frontendURL,
 		JWTSecret:      jwtSecret,
 		DBHost:         dbHost,
 		DBPort:         dbPort,
@@ -136,6 +239,13 @@ func Load() {
 		SMTPUsername:   smtpUsername,
 		SMTPPassword:   smtpPassword,
 		UploadsDir:     uploadsDir,
+
+		ChatScreeningActions: chatScreeningActions,
+		ChatBannedPhrases:    chatBannedPhrases,
+		ChatBurstLimit:       chatBurstLimit,
+		ChatBurstWindow:      chatBurstWindow,
+
+		OIDCProviders: oidcProviders,
 	}
 
 	log.Println("Configuration loaded successfully")
@@ -145,3 +255,55 @@ func Load() {
 func GetConfig() AppConfig {
 	return Config
 }
+
+// defaultTrustedProxies are the loopback and private networks, where nginx runs next to the server
+var defaultTrustedProxies = []string{"127.0.0.0/8", "::1/128", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+
+// parseTrustedProxies parses comma-separated proxy IPs or CIDRs. An empty value means the
+// default private networks and "none" trusts no proxy, so the peer address is the client IP.
+func parseTrustedProxies(value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultTrustedProxies
+	}
+	if value == "none" {
+		return nil
+	}
+
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// parseChatScreeningActions parses per-rule chat screening actions given as "rule=action,...".
+// Entries without "=" are skipped; the actions themselves are checked by the screening rules.
+func parseChatScreeningActions(value string) map[string]string {
+	actions := make(map[string]string)
+	if value == "" {
+		return actions
+	}
+	for _, rule := range strings.Split(value, ",") {
+		name, action, ok := strings.Cut(strings.TrimSpace(rule), "=")
+		if !ok {
+			log.Printf("WARNING: invalid chat screening rule %q in CHAT_SCREENING_RULES", rule)
+			continue
+		}
+		actions[strings.TrimSpace(name)] = strings.TrimSpace(action)
+	}
+	return actions
+}
+
+// parseChatBannedPhrases parses banned chat phrases separated by semicolons
+func parseChatBannedPhrases(value string) []string {
+	var phrases []string
+	for _, phrase := range strings.Split(value, ";") {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+	return phrases
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChatScreeningActions(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]string
+	}{
+		{"", map[string]string{}},
+		{"links=warn", map[string]string{"links": "warn"}},
+		{" links = hold , burst=off ", map[string]string{"links": "hold", "burst": "off"}},
+		{"links=warn,phones,cards=reject", map[string]string{"links": "warn", "cards": "reject"}},
+		{"links=warn,links=hold", map[string]string{"links": "hold"}},
+	}
+	for _, tt := range tests {
+		if got := parseChatScreeningActions(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseChatScreeningActions(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseChatBannedPhrases(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"предоплата", []string{"предоплата"}},
+		{" предоплата ; номер карты;;  ", []string{"предоплата", "номер карты"}},
+	}
+	for _, tt := range tests {
+		if got := parseChatBannedPhrases(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseChatBannedPhrases(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", defaultTrustedProxies},
+		{"none", nil},
+		{"10.1.2.3", []string{"10.1.2.3"}},
+		{" 10.1.2.3 , 172.18.0.0/16,, ", []string{"10.1.2.3", "172.18.0.0/16"}},
+	}
+	for _, tt := range tests {
+		if got := parseTrustedProxies(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTrustedProxies(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -16,8 +16,7 @@ var DB *sqlx.DB
 // Init initializes the database connection
 func Init() (*sqlx.DB, error) {
 	// Build connection string from config
-	connectionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.Config.DBHost, config.Config.DBPort, config.Config.DBUser, config.Config.DBPassword, config.Config.DBName)
+	connectionString := ConnectionString()
 	log.Println("Connecting to database:", connectionString)
 
 	// Connect to the database
@@ -38,6 +37,12 @@ func Init() (*sqlx.DB, error) {
 	return db, nil
 }
 
+// ConnectionString builds the PostgreSQL connection string from config
+func ConnectionString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		config.Config.DBHost, config.Config.DBPort, config.Config.DBUser, config.Config.DBPassword, config.Config.DBName)
+}
+
 // GetDB returns the global database instance
 func GetDB() *sqlx.DB {
 	return DB
@@ -10,11 +10,25 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// WebSocketAuthProtocol is the WebSocket subprotocol a browser offers first, followed by the
+// JWT, since it can't set the Authorization header on a handshake:
+// new WebSocket(url, ["bearer", token]). Unlike a query parameter, the header isn't written to
+// access logs. The server picks this subprotocol, so the token is never echoed back.
+const WebSocketAuthProtocol = "bearer"
+
 // AuthRequired middleware checks JWT token and authorizes the user
 func AuthRequired(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
+
+		// Browsers can't set headers on WebSocket handshakes, so the token may be passed as a subprotocol
+		if authHeader == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			if token := webSocketToken(c.GetHeader("Sec-WebSocket-Protocol")); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
+
 		if authHeader == "" {
 			log.Println("Missing authorization token in request")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing authorization token"})
@@ -25,7 +39,7 @@ func AuthRequired(db *sqlx.DB) gin.HandlerFunc {
 		// Check "Bearer <token>" format
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			log.Println("Invalid token format:", authHeader)
+			log.Println("Invalid authorization token format")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization token format"})
 			c.Abort()
 			return
@@ -42,14 +56,15 @@ func AuthRequired(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Validate token and get user ID
-		userID, err := utils.ValidateToken(token)
+		// Validate token and get user and session IDs
+		claims, err := utils.ParseToken(token)
 		if err != nil {
 			log.Printf("Token validation error: %v\n", err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			c.Abort()
 			return
 		}
+		userID := claims.UserID
 
 		// Check if userID is positive
 		if userID <= 0 {
@@ -61,9 +76,9 @@ func AuthRequired(db *sqlx.DB) gin.HandlerFunc {
 
 		log.Printf("Token successfully validated for user ID: %d\n", userID)
 
-		// Check if user exists in the database
-		var exists bool
-		err = db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND is_verified = true)", userID)
+		// Check if user exists in the database and the session of the token wasn't ended,
+		// and get the current role of the user
+		role, found, err := sessionRole(db, claims)
 		if err != nil {
 			log.Printf("Error checking user in database: %v\n", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "error checking user"})
@@ -71,16 +86,93 @@ func AuthRequired(db *sqlx.DB) gin.HandlerFunc {
 			return
 		}
 
-		if !exists {
-			log.Printf("User ID: %d not found or not verified\n", userID)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found or not verified"})
+		if !found {
+			log.Printf("User ID: %d not found, not verified or session %d ended\n", userID, claims.SessionID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found, not verified or session ended"})
+			c.Abort()
+			return
+		}
+
+		// A token issued before a role change carries the old role; the client gets a new one with /auth/refresh
+		if role != claims.Role {
+			log.Printf("Role of user ID: %d changed from %q to %q\n", userID, claims.Role, role)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "role changed, refresh the token"})
 			c.Abort()
 			return
 		}
 
-		// Set user ID in context for further use
+		// Set user and session IDs and the role in context for further use
 		c.Set("userID", userID)
+		c.Set("sessionID", claims.SessionID)
+		c.Set("role", role)
 		log.Printf("User ID: %d successfully authenticated\n", userID)
 		c.Next()
 	}
 }
+
+// OptionalAuth middleware sets the user ID and role in the context if a valid JWT token of an
+// active session is present, without rejecting anonymous requests. A token AuthRequired would
+// refuse is ignored, so the request goes on as anonymous. It is used on public routes that
+// personalize results.
+func OptionalAuth(db *sqlx.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(c.GetHeader("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" || parts[1] == "" {
+			c.Next()
+			return
+		}
+
+		claims, err := utils.ParseToken(parts[1])
+		if err != nil || claims.UserID <= 0 {
+			c.Next()
+			return
+		}
+
+		role, found, err := sessionRole(db, claims)
+		if err != nil {
+			log.Printf("Error checking user in database: %v\n", err)
+		}
+		if found && role == claims.Role {
+			c.Set("userID", claims.UserID)
+			c.Set("sessionID", claims.SessionID)
+			c.Set("role", role)
+		}
+		c.Next()
+	}
+}
+
+// SessionActive checks if a session of a user wasn't revoked and hasn't expired. Long-lived
+// connections opened with a token use it to notice the session has ended.
+func SessionActive(db *sqlx.DB, userID, sessionID int) (bool, error) {
+	_, found, err := sessionRole(db, &utils.JWTClaims{UserID: userID, SessionID: sessionID})
+	return found, err
+}
+
+// sessionRole gets the current role of the verified user a token was issued to, if the
+// session of the token wasn't revoked and hasn't expired
+func sessionRole(db *sqlx.DB, claims *utils.JWTClaims) (string, bool, error) {
+	var roles []string
+	err := db.Select(&roles, `
+		SELECT u.role FROM users u
+		JOIN sessions s ON s.user_id = u.id
+		WHERE u.id = $1 AND u.is_verified = true
+		  AND s.id = $2 AND s.revoked_at IS NULL AND s.expires_at > NOW()
+	`, claims.UserID, claims.SessionID)
+	if err != nil {
+		return "", false, err
+	}
+	if len(roles) == 0 {
+		return "", false, nil
+	}
+	return roles[0], true, nil
+}
+
+// webSocketToken gets the JWT from the subprotocols of a WebSocket handshake, offered as
+// WebSocketAuthProtocol followed by the token
+func webSocketToken(header string) string {
+	protocols := strings.Split(header, ",")
+	if len(protocols) != 2 || strings.TrimSpace(protocols[0]) != WebSocketAuthProtocol {
+		return ""
+	}
+	return strings.TrimSpace(protocols[1])
+}
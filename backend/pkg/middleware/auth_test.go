@@ -0,0 +1,26 @@
+package middleware
+
+import "testing"
+
+func TestWebSocketToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"bearer and token", "bearer, abc.def.ghi", "abc.def.ghi"},
+		{"without spaces", "bearer,abc.def.ghi", "abc.def.ghi"},
+		{"no header", "", ""},
+		{"other subprotocol", "chat, abc.def.ghi", ""},
+		{"bearer only", "bearer", ""},
+		{"token first", "abc.def.ghi, bearer", ""},
+		{"extra subprotocol", "bearer, abc.def.ghi, chat", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webSocketToken(tt.header); got != tt.want {
+				t.Errorf("webSocketToken(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
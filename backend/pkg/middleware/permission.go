@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+// Permissions checked by routes; the roles having them are stored in role_permissions
+const (
+	PermissionModerateDisputes = "disputes.moderate"
+	PermissionModerateChats    = "chats.moderate"
+	PermissionReadUsers        = "users.read"
+	PermissionManageUsers      = "users.manage"
+)
+
+// RequirePermission middleware allows only users whose role has the permission to access the route.
+// It must be used after AuthRequired, which sets the role verified against the database.
+func RequirePermission(db *sqlx.DB, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		role, hasRole := c.Get("role")
+		if !exists || !hasRole {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		allowed, err := HasPermission(db, role.(string), permission)
+		if err != nil {
+			log.Printf("Error checking permission %s: %v\n", permission, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error checking permissions"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			log.Printf("User ID: %d with role %q lacks permission %s\n", userID.(int), role.(string), permission)
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission required: " + permission})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission checks if a role has a permission
+func HasPermission(db *sqlx.DB, role, permission string) (bool, error) {
+	var allowed bool
+	err := db.Get(&allowed, `
+		SELECT EXISTS(SELECT 1 FROM role_permissions WHERE role = $1 AND permission = $2)
+	`, role, permission)
+	return allowed, err
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PrivateUploads hides upload folders from the public static file server.
+// Files in these folders are served through endpoints with access control.
+func PrivateUploads(folders ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filePath := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+		for _, folder := range folders {
+			if filePath == folder || strings.HasPrefix(filePath, folder+"/") {
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -1,8 +1,12 @@
 package utils
 
 import (
-	"math/rand"
-	"time"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
 )
 
 const (
@@ -13,14 +17,30 @@ const (
 )
 
 // GenerateCode generates a random 6-digit verification code
-func GenerateCode() string {
-	// Create a properly seeded random source
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+func GenerateCode() (string, error) {
+	max := big.NewInt(int64(len(codeChars)))
 
 	code := make([]byte, codeLength)
 	for i := 0; i < codeLength; i++ {
-		code[i] = codeChars[r.Intn(len(codeChars))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error generating code: %w", err)
+		}
+		code[i] = codeChars[n.Int64()]
 	}
 
-	return string(code)
+	return string(code), nil
+}
+
+// HashCode hashes a verification code for storage. Short codes are keyed with the
+// server secret, so a leaked hash can't be reversed by trying all million codes.
+func HashCode(code string) string {
+	mac := hmac.New(sha256.New, []byte(jwtSecretKey()))
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CodeMatches checks a code against a stored hash in constant time
+func CodeMatches(code, hash string) bool {
+	return hmac.Equal([]byte(HashCode(code)), []byte(hash))
 }
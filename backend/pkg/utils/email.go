@@ -9,6 +9,11 @@ import (
 
 // SendEmail sends an email to the specified address
 func SendEmail(to, subject, body string) error {
+	// In development the mail catcher keeps emails in a local inbox instead of sending them
+	if dir := MailCatcherDir(); dir != "" {
+		return catchEmail(dir, to, subject, body)
+	}
+
 	// Get email configuration from environment
 	smtpHost := os.Getenv("SMTP_HOST")
 	if smtpHost == "" {
@@ -23,16 +28,16 @@ func SendEmail(to, subject, body string) error {
 	smtpUsername := os.Getenv("SMTP_USERNAME")
 	if smtpUsername == "" {
 		log.Println("WARNING: SMTP_USERNAME not configured")
-		// In development mode, log the email instead of sending it
-		log.Printf("Would send email to %s\nSubject: %s\nBody: %s", to, subject, body)
+		// The body isn't logged as it may contain codes; use MAIL_CATCHER_DIR to read emails in development
+		log.Printf("Email to %s not sent: %s", to, subject)
 		return nil
 	}
 
 	smtpPassword := os.Getenv("SMTP_PASSWORD")
 	if smtpPassword == "" {
 		log.Println("WARNING: SMTP_PASSWORD not configured")
-		// In development mode, log the email instead of sending it
-		log.Printf("Would send email to %s\nSubject: %s\nBody: %s", to, subject, body)
+		// The body isn't logged as it may contain codes; use MAIL_CATCHER_DIR to read emails in development
+		log.Printf("Email to %s not sent: %s", to, subject)
 		return nil
 	}
 
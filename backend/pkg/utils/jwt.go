@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -10,26 +14,51 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Token lifetimes
+const (
+	// AccessTokenTTL is how long an access token (JWT) is valid
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a session can be refreshed after the last login
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// DeviceTokenTTL is how long a device stays trusted
+	DeviceTokenTTL = 30 * 24 * time.Hour
+)
+
+// deviceTokenAudience marks device tokens, so they can't be used as access tokens
+const deviceTokenAudience = "trusted-device"
+
 // JWTClaims struct contains custom claims for JWT
 type JWTClaims struct {
 	UserID int `json:"user_id"`
+	// SessionID is the login session the token was issued for; revoking the session invalidates the token
+	SessionID int `json:"sid"`
+	// Role of the user when the token was issued; the auth middleware rejects the token if it changed since
+	Role string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(userID int) (string, error) {
-	// Get secret key from environment variables
+// jwtSecretKey gets the secret key for signing tokens from environment variables
+func jwtSecretKey() string {
 	secretKey := os.Getenv("JWT_SECRET_KEY")
 	if secretKey == "" {
 		log.Println("WARNING: JWT_SECRET_KEY not configured, using default value")
 		secretKey = "default_secret_key_change_in_production" // Fallback for development
 	}
+	return secretKey
+}
 
-	// Create new claims with user ID
+// GenerateToken generates a new short-lived access token for a user session
+func GenerateToken(userID, sessionID int, role string) (string, time.Time, error) {
+	secretKey := jwtSecretKey()
+
+	// Create new claims with user and session IDs and the user's role
+	expiresAt := time.Now().Add(AccessTokenTTL)
 	claims := JWTClaims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token valid for 24 hours
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -40,20 +69,15 @@ func GenerateToken(userID int) (string, error) {
 	// Sign token with secret key
 	tokenString, err := token.SignedString([]byte(secretKey))
 	if err != nil {
-		return "", fmt.Errorf("error signing token: %w", err)
+		return "", time.Time{}, fmt.Errorf("error signing token: %w", err)
 	}
 
-	return tokenString, nil
+	return tokenString, expiresAt, nil
 }
 
-// ValidateToken validates token and returns user ID
-func ValidateToken(tokenString string) (int, error) {
-	// Get secret key from environment variables
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		log.Println("WARNING: JWT_SECRET_KEY not configured, using default value")
-		secretKey = "default_secret_key_change_in_production" // Fallback for development
-	}
+// ParseToken validates a token and returns its claims
+func ParseToken(tokenString string) (*JWTClaims, error) {
+	secretKey := jwtSecretKey()
 
 	// Parse and validate token
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -65,19 +89,94 @@ func ValidateToken(tokenString string) (int, error) {
 	})
 
 	if err != nil {
-		return 0, fmt.Errorf("error parsing token: %w", err)
+		return nil, fmt.Errorf("error parsing token: %w", err)
 	}
 
 	// Check if token is valid
 	if !token.Valid {
-		return 0, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	// Get claims from token
 	claims, ok := token.Claims.(*JWTClaims)
 	if !ok {
-		return 0, errors.New("unable to get claims from token")
+		return nil, errors.New("unable to get claims from token")
+	}
+
+	if claims.VerifyAudience(deviceTokenAudience, true) {
+		return nil, errors.New("device token used as access token")
+	}
+
+	return claims, nil
+}
+
+// DeviceClaims are the claims of a token remembering a trusted device
+type DeviceClaims struct {
+	UserID   int `json:"user_id"`
+	DeviceID int `json:"did"`
+	jwt.RegisteredClaims
+}
+
+// GenerateDeviceToken generates a signed token for a trusted device of a user
+func GenerateDeviceToken(userID, deviceID int, expiresAt time.Time) (string, error) {
+	claims := DeviceClaims{
+		UserID:   userID,
+		DeviceID: deviceID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{deviceTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
 	}
 
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtSecretKey()))
+	if err != nil {
+		return "", fmt.Errorf("error signing device token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// ParseDeviceToken validates a device token and returns its claims
+func ParseDeviceToken(tokenString string) (*DeviceClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &DeviceClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("invalid token signing method: %v", token.Header["alg"])
+		}
+		return []byte(jwtSecretKey()), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing device token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*DeviceClaims)
+	if !ok || !token.Valid || !claims.VerifyAudience(deviceTokenAudience, true) {
+		return nil, errors.New("invalid device token")
+	}
+	return claims, nil
+}
+
+// ValidateToken validates token and returns user ID
+func ValidateToken(tokenString string) (int, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
 	return claims.UserID, nil
 }
+
+// GenerateSecureToken generates a random opaque token (refresh or password reset) and the hash to store instead of it
+func GenerateSecureToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("error generating token: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a random token for storage. Tokens have enough entropy for a plain SHA-256.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
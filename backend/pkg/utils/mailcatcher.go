@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CaughtEmail is an email written to the mail catcher inbox instead of being sent
+type CaughtEmail struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// MailCatcherDir returns the inbox directory of the development mail catcher, or ""
+// if it is off. The catcher is set with MAIL_CATCHER_DIR and never works in release mode.
+func MailCatcherDir() string {
+	dir := os.Getenv("MAIL_CATCHER_DIR")
+	if dir != "" && os.Getenv("GIN_MODE") == "release" {
+		log.Println("WARNING: MAIL_CATCHER_DIR is ignored in release mode")
+		return ""
+	}
+	return dir
+}
+
+// catchEmail writes an email to the mail catcher inbox as a JSON file
+func catchEmail(dir, to, subject, body string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating mail catcher directory: %w", err)
+	}
+
+	email := CaughtEmail{To: to, Subject: subject, Body: body, SentAt: time.Now()}
+	data, err := json.MarshalIndent(email, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding email: %w", err)
+	}
+
+	// The timestamp prefix keeps files in sending order
+	name := fmt.Sprintf("%d-%s.json", email.SentAt.UnixNano(), strings.NewReplacer("/", "_", "\\", "_").Replace(to))
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("error writing email: %w", err)
+	}
+
+	log.Printf("Email to %s caught in %s", to, dir)
+	return nil
+}
+
+// CaughtEmails reads the emails in the mail catcher inbox, oldest first; an empty to returns all of them
+func CaughtEmails(dir, to string) ([]CaughtEmail, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing caught emails: %w", err)
+	}
+	sort.Strings(files)
+
+	emails := []CaughtEmail{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading caught email: %w", err)
+		}
+
+		var email CaughtEmail
+		if err := json.Unmarshal(data, &email); err != nil {
+			return nil, fmt.Errorf("error decoding caught email %s: %w", file, err)
+		}
+		if to == "" || strings.EqualFold(email.To, to) {
+			emails = append(emails, email)
+		}
+	}
+	return emails, nil
+}
+
+// ClearCaughtEmails deletes all emails in the mail catcher inbox
+func ClearCaughtEmails(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("error listing caught emails: %w", err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("error deleting caught email: %w", err)
+		}
+	}
+	return nil
+}
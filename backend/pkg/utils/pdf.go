@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// A4 page size in points
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	// Page margins in points
+	pdfMarginLeft   = 50.0
+	pdfMarginTop    = 60.0
+	pdfMarginBottom = 60.0
+	// X position of field values in AddField
+	pdfValueOffset = 200.0
+)
+
+// pdfLine is a single line of text placed on a page
+type pdfLine struct {
+	x    float64
+	y    float64
+	size float64
+	bold bool
+	text string
+}
+
+// PDFDocument is a minimal PDF writer for simple text documents such as receipts.
+// It uses the standard Helvetica fonts, so it needs no external services or font files.
+// Characters outside of Latin-1 are transliterated (Cyrillic) or replaced with "?".
+type PDFDocument struct {
+	pages [][]pdfLine
+	y     float64
+}
+
+// NewPDFDocument creates an empty PDF document with one page
+func NewPDFDocument() *PDFDocument {
+	return &PDFDocument{
+		pages: [][]pdfLine{{}},
+		y:     pdfPageHeight - pdfMarginTop,
+	}
+}
+
+// AddText adds a line of text, wrapping it if it doesn't fit the page width
+func (d *PDFDocument) AddText(text string, size float64, bold bool) {
+	for _, line := range wrapPDFText(text, pdfPageWidth-2*pdfMarginLeft, size) {
+		d.nextLine(size)
+		d.addLine(pdfMarginLeft, size, bold, line)
+	}
+}
+
+// AddField adds a "label: value" line with the value aligned in a second column
+func (d *PDFDocument) AddField(label, value string, size float64) {
+	lines := wrapPDFText(value, pdfPageWidth-pdfMarginLeft-pdfValueOffset, size)
+	for i, line := range lines {
+		d.nextLine(size)
+		if i == 0 {
+			d.addLine(pdfMarginLeft, size, true, label)
+		}
+		d.addLine(pdfValueOffset, size, false, line)
+	}
+}
+
+// AddSpace adds vertical space
+func (d *PDFDocument) AddSpace(height float64) {
+	d.y -= height
+}
+
+// Bytes renders the document
+func (d *PDFDocument) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	// Objects 1-4: catalog, page tree and fonts; then a page and a content stream per page
+	pageCount := len(d.pages)
+	kids := make([]string, pageCount)
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range d.pages {
+		var content bytes.Buffer
+		for _, line := range lines {
+			font := "F1"
+			if line.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, line.size, line.x, line.y, escapePDFText(line.text))
+		}
+
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 6+2*i))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	// Cross-reference table and trailer
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+// nextLine moves the cursor one line down, starting a new page if needed
+func (d *PDFDocument) nextLine(size float64) {
+	d.y -= size * 1.4
+	if d.y < pdfMarginBottom {
+		d.pages = append(d.pages, []pdfLine{})
+		d.y = pdfPageHeight - pdfMarginTop - size*1.4
+	}
+}
+
+// addLine places text on the current page at the current cursor height
+func (d *PDFDocument) addLine(x, size float64, bold bool, text string) {
+	page := len(d.pages) - 1
+	d.pages[page] = append(d.pages[page], pdfLine{x: x, y: d.y, size: size, bold: bold, text: text})
+}
+
+// wrapPDFText splits text into lines that fit the given width.
+// Helvetica glyphs are about half the font size wide on average.
+func wrapPDFText(text string, width, size float64) []string {
+	maxChars := int(width / (size * 0.5))
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		if current == "" {
+			current = word
+		} else if len([]rune(current))+1+len([]rune(word)) <= maxChars {
+			current += " " + word
+		} else {
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	return append(lines, current)
+}
+
+// escapePDFText converts text to WinAnsi bytes and escapes PDF string delimiters
+func escapePDFText(text string) string {
+	var b strings.Builder
+	for _, r := range Transliterate(text) {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r >= 160 && r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// cyrillicToLatin maps Russian letters to their Latin transliteration
+var cyrillicToLatin = map[rune]string{
+	'А': "A", 'Б': "B", 'В': "V", 'Г': "G", 'Д': "D", 'Е': "E", 'Ё': "Yo", 'Ж': "Zh",
+	'З': "Z", 'И': "I", 'Й': "Y", 'К': "K", 'Л': "L", 'М': "M", 'Н': "N", 'О': "O",
+	'П': "P", 'Р': "R", 'С': "S", 'Т': "T", 'У': "U", 'Ф': "F", 'Х': "Kh", 'Ц': "Ts",
+	'Ч': "Ch", 'Ш': "Sh", 'Щ': "Shch", 'Ъ': "", 'Ы': "Y", 'Ь': "", 'Э': "E", 'Ю': "Yu",
+	'Я': "Ya",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+	'₽': "RUB", '№': "No.", '«': "\"", '»': "\"", '—': "-", '–': "-",
+}
+
+// Transliterate replaces Cyrillic letters and common symbols with Latin equivalents
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
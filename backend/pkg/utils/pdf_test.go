@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkPDFStructure checks that every object starts where the cross-reference table says
+// and that startxref points to the table
+func checkPDFStructure(t *testing.T, pdf []byte) int {
+	t.Helper()
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) {
+		t.Fatalf("missing PDF header")
+	}
+	if !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("missing %%%%EOF marker")
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatalf("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(pdf[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref %d doesn't point to the xref table", xrefOffset)
+	}
+
+	lines := strings.Split(string(pdf[xrefOffset:]), "\n")
+	var count int
+	if _, err := fmt.Sscanf(lines[1], "0 %d", &count); err != nil {
+		t.Fatalf("invalid xref subsection %q: %v", lines[1], err)
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("invalid free entry %q", lines[2])
+	}
+	for i := 1; i < count; i++ {
+		entry := lines[2+i]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("invalid xref entry %q", entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points to %q, want %q", i, pdf[offset:offset+10], want)
+		}
+	}
+
+	if !strings.Contains(string(pdf[xrefOffset:]), fmt.Sprintf("/Size %d /Root 1 0 R", count)) {
+		t.Errorf("trailer doesn't match %d objects", count)
+	}
+	return count
+}
+
+func TestPDFDocumentStructure(t *testing.T) {
+	doc := NewPDFDocument()
+	doc.AddText("Receipt", 18, true)
+	doc.AddField("Listing:", "Шкаф (дуб)", 11)
+	doc.AddSpace(10)
+	doc.AddText(`Back\slash`, 9, false)
+	pdf := doc.Bytes()
+
+	// Catalog, page tree, two fonts, a page and its content stream, plus the free entry
+	if count := checkPDFStructure(t, pdf); count != 7 {
+		t.Errorf("xref has %d entries, want 7", count)
+	}
+	if !bytes.Contains(pdf, []byte("/Count 1")) {
+		t.Errorf("want one page")
+	}
+
+	for _, want := range []string{"(Receipt) Tj", "(Listing:) Tj", `(Shkaf \(dub\)) Tj`, `(Back\\slash) Tj`} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("content missing %s", want)
+		}
+	}
+}
+
+func TestPDFDocumentStreamLength(t *testing.T) {
+	doc := NewPDFDocument()
+	doc.AddText("Hello", 12, false)
+	pdf := string(doc.Bytes())
+
+	match := regexp.MustCompile(`<< /Length (\d+) >>\nstream\n`).FindStringSubmatchIndex(pdf)
+	if match == nil {
+		t.Fatalf("missing content stream")
+	}
+	length, _ := strconv.Atoi(pdf[match[2]:match[3]])
+	if !strings.HasPrefix(pdf[match[1]+length:], "endstream") {
+		t.Errorf("stream /Length %d doesn't end at endstream", length)
+	}
+}
+
+func TestPDFDocumentPageBreak(t *testing.T) {
+	doc := NewPDFDocument()
+	for i := 0; i < 100; i++ {
+		doc.AddText(fmt.Sprintf("Line %d", i), 11, false)
+	}
+	pdf := doc.Bytes()
+
+	// Each extra page adds a page and a content stream
+	count := checkPDFStructure(t, pdf)
+	pages := (count - 5) / 2
+	if pages < 2 {
+		t.Fatalf("want several pages, got %d", pages)
+	}
+	if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d", pages))) {
+		t.Errorf("page tree doesn't count %d pages", pages)
+	}
+	if !bytes.Contains(pdf, []byte("(Line 99) Tj")) {
+		t.Errorf("last line is missing")
+	}
+}
+
+func TestWrapPDFText(t *testing.T) {
+	lines := wrapPDFText("one two three four five six", 50, 10)
+	for _, line := range lines {
+		if len(line) > 10 {
+			t.Errorf("line %q is longer than 10 characters", line)
+		}
+	}
+	if got := strings.Join(lines, " "); got != "one two three four five six" {
+		t.Errorf("wrapped text = %q", got)
+	}
+
+	if got := wrapPDFText("   ", 50, 10); len(got) != 1 || got[0] != "" {
+		t.Errorf("wrapPDFText of blank text = %q, want one empty line", got)
+	}
+}
+
+func TestEscapePDFText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"(a) \\ b", `\(a\) \\ b`},
+		{"Цена: 100 ₽", "Tsena: 100 RUB"},
+		{"tab\there", "tab here"},
+		{"café", "caf\xe9"},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		if got := escapePDFText(tt.text); got != tt.want {
+			t.Errorf("escapePDFText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 key of the RFC 6238 test vectors, "12345678901234567890", in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 Appendix B lists 8-digit codes; a 6-digit code is their last six digits
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode at %d: %v", v.unix, err)
+		}
+		if code != v.code {
+			t.Errorf("TOTPCode at %d = %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestTOTPCodeLowercaseSecret(t *testing.T) {
+	code, err := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", TOTPStep(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	if code != "287082" {
+		t.Errorf("TOTPCode = %s, want 287082", code)
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("TOTPCode with invalid secret: want error")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+	}{
+		{"current step", "050471", step},
+		{"with spaces", "050 471", step},
+		{"previous step", mustTOTPCode(t, step-1), step - 1},
+		{"next step", mustTOTPCode(t, step+1), step + 1},
+		{"outside the skew", mustTOTPCode(t, step+2), 0},
+		{"wrong code", "000000", 0},
+		{"too short", "05047", 0},
+		{"too long", "0050471", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateTOTP(rfc6238Secret, tt.code, now)
+			if err != nil {
+				t.Fatalf("ValidateTOTP: %v", err)
+			}
+			if got != tt.wantStep {
+				t.Errorf("ValidateTOTP(%q) = %d, want %d", tt.code, got, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("generated secret %q isn't usable: %v", secret, err)
+	}
+	other, _ := GenerateTOTPSecret()
+	if other == secret {
+		t.Error("GenerateTOTPSecret returned the same secret twice")
+	}
+}
+
+func mustTOTPCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := TOTPCode(rfc6238Secret, step)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+	return code
+}
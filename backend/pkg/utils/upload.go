@@ -5,6 +5,7 @@ import (
 	"io"
 	"log"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,6 +16,35 @@ import (
 
 // UploadFile handles file uploads and returns the file path
 func UploadFile(file *multipart.FileHeader, folderName string) (string, error) {
+	return saveUpload(file, folderName, generateUniqueFilename(file.Filename))
+}
+
+// UploadFileAs handles file uploads like UploadFile, but the file gets the given extension
+// instead of the one the client sent, so it is served with the type it was checked to have
+func UploadFileAs(file *multipart.FileHeader, folderName, ext string) (string, error) {
+	name := strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)) + ext
+	return saveUpload(file, folderName, generateUniqueFilename(name))
+}
+
+// DetectContentType sniffs the content type of an uploaded file rather than trusting the client
+func DetectContentType(file *multipart.FileHeader) (string, error) {
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("error opening uploaded file: %w", err)
+	}
+	defer src.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("error reading uploaded file: %w", err)
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// saveUpload stores an uploaded file under the name in the folder and returns the file path
+func saveUpload(file *multipart.FileHeader, folderName, filename string) (string, error) {
 	// Create the uploads folder if it doesn't exist
 	uploadsDir := filepath.Join("uploads", folderName)
 	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
@@ -22,9 +52,6 @@ func UploadFile(file *multipart.FileHeader, folderName string) (string, error) {
 		return "", fmt.Errorf("error creating directory: %w", err)
 	}
 
-	// Generate a unique filename
-	filename := generateUniqueFilename(file.Filename)
-
 	// Full path for the file
 	filepath := filepath.Join(uploadsDir, filename)
 
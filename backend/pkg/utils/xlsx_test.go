@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readXLSX unzips a workbook into its parts
+func readXLSX(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("workbook isn't a ZIP archive: %v", err)
+	}
+
+	parts := make(map[string]string, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", f.Name, err)
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func TestWriteXLSXStructure(t *testing.T) {
+	var buf bytes.Buffer
+	rows := [][]interface{}{
+		{"ID", "Listing", "Price"},
+		{1, "Sofa <new> & clean", 1500.5},
+		{2, "=HYPERLINK(\"http://evil\")", 10.0},
+	}
+	if err := WriteXLSX(&buf, "Sales & more", rows); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+	parts := readXLSX(t, buf.Bytes())
+
+	for _, name := range []string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml",
+	} {
+		content, ok := parts[name]
+		if !ok {
+			t.Errorf("missing part %s", name)
+			continue
+		}
+		// Every part has to be well-formed XML
+		decoder := xml.NewDecoder(strings.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err != nil {
+				if err != io.EOF {
+					t.Errorf("%s isn't well-formed XML: %v", name, err)
+				}
+				break
+			}
+		}
+	}
+	if len(parts) != 5 {
+		t.Errorf("workbook has %d parts, want 5", len(parts))
+	}
+
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="Sales &amp; more"`) {
+		t.Errorf("sheet name isn't escaped: %s", parts["xl/workbook.xml"])
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<row r="1"><c r="A1" t="inlineStr"><is><t>ID</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2" t="inlineStr"><is><t>Sofa &lt;new&gt; &amp; clean</t></is></c>`,
+		`<c r="C2"><v>1500.5</v></c>`,
+		// Text is an inline string, never a formula
+		`<c r="B3" t="inlineStr"><is><t>=HYPERLINK(&#34;http://evil&#34;)</t></is></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s", want)
+		}
+	}
+	if strings.Contains(sheet, "<f>") {
+		t.Errorf("sheet has a formula cell")
+	}
+}
+
+func TestXLSXColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := xlsxColumnName(index); got != want {
+			t.Errorf("xlsxColumnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
@@ -82,6 +82,28 @@ api.interceptors.request.use((config) => {
   return Promise.reject(error);
 });
 
+// The access token lives 15 minutes; a 401 is answered by refreshing the session once.
+// Concurrent requests share one refresh, since a refresh token works only once.
+let refreshPromise: Promise<string> | null = null;
+
+const refreshAccessToken = (): Promise<string> => {
+  if (!refreshPromise) {
+    const refreshToken = localStorage.getItem('refresh_token');
+    refreshPromise = (refreshToken
+      ? axios.post(`${API_URL}/auth/refresh`, { refresh_token: refreshToken }, { withCredentials: true, timeout: 15000 })
+          .then((response) => {
+            localStorage.setItem('token', response.data.token);
+            localStorage.setItem('refresh_token', response.data.refresh_token);
+            return response.data.token as string;
+          })
+      : Promise.reject(new Error('No refresh token'))
+    ).finally(() => {
+      refreshPromise = null;
+    });
+  }
+  return refreshPromise;
+};
+
 // Interceptor for handling errors
 api.interceptors.response.use(
   (response) => {
@@ -103,10 +125,22 @@ api.interceptors.response.use(
         headers: error.response.headers
       });
       
-      // On 401 error (not authorized) redirect to login page
-      if (error.response.status === 401) {
-        console.warn('[API ERROR] Authentication token expired or invalid, redirecting to login');
+      // On 401 error (not authorized) refresh the session and retry once, otherwise redirect to login page
+      const originalRequest = error.config;
+      const isAuthRequest = (originalRequest?.url || '').startsWith('/auth/');
+      if (error.response.status === 401 && originalRequest && !originalRequest._retry && !isAuthRequest) {
+        originalRequest._retry = true;
+        try {
+          const token = await refreshAccessToken();
+          originalRequest.headers.Authorization = `Bearer ${token}`;
+          return api(originalRequest);
+        } catch {
+          console.warn('[API ERROR] Session expired or ended, redirecting to login');
+        }
+      }
+      if (error.response.status === 401 && !isAuthRequest) {
         localStorage.removeItem('token');
+        localStorage.removeItem('refresh_token');
         window.location.href = '/login';
       }
     } else if (error.request) {
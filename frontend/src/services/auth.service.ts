@@ -36,11 +36,6 @@ class AuthService {
       }
     });
     
-    // Log the 2FA code if it exists in the response
-    if (response.data && response.data.code) {
-      console.log('2FA Code from registration:', response.data.code);
-    }
-    
     return response.data;
   }
 
@@ -52,11 +47,6 @@ class AuthService {
   async login(data: LoginData) {
     const response = await api.post('/auth/login', data);
     
-    // Log the 2FA code if it exists in the response
-    if (response.data && response.data.code) {
-      console.log('2FA Code from login:', response.data.code);
-    }
-    
     return response.data;
   }
 
@@ -65,12 +55,26 @@ class AuthService {
     if (response.data.token) {
       localStorage.setItem('token', response.data.token);
     }
+    if (response.data.refresh_token) {
+      localStorage.setItem('refresh_token', response.data.refresh_token);
+    }
     return response.data;
   }
 
-  logout() {
+  // Ends the session on the server too, so its tokens stop working everywhere
+  async logout() {
+    const refreshToken = localStorage.getItem('refresh_token');
     localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
     localStorage.removeItem('user');
+
+    if (refreshToken) {
+      try {
+        await api.post('/auth/logout', { refresh_token: refreshToken });
+      } catch (error) {
+        console.error('Error ending the session on logout:', error);
+      }
+    }
   }
 
   getCurrentUser() {
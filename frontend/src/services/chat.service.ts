@@ -247,6 +247,9 @@ class ChatService {
           };
         }).filter(Boolean) as Message[];
         
+        // The API returns the newest messages first; the chat shows them oldest first
+        transformedMessages.reverse();
+        
         return { messages: transformedMessages };
       } catch (error: any) {
         console.error("Error fetching chat details:", error);